@@ -1,17 +1,54 @@
 package main
 
 import (
+    "flag"
     "fmt"
     "os"
+    "strconv"
+    "strings"
 
     "github.com/ripsline/virtual-private-node/internal/config"
     "github.com/ripsline/virtual-private-node/internal/installer"
+    "github.com/ripsline/virtual-private-node/internal/paths"
     "github.com/ripsline/virtual-private-node/internal/welcome"
 )
 
 const version = "0.1.0"
 
 func main() {
+    if len(os.Args) > 1 {
+        switch os.Args[1] {
+        case "install":
+            runInstall(os.Args[2:])
+            return
+        case "status":
+            runStatus(os.Args[2:])
+            return
+        case "uninstall":
+            runUninstall(os.Args[2:])
+            return
+        case "network":
+            runNetwork(os.Args[2:])
+            return
+        case "upgrade":
+            runUpgrade(os.Args[2:])
+            return
+        case "storage":
+            runStorage(os.Args[2:])
+            return
+        case "tune":
+            runTune(os.Args[2:])
+            return
+        case "rpcauth":
+            runRPCAuth(os.Args[2:])
+            return
+        default:
+            fmt.Fprintf(os.Stderr, "unknown command: %s\n", os.Args[1])
+            fmt.Fprintln(os.Stderr, "usage: rlvpn [install [--answers FILE] [--plan] [--root DIR] | status [--json] [--root DIR] | uninstall [--component NAME] [--keep-data] | network [switch NAME [--signet-challenge HEX] [--signet-seednode HOST:PORT]] | upgrade bitcoin|lnd VERSION | storage [unprune | prune GB] | tune | rpcauth [add [NAME] | revoke NAME]]")
+            os.Exit(2)
+        }
+    }
+
     if !installer.NeedsInstall() {
         cfg, err := config.Load()
         if err != nil {
@@ -21,17 +58,285 @@ func main() {
         welcome.Show(cfg, version)
         return
     }
-    if os.Geteuid() != 0 {
-        fmt.Println("ERROR: Run with sudo")
+    requireRoot()
+    if err := installer.Run(installer.Options{}); err != nil {
+        fmt.Fprintf(os.Stderr, "\n  Failed: %v\n", err)
         os.Exit(1)
     }
-    if err := installer.Run(); err != nil {
+    cfg, err := config.Load()
+    if err != nil {
+        cfg = config.Default()
+    }
+    welcome.Show(cfg, version)
+}
+
+// runInstall handles `rlvpn install`. With --answers the
+// questionnaire is skipped and the dashboard is not opened
+// afterwards, so it can be driven from provisioning scripts.
+// With --root the generated files are written under DIR instead
+// of the live system, for review or image building.
+func runInstall(args []string) {
+    fs := flag.NewFlagSet("install", flag.ExitOnError)
+    answers := fs.String("answers", "", "JSON or YAML answers file for a non-interactive install")
+    plan := fs.Bool("plan", false, "print the steps, files and commands without installing")
+    root := fs.String("root", "", "write generated files under `DIR` instead of /")
+    fs.Parse(args)
+    paths.SetRoot(*root)
+
+    if *plan {
+        opts := installer.Options{AnswersFile: *answers, Plan: true}
+        if err := installer.Run(opts); err != nil {
+            fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+            os.Exit(1)
+        }
+        return
+    }
+
+    if !installer.NeedsInstall() {
+        fmt.Fprintln(os.Stderr, "ERROR: node is already installed")
+        os.Exit(1)
+    }
+    if paths.Node.Root == "" {
+        requireRoot()
+    }
+    if err := installer.Run(installer.Options{AnswersFile: *answers}); err != nil {
         fmt.Fprintf(os.Stderr, "\n  Failed: %v\n", err)
         os.Exit(1)
     }
+    if *answers != "" || paths.Node.Root != "" {
+        return
+    }
     cfg, err := config.Load()
     if err != nil {
         cfg = config.Default()
     }
     welcome.Show(cfg, version)
-}
\ No newline at end of file
+}
+
+// runStatus handles `rlvpn status`, printing the same data the
+// dashboard shows for monitoring and cron jobs.
+func runStatus(args []string) {
+    fs := flag.NewFlagSet("status", flag.ExitOnError)
+    asJSON := fs.Bool("json", false, "print status as JSON")
+    root := fs.String("root", "", "read the node's files under `DIR` instead of /")
+    fs.Parse(args)
+    paths.SetRoot(*root)
+
+    if installer.NeedsInstall() {
+        fmt.Fprintln(os.Stderr, "ERROR: node is not installed")
+        os.Exit(1)
+    }
+    cfg, err := config.Load()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+        os.Exit(1)
+    }
+    if err := welcome.PrintStatus(os.Stdout, cfg, *asJSON); err != nil {
+        fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+        os.Exit(1)
+    }
+}
+
+// runUninstall handles `rlvpn uninstall`. The confirmation screen
+// lists everything that will be removed before anything changes.
+func runUninstall(args []string) {
+    fs := flag.NewFlagSet("uninstall", flag.ExitOnError)
+    component := fs.String("component", "all",
+        "what to remove: "+strings.Join(installer.UninstallComponents, ", "))
+    keepData := fs.Bool("keep-data", false, "keep /var/lib/bitcoin and /var/lib/lnd")
+    fs.Parse(args)
+
+    if installer.NeedsInstall() {
+        fmt.Fprintln(os.Stderr, "ERROR: node is not installed")
+        os.Exit(1)
+    }
+    requireRoot()
+    cfg, err := config.Load()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+        os.Exit(1)
+    }
+    if err := installer.RunUninstall(cfg, *component, *keepData); err != nil {
+        fmt.Fprintf(os.Stderr, "\n  Failed: %v\n", err)
+        os.Exit(1)
+    }
+}
+
+// runNetwork handles `rlvpn network`, printing the current
+// network, and `rlvpn network switch NAME`, which reconfigures the
+// node for another network and keeps the old chain data. Switching
+// to signet takes --signet-challenge and --signet-seednode to join
+// a custom signet.
+func runNetwork(args []string) {
+    if installer.NeedsInstall() {
+        fmt.Fprintln(os.Stderr, "ERROR: node is not installed")
+        os.Exit(1)
+    }
+    cfg, err := config.Load()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+        os.Exit(1)
+    }
+    if len(args) == 0 {
+        fmt.Println(cfg.Network)
+        return
+    }
+    if len(args) < 2 || args[0] != "switch" {
+        fmt.Fprintf(os.Stderr, "usage: rlvpn network [switch %s [--signet-challenge HEX] [--signet-seednode HOST:PORT]]\n",
+            strings.Join(installer.NetworkNames, "|"))
+        os.Exit(2)
+    }
+    fs := flag.NewFlagSet("network switch", flag.ExitOnError)
+    challenge := fs.String("signet-challenge", "", "challenge script of a custom signet, in hex")
+    seedNode := fs.String("signet-seednode", "", "`HOST:PORT` of a custom signet's seed node")
+    fs.Parse(args[2:])
+    requireRoot()
+    if err := installer.RunNetworkSwitch(cfg, args[1], *challenge, *seedNode); err != nil {
+        fmt.Fprintf(os.Stderr, "\n  Failed: %v\n", err)
+        os.Exit(1)
+    }
+}
+
+// runUpgrade handles `rlvpn upgrade bitcoin|lnd VERSION`. The new
+// release is installed next to the old one, which is restored if
+// the daemon does not come back up.
+func runUpgrade(args []string) {
+    if len(args) != 2 || (args[0] != "bitcoin" && args[0] != "lnd") {
+        fmt.Fprintln(os.Stderr, "usage: rlvpn upgrade bitcoin|lnd VERSION")
+        os.Exit(2)
+    }
+    if installer.NeedsInstall() {
+        fmt.Fprintln(os.Stderr, "ERROR: node is not installed")
+        os.Exit(1)
+    }
+    requireRoot()
+    cfg, err := config.Load()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+        os.Exit(1)
+    }
+    upgrade := installer.RunBitcoinUpgrade
+    if args[0] == "lnd" {
+        upgrade = installer.RunLNDUpgrade
+    }
+    if err := upgrade(cfg, args[1]); err != nil {
+        fmt.Fprintf(os.Stderr, "\n  Failed: %v\n", err)
+        os.Exit(1)
+    }
+}
+
+// runStorage handles `rlvpn storage`, printing how bitcoind stores
+// the chain, `rlvpn storage unprune`, which converts a pruned node
+// to a full one and downloads the chain again, and `rlvpn storage
+// prune GB`, which changes a pruned node's prune target.
+func runStorage(args []string) {
+    if installer.NeedsInstall() {
+        fmt.Fprintln(os.Stderr, "ERROR: node is not installed")
+        os.Exit(1)
+    }
+    cfg, err := config.Load()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+        os.Exit(1)
+    }
+    if len(args) == 0 {
+        if cfg.IsPruned() {
+            fmt.Printf("pruned %d GB\n", cfg.PruneSize)
+        } else {
+            fmt.Println("unpruned (txindex, block filters)")
+        }
+        return
+    }
+    var run func() error
+    switch {
+    case len(args) == 1 && args[0] == "unprune":
+        run = func() error { return installer.RunUnprune(cfg) }
+    case len(args) == 2 && args[0] == "prune":
+        size, err := strconv.Atoi(strings.TrimSuffix(strings.ToUpper(args[1]), "GB"))
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "ERROR: %q is not a whole number of GB\n", args[1])
+            os.Exit(2)
+        }
+        run = func() error { return installer.RunPruneResize(cfg, size) }
+    default:
+        fmt.Fprintln(os.Stderr, "usage: rlvpn storage [unprune | prune GB]")
+        os.Exit(2)
+    }
+    requireRoot()
+    if err := run(); err != nil {
+        fmt.Fprintf(os.Stderr, "\n  Failed: %v\n", err)
+        os.Exit(1)
+    }
+}
+
+// runTune handles `rlvpn tune`, printing the detected hardware and
+// the bitcoind settings derived from it. `rlvpn tune ibd-check` is
+// run by bitcoind-ibd.timer to drop the cache once the initial
+// block download is done.
+func runTune(args []string) {
+    if len(args) == 0 {
+        installer.PrintTuning()
+        return
+    }
+    if len(args) != 1 || args[0] != "ibd-check" {
+        fmt.Fprintln(os.Stderr, "usage: rlvpn tune [ibd-check]")
+        os.Exit(2)
+    }
+    requireRoot()
+    cfg, err := config.Load()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+        os.Exit(1)
+    }
+    if err := installer.FinishIBDCache(cfg); err != nil {
+        fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+        os.Exit(1)
+    }
+}
+
+// runRPCAuth handles `rlvpn rpcauth`, listing the wallet RPC
+// logins, `rlvpn rpcauth add [NAME]`, which creates one, and
+// `rlvpn rpcauth revoke NAME`, which removes it.
+func runRPCAuth(args []string) {
+    if installer.NeedsInstall() {
+        fmt.Fprintln(os.Stderr, "ERROR: node is not installed")
+        os.Exit(1)
+    }
+    cfg, err := config.Load()
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+        os.Exit(1)
+    }
+    if len(args) == 0 {
+        for _, u := range cfg.RPCUsers {
+            fmt.Println(u.Name)
+        }
+        return
+    }
+    var run func() error
+    switch {
+    case args[0] == "add" && len(args) <= 2:
+        name := ""
+        if len(args) == 2 {
+            name = args[1]
+        }
+        run = func() error { return installer.RunRPCUserAdd(cfg, name) }
+    case args[0] == "revoke" && len(args) == 2:
+        run = func() error { return installer.RunRPCUserRevoke(cfg, args[1]) }
+    default:
+        fmt.Fprintln(os.Stderr, "usage: rlvpn rpcauth [add [NAME] | revoke NAME]")
+        os.Exit(2)
+    }
+    requireRoot()
+    if err := run(); err != nil {
+        fmt.Fprintf(os.Stderr, "\n  Failed: %v\n", err)
+        os.Exit(1)
+    }
+}
+
+func requireRoot() {
+    if os.Geteuid() != 0 {
+        fmt.Println("ERROR: Run with sudo")
+        os.Exit(1)
+    }
+}
@@ -0,0 +1,223 @@
+// Package bitcoinrpc is a small JSON-RPC client for the node's own
+// bitcoind. It authenticates with the RPC cookie and only talks to
+// 127.0.0.1, where bitcoind's rpcbind puts it.
+package bitcoinrpc
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "strings"
+    "time"
+)
+
+// Client calls bitcoind on one network's RPC port.
+type Client struct {
+    // URL is the RPC endpoint, http://127.0.0.1:<port>/.
+    URL string
+    // CookiePath is read on every call, since bitcoind writes a
+    // new cookie each time it starts.
+    CookiePath string
+    HTTP       *http.Client
+}
+
+// New returns a client for the bitcoind listening on port, using
+// the cookie at cookiePath.
+func New(port int, cookiePath string) *Client {
+    return &Client{
+        URL:        fmt.Sprintf("http://127.0.0.1:%d/", port),
+        CookiePath: cookiePath,
+        HTTP:       &http.Client{Timeout: 30 * time.Second},
+    }
+}
+
+// Error is an error reported by bitcoind, such as an unknown
+// method or bad parameters.
+type Error struct {
+    Code    int    `json:"code"`
+    Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+    return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// Call runs method with params and decodes its result into
+// result, which may be nil to discard it.
+func (c *Client) Call(ctx context.Context, method string, result any, params ...any) error {
+    cookie, err := os.ReadFile(c.CookiePath)
+    if err != nil {
+        return fmt.Errorf("read cookie: %w", err)
+    }
+    user, pass, ok := strings.Cut(strings.TrimSpace(string(cookie)), ":")
+    if !ok {
+        return fmt.Errorf("malformed cookie in %s", c.CookiePath)
+    }
+    if params == nil {
+        params = []any{}
+    }
+    body, err := json.Marshal(map[string]any{
+        "jsonrpc": "1.0", "id": "rlvpn", "method": method, "params": params,
+    })
+    if err != nil {
+        return err
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    req.SetBasicAuth(user, pass)
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := c.HTTP.Do(req)
+    if err != nil {
+        return fmt.Errorf("%s: %w", method, err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode == http.StatusUnauthorized {
+        return fmt.Errorf("%s: cookie rejected", method)
+    }
+    data, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return fmt.Errorf("%s: %w", method, err)
+    }
+    // RPC errors come back with a 4xx/5xx status and a JSON body
+    var reply struct {
+        Result json.RawMessage `json:"result"`
+        Error  *Error          `json:"error"`
+    }
+    if err := json.Unmarshal(data, &reply); err != nil {
+        return fmt.Errorf("%s: HTTP %d: %s", method, resp.StatusCode, strings.TrimSpace(string(data)))
+    }
+    if reply.Error != nil {
+        return fmt.Errorf("%s: %w", method, reply.Error)
+    }
+    if result == nil {
+        return nil
+    }
+    if err := json.Unmarshal(reply.Result, result); err != nil {
+        return fmt.Errorf("%s: %w", method, err)
+    }
+    return nil
+}
+
+// BlockchainInfo is the result of getblockchaininfo.
+type BlockchainInfo struct {
+    Chain                string  `json:"chain"`
+    Blocks               int64   `json:"blocks"`
+    Headers              int64   `json:"headers"`
+    BestBlockHash        string  `json:"bestblockhash"`
+    VerificationProgress float64 `json:"verificationprogress"`
+    InitialBlockDownload bool    `json:"initialblockdownload"`
+    SizeOnDisk           int64   `json:"size_on_disk"`
+    Pruned               bool    `json:"pruned"`
+    PruneHeight          int64   `json:"pruneheight"`
+}
+
+func (c *Client) GetBlockchainInfo(ctx context.Context) (*BlockchainInfo, error) {
+    var info BlockchainInfo
+    if err := c.Call(ctx, "getblockchaininfo", &info); err != nil {
+        return nil, err
+    }
+    return &info, nil
+}
+
+// NetworkInfo is the result of getnetworkinfo.
+type NetworkInfo struct {
+    Version         int     `json:"version"`
+    SubVersion      string  `json:"subversion"`
+    ProtocolVersion int     `json:"protocolversion"`
+    Connections     int     `json:"connections"`
+    ConnectionsIn   int     `json:"connections_in"`
+    ConnectionsOut  int     `json:"connections_out"`
+    NetworkActive   bool    `json:"networkactive"`
+    RelayFee        float64 `json:"relayfee"`
+    LocalAddresses  []struct {
+        Address string `json:"address"`
+        Port    int    `json:"port"`
+        Score   int    `json:"score"`
+    } `json:"localaddresses"`
+}
+
+func (c *Client) GetNetworkInfo(ctx context.Context) (*NetworkInfo, error) {
+    var info NetworkInfo
+    if err := c.Call(ctx, "getnetworkinfo", &info); err != nil {
+        return nil, err
+    }
+    return &info, nil
+}
+
+// Peer is one entry of getpeerinfo.
+type Peer struct {
+    ID             int     `json:"id"`
+    Addr           string  `json:"addr"`
+    Network        string  `json:"network"`
+    SubVersion     string  `json:"subver"`
+    Inbound        bool    `json:"inbound"`
+    ConnectionType string  `json:"connection_type"`
+    SyncedBlocks   int64   `json:"synced_blocks"`
+    PingTime       float64 `json:"pingtime"`
+    BytesSent      int64   `json:"bytessent"`
+    BytesRecv      int64   `json:"bytesrecv"`
+}
+
+func (c *Client) GetPeerInfo(ctx context.Context) ([]Peer, error) {
+    var peers []Peer
+    if err := c.Call(ctx, "getpeerinfo", &peers); err != nil {
+        return nil, err
+    }
+    return peers, nil
+}
+
+// MempoolInfo is the result of getmempoolinfo. Fee rates are in
+// BTC/kvB, as bitcoind reports them.
+type MempoolInfo struct {
+    Loaded        bool    `json:"loaded"`
+    Size          int     `json:"size"`
+    Bytes         int64   `json:"bytes"`
+    Usage         int64   `json:"usage"`
+    TotalFee      float64 `json:"total_fee"`
+    MaxMempool    int64   `json:"maxmempool"`
+    MempoolMinFee float64 `json:"mempoolminfee"`
+    MinRelayTxFee float64 `json:"minrelaytxfee"`
+}
+
+func (c *Client) GetMempoolInfo(ctx context.Context) (*MempoolInfo, error) {
+    var info MempoolInfo
+    if err := c.Call(ctx, "getmempoolinfo", &info); err != nil {
+        return nil, err
+    }
+    return &info, nil
+}
+
+// FeeEstimate is the result of estimatesmartfee. FeeRate is in
+// BTC/kvB and is 0 when bitcoind has too little data, in which
+// case Errors says why.
+type FeeEstimate struct {
+    FeeRate float64  `json:"feerate"`
+    Errors  []string `json:"errors"`
+    Blocks  int      `json:"blocks"`
+}
+
+// SatPerVByte converts FeeRate to sat/vB.
+func (f *FeeEstimate) SatPerVByte() float64 {
+    return f.FeeRate * 1e5
+}
+
+// EstimateSmartFee estimates the fee rate to confirm within
+// target blocks. mode is "economical", "conservative" or empty
+// for bitcoind's default.
+func (c *Client) EstimateSmartFee(ctx context.Context, target int, mode string) (*FeeEstimate, error) {
+    params := []any{target}
+    if mode != "" {
+        params = append(params, mode)
+    }
+    var est FeeEstimate
+    if err := c.Call(ctx, "estimatesmartfee", &est, params...); err != nil {
+        return nil, err
+    }
+    return &est, nil
+}
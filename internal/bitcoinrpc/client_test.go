@@ -0,0 +1,99 @@
+package bitcoinrpc
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+// newTestClient serves handle as bitcoind, with a cookie on disk
+// for __cookie__:secret.
+func newTestClient(t *testing.T, handle func(method string, params []any) (int, string)) *Client {
+    t.Helper()
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        user, pass, ok := r.BasicAuth()
+        if !ok || user != "__cookie__" || pass != "secret" {
+            w.WriteHeader(http.StatusUnauthorized)
+            return
+        }
+        var req struct {
+            Method string `json:"method"`
+            Params []any  `json:"params"`
+        }
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            t.Errorf("decode request: %v", err)
+        }
+        status, body := handle(req.Method, req.Params)
+        w.WriteHeader(status)
+        w.Write([]byte(body))
+    }))
+    t.Cleanup(srv.Close)
+    cookie := filepath.Join(t.TempDir(), ".cookie")
+    if err := os.WriteFile(cookie, []byte("__cookie__:secret\n"), 0600); err != nil {
+        t.Fatal(err)
+    }
+    c := New(0, cookie)
+    c.URL = srv.URL
+    return c
+}
+
+func TestGetBlockchainInfo(t *testing.T) {
+    c := newTestClient(t, func(method string, params []any) (int, string) {
+        if method != "getblockchaininfo" || len(params) != 0 {
+            t.Errorf("got %s %v", method, params)
+        }
+        return 200, `{"result": {"chain": "testnet4", "blocks": 120345, "headers": 120350,
+            "verificationprogress": 0.9999, "initialblockdownload": false, "pruned": true}, "error": null, "id": "rlvpn"}`
+    })
+    info, err := c.GetBlockchainInfo(context.Background())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if info.Chain != "testnet4" || info.Blocks != 120345 || info.Headers != 120350 || !info.Pruned {
+        t.Errorf("info = %+v", info)
+    }
+}
+
+func TestEstimateSmartFee(t *testing.T) {
+    c := newTestClient(t, func(method string, params []any) (int, string) {
+        if method != "estimatesmartfee" || len(params) != 2 || params[0] != float64(6) || params[1] != "economical" {
+            t.Errorf("got %s %v", method, params)
+        }
+        return 200, `{"result": {"feerate": 0.00012, "blocks": 6}, "error": null, "id": "rlvpn"}`
+    })
+    est, err := c.EstimateSmartFee(context.Background(), 6, "economical")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got := est.SatPerVByte(); got < 11.99 || got > 12.01 {
+        t.Errorf("SatPerVByte() = %v, want 12", got)
+    }
+}
+
+func TestRPCError(t *testing.T) {
+    c := newTestClient(t, func(method string, params []any) (int, string) {
+        return 404, `{"result": null, "error": {"code": -32601, "message": "Method not found"}, "id": "rlvpn"}`
+    })
+    err := c.Call(context.Background(), "nosuchmethod", nil)
+    var rpcErr *Error
+    if !errors.As(err, &rpcErr) || rpcErr.Code != -32601 {
+        t.Fatalf("err = %v, want RPC error -32601", err)
+    }
+}
+
+func TestStaleCookie(t *testing.T) {
+    c := newTestClient(t, func(method string, params []any) (int, string) {
+        return 200, `{"result": null, "error": null}`
+    })
+    if err := os.WriteFile(c.CookiePath, []byte("__cookie__:old\n"), 0600); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := c.GetMempoolInfo(context.Background()); err == nil {
+        t.Fatal("stale cookie accepted")
+    }
+}
@@ -3,21 +3,33 @@ package config
 import (
     "encoding/json"
     "os"
-)
 
-const configDir = "/etc/rlvpn"
-const configPath = "/etc/rlvpn/config.json"
+    "github.com/ripsline/virtual-private-node/internal/paths"
+)
 
 type AppConfig struct {
-    Network            string `json:"network"`
-    Components         string `json:"components"`
-    PruneSize          int    `json:"prune_size"`
-    P2PMode            string `json:"p2p_mode"`
-    AutoUnlock         bool   `json:"auto_unlock"`
-    LITInstalled       bool   `json:"lit_installed"`
-    LITPassword        string `json:"lit_password,omitempty"`
-    SyncthingInstalled bool   `json:"syncthing_installed"`
-    SyncthingPassword  string `json:"syncthing_password,omitempty"`
+    Network            string    `json:"network"`
+    Components         string    `json:"components"`
+    PruneSize          int       `json:"prune_size"`
+    P2PMode            string    `json:"p2p_mode"`
+    Downloads          string    `json:"downloads,omitempty"`
+    SignetChallenge    string    `json:"signet_challenge,omitempty"`
+    SignetSeedNode     string    `json:"signet_seednode,omitempty"`
+    BitcoinVersion     string    `json:"bitcoin_version,omitempty"`
+    LNDVersion         string    `json:"lnd_version,omitempty"`
+    AutoUnlock         bool      `json:"auto_unlock"`
+    LITInstalled       bool      `json:"lit_installed"`
+    LITPassword        string    `json:"lit_password,omitempty"`
+    SyncthingInstalled bool      `json:"syncthing_installed"`
+    SyncthingPassword  string    `json:"syncthing_password,omitempty"`
+    RPCUsers           []RPCUser `json:"rpc_users,omitempty"`
+}
+
+// RPCUser is a bitcoind RPC login handed to a wallet such as
+// Sparrow. Only a salted hash of Password reaches bitcoind.
+type RPCUser struct {
+    Name     string `json:"name"`
+    Password string `json:"password"`
 }
 
 func Default() *AppConfig {
@@ -30,7 +42,7 @@ func Default() *AppConfig {
 }
 
 func Load() (*AppConfig, error) {
-    data, err := os.ReadFile(configPath)
+    data, err := os.ReadFile(paths.Node.Config())
     if err != nil {
         return nil, err
     }
@@ -42,14 +54,25 @@ func Load() (*AppConfig, error) {
 }
 
 func Save(cfg *AppConfig) error {
-    if err := os.MkdirAll(configDir, 0755); err != nil {
+    if err := os.MkdirAll(paths.Node.RLVPNDir(), 0755); err != nil {
         return err
     }
-    data, err := json.MarshalIndent(cfg, "", "  ")
+    data, err := Encode(cfg)
     if err != nil {
         return err
     }
-    return os.WriteFile(configPath, data, 0600)
+    return os.WriteFile(paths.Node.Config(), data, 0600)
+}
+
+// Encode returns cfg as it is stored in config.json.
+func Encode(cfg *AppConfig) ([]byte, error) {
+    return json.MarshalIndent(cfg, "", "  ")
+}
+
+// DownloadsViaTor reports whether release downloads go through
+// the local Tor SOCKS proxy.
+func (c *AppConfig) DownloadsViaTor() bool {
+    return c.Downloads == "tor"
 }
 
 func (c *AppConfig) HasLND() bool {
@@ -60,12 +83,24 @@ func (c *AppConfig) IsMainnet() bool {
     return c.Network == "mainnet"
 }
 
+// IsRegtest reports whether the node runs a private development
+// chain, which enables the dashboard's mining actions.
+func (c *AppConfig) IsRegtest() bool {
+    return c.Network == "regtest"
+}
+
+// IsPruned reports whether bitcoind discards old blocks. A
+// PruneSize of 0 is an unpruned node with txindex and block
+// filters.
+func (c *AppConfig) IsPruned() bool {
+    return c.PruneSize > 0
+}
+
 func (c *AppConfig) WalletExists() bool {
     network := c.Network
     if c.IsMainnet() {
         network = "mainnet"
     }
-    path := "/var/lib/lnd/data/chain/bitcoin/" + network + "/wallet.db"
-    _, err := os.Stat(path)
+    _, err := os.Stat(paths.Node.LNDWalletDB(network))
     return err == nil
 }
\ No newline at end of file
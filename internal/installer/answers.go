@@ -0,0 +1,155 @@
+package installer
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "net"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+)
+
+// answers mirrors the TUI questionnaire for unattended installs.
+// Field names match the keys written to /etc/rlvpn/config.json.
+type answers struct {
+    Network    string `json:"network" yaml:"network"`
+    Components string `json:"components" yaml:"components"`
+    PruneSize  int    `json:"prune_size" yaml:"prune_size"`
+    Unpruned   bool   `json:"unpruned" yaml:"unpruned"`
+    P2PMode    string `json:"p2p_mode" yaml:"p2p_mode"`
+    PublicIPv4 string `json:"public_ipv4" yaml:"public_ipv4"`
+    Downloads  string `json:"downloads" yaml:"downloads"`
+
+    // Custom signet only; leave empty for the default signet.
+    SignetChallenge string `json:"signet_challenge" yaml:"signet_challenge"`
+    SignetSeedNode  string `json:"signet_seednode" yaml:"signet_seednode"`
+}
+
+// loadAnswers reads a JSON or YAML answers file. The format is
+// picked from the extension; unknown keys are rejected so typos
+// don't silently fall back to defaults.
+func loadAnswers(path string) (*answers, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("read answers: %w", err)
+    }
+    var a answers
+    switch strings.ToLower(filepath.Ext(path)) {
+    case ".yaml", ".yml":
+        dec := yaml.NewDecoder(bytes.NewReader(data))
+        dec.KnownFields(true)
+        if err := dec.Decode(&a); err != nil {
+            return nil, fmt.Errorf("parse %s: %w", path, err)
+        }
+    default:
+        dec := json.NewDecoder(bytes.NewReader(data))
+        dec.DisallowUnknownFields()
+        if err := dec.Decode(&a); err != nil {
+            return nil, fmt.Errorf("parse %s: %w", path, err)
+        }
+    }
+    return &a, nil
+}
+
+// toResult fills unset answers with the TUI defaults and checks
+// every value against the options the questionnaire offers.
+func (a *answers) toResult() (tuiResult, error) {
+    r := defaultResult()
+    if a.Network != "" {
+        r.network = a.Network
+    }
+    if a.Components != "" {
+        r.components = a.Components
+    }
+    if a.PruneSize != 0 {
+        r.pruneSize = strconv.Itoa(a.PruneSize)
+    }
+    if a.Unpruned {
+        if a.PruneSize != 0 {
+            return r, fmt.Errorf("prune_size and unpruned cannot both be set")
+        }
+        r.pruneSize = "full"
+    }
+    if a.P2PMode != "" {
+        r.p2pMode = a.P2PMode
+    }
+    if a.Downloads != "" {
+        r.downloads = a.Downloads
+    }
+    r.signetChallenge = a.SignetChallenge
+    r.signetSeedNode = a.SignetSeedNode
+    if err := validateResult(r); err != nil {
+        return r, err
+    }
+    if r.network != "signet" && (a.SignetChallenge != "" || a.SignetSeedNode != "") {
+        return r, fmt.Errorf("signet_challenge and signet_seednode need network: signet")
+    }
+    if err := validateSignet(a.SignetChallenge, a.SignetSeedNode); err != nil {
+        return r, err
+    }
+    if a.PublicIPv4 != "" {
+        ip := net.ParseIP(a.PublicIPv4)
+        if ip == nil || ip.To4() == nil {
+            return r, fmt.Errorf("public_ipv4: %q is not an IPv4 address", a.PublicIPv4)
+        }
+    }
+    return r, nil
+}
+
+// validateResult rejects any answer the TUI would not let the
+// user pick.
+func validateResult(r tuiResult) error {
+    qs := append(buildQuestions(), p2pQuestion())
+    checks := []struct{ title, key, value string }{
+        {"Network", "network", r.network},
+        {"Components", "components", r.components},
+        {"Blockchain Storage", "prune_size", r.pruneSize},
+        {"LND P2P Mode", "p2p_mode", r.p2pMode},
+        {"Release Downloads", "downloads", r.downloads},
+    }
+    for _, c := range checks {
+        for _, q := range qs {
+            if q.title != c.title {
+                continue
+            }
+            var valid []string
+            ok := false
+            for _, opt := range q.options {
+                valid = append(valid, opt.value)
+                if opt.value == c.value {
+                    ok = true
+                }
+            }
+            if !ok {
+                return fmt.Errorf("%s: %q is not one of %s",
+                    c.key, c.value, strings.Join(valid, ", "))
+            }
+        }
+    }
+    return nil
+}
+
+// configFromAnswers loads and validates an answers file and
+// builds the same installConfig the TUI would produce.
+func configFromAnswers(path string) (*installConfig, error) {
+    a, err := loadAnswers(path)
+    if err != nil {
+        return nil, err
+    }
+    r, err := a.toResult()
+    if err != nil {
+        return nil, err
+    }
+    cfg, err := newInstallConfig(r)
+    if err != nil {
+        return nil, err
+    }
+    if cfg.p2pMode == "hybrid" {
+        cfg.publicIPv4 = a.PublicIPv4
+    }
+    return cfg, nil
+}
@@ -2,86 +2,102 @@ package installer
 
 import (
     "fmt"
-    "os"
-    "os/exec"
+
+    "github.com/ripsline/virtual-private-node/internal/bitcoinrpc"
+    "github.com/ripsline/virtual-private-node/internal/paths"
 )
 
+// newBitcoinRPC returns an RPC client for bitcoind on network,
+// authenticated with its cookie. A variable so tests can stand in
+// for bitcoind.
+var newBitcoinRPC = func(network string) (*bitcoinrpc.Client, error) {
+    n, err := NetworkConfigFromName(network)
+    if err != nil {
+        return nil, err
+    }
+    return bitcoinrpc.New(n.RPCPort, paths.Node.BitcoinCookie(network)), nil
+}
+
 func downloadBitcoin(version string) error {
     filename := fmt.Sprintf("bitcoin-%s-x86_64-linux-gnu.tar.gz", version)
     url := fmt.Sprintf("https://bitcoincore.org/bin/bitcoin-core-%s/%s", version, filename)
     shaURL := fmt.Sprintf("https://bitcoincore.org/bin/bitcoin-core-%s/SHA256SUMS", version)
-    if err := download(url, "/tmp/"+filename); err != nil {
+    if err := download(url, paths.Node.Tmp(filename)); err != nil {
         return err
     }
-    return download(shaURL, "/tmp/SHA256SUMS")
+    return download(shaURL, paths.Node.Tmp("SHA256SUMS"))
 }
 
 func verifyBitcoin(version string) error {
-    cmd := exec.Command("sha256sum", "--ignore-missing", "--check", "SHA256SUMS")
-    cmd.Dir = "/tmp"
-    if output, err := cmd.CombinedOutput(); err != nil {
-        return fmt.Errorf("checksum failed: %s: %s", err, output)
-    }
-    return nil
+    filename := fmt.Sprintf("bitcoin-%s-x86_64-linux-gnu.tar.gz", version)
+    return verifyChecksum(paths.Node.Tmp("SHA256SUMS"), paths.Node.Tmp(filename))
 }
 
 func extractAndInstallBitcoin(version string) error {
     filename := fmt.Sprintf("bitcoin-%s-x86_64-linux-gnu.tar.gz", version)
-    cmd := exec.Command("tar", "-xzf", "/tmp/"+filename, "-C", "/tmp")
-    if output, err := cmd.CombinedOutput(); err != nil {
+    if output, err := sys.Run("tar", "-xzf", paths.Live.Tmp(filename), "-C", paths.Live.Tmp("")); err != nil {
         return fmt.Errorf("extract: %s: %s", err, output)
     }
-    extractDir := fmt.Sprintf("/tmp/bitcoin-%s/bin", version)
-    entries, err := os.ReadDir(extractDir)
+    extractDir := paths.Live.Tmp(fmt.Sprintf("bitcoin-%s/bin", version))
+    entries, err := sys.ReadDir(paths.Node.Tmp(fmt.Sprintf("bitcoin-%s/bin", version)))
     if err != nil {
         return fmt.Errorf("read dir: %w", err)
     }
     for _, entry := range entries {
         src := fmt.Sprintf("%s/%s", extractDir, entry.Name())
-        cmd = exec.Command("install", "-m", "0755", "-o", "root", "-g", "root",
-            src, "/usr/local/bin/")
-        if output, err := cmd.CombinedOutput(); err != nil {
+        if output, err := sys.Run("install", "-m", "0755", "-o", "root", "-g", "root",
+            src, paths.Live.Bin("")+"/"); err != nil {
             return fmt.Errorf("install %s: %s: %s", entry.Name(), err, output)
         }
     }
-    os.Remove("/tmp/" + filename)
-    os.Remove("/tmp/SHA256SUMS")
-    os.Remove("/tmp/SHA256SUMS.asc")
-    os.RemoveAll(fmt.Sprintf("/tmp/bitcoin-%s", version))
+    sys.Remove(paths.Node.Tmp(filename))
+    sys.Remove(paths.Node.Tmp("SHA256SUMS"))
+    sys.Remove(paths.Node.Tmp("SHA256SUMS.asc"))
+    sys.RemoveAll(paths.Node.Tmp("bitcoin-" + version))
     return nil
 }
 
+// bitcoinStorage returns the bitcoin.conf lines for the chosen
+// storage: a prune target, or for an unpruned node (pruneSize 0)
+// the transaction index and BIP157 block filters, which need the
+// full block history.
+func bitcoinStorage(pruneSize int) string {
+    if pruneSize == 0 {
+        return "txindex=1\nblockfilterindex=1\npeerblockfilters=1\n"
+    }
+    return fmt.Sprintf("prune=%d\n", pruneSize*1000)
+}
+
 func writeBitcoinConfig(cfg *installConfig) error {
-    pruneMB := cfg.pruneSize * 1000
+    storage := bitcoinStorage(cfg.pruneSize)
+    tuning := tuneBitcoin(detectHardware()).confLines()
+    if err := ensureRPCAuthConf(); err != nil {
+        return err
+    }
     var content string
 
-    if cfg.network.Name == "testnet4" {
+    if cfg.network.Name != "mainnet" {
         content = fmt.Sprintf(`# Virtual Private Node — Bitcoin Core
 server=1
-%s
-prune=%d
-dbcache=512
-maxmempool=300
-proxy=127.0.0.1:9050
+%s%s
+%s%sproxy=127.0.0.1:9050
 listen=1
 listenonion=1
 
-[testnet4]
+[%s]
 bind=127.0.0.1
 rpcbind=127.0.0.1
 rpcport=%d
 rpcallowip=127.0.0.1
 zmqpubrawblock=tcp://127.0.0.1:%d
 zmqpubrawtx=tcp://127.0.0.1:%d
-`, cfg.network.BitcoinFlag, pruneMB,
-            cfg.network.RPCPort, cfg.network.ZMQBlockPort, cfg.network.ZMQTxPort)
+%s`, rpcAuthLines(), cfg.network.BitcoinFlag, storage, tuning, cfg.network.Name,
+            cfg.network.RPCPort, cfg.network.ZMQBlockPort, cfg.network.ZMQTxPort,
+            cfg.network.bitcoinExtra())
     } else {
         content = fmt.Sprintf(`# Virtual Private Node — Bitcoin Core
 server=1
-prune=%d
-dbcache=512
-maxmempool=300
-proxy=127.0.0.1:9050
+%s%s%sproxy=127.0.0.1:9050
 listen=1
 listenonion=1
 
@@ -91,15 +107,14 @@ rpcport=%d
 rpcallowip=127.0.0.1
 zmqpubrawblock=tcp://127.0.0.1:%d
 zmqpubrawtx=tcp://127.0.0.1:%d
-`, pruneMB,
+`, rpcAuthLines(), storage, tuning,
             cfg.network.RPCPort, cfg.network.ZMQBlockPort, cfg.network.ZMQTxPort)
     }
 
-    if err := os.WriteFile("/etc/bitcoin/bitcoin.conf", []byte(content), 0640); err != nil {
+    if err := sys.WriteFile(paths.Node.BitcoinConf(), []byte(content), 0640); err != nil {
         return err
     }
-    cmd := exec.Command("chown", "root:"+systemUser, "/etc/bitcoin/bitcoin.conf")
-    if output, err := cmd.CombinedOutput(); err != nil {
+    if output, err := sys.Run("chown", "root:"+systemUser, paths.Live.BitcoinConf()); err != nil {
         return fmt.Errorf("%s: %s", err, output)
     }
     return nil
@@ -115,7 +130,7 @@ Wants=network-online.target
 Type=simple
 User=%s
 Group=%s
-ExecStart=/usr/local/bin/bitcoind -conf=/etc/bitcoin/bitcoin.conf -datadir=/var/lib/bitcoin
+ExecStart=%s -conf=%s -datadir=%s
 Restart=on-failure
 RestartSec=30
 TimeoutStopSec=600
@@ -125,8 +140,9 @@ NoNewPrivileges=true
 
 [Install]
 WantedBy=multi-user.target
-`, username, username)
-    return os.WriteFile("/etc/systemd/system/bitcoind.service", []byte(content), 0644)
+`, username, username, paths.Live.Bin("bitcoind"),
+        paths.Live.BitcoinConf(), paths.Live.BitcoinData())
+    return sys.WriteFile(paths.Node.Unit("bitcoind.service"), []byte(content), 0644)
 }
 
 func startBitcoind() error {
@@ -135,23 +151,9 @@ func startBitcoind() error {
         {"systemctl", "enable", "bitcoind"},
         {"systemctl", "start", "bitcoind"},
     } {
-        cmd := exec.Command(args[0], args[1:]...)
-        if output, err := cmd.CombinedOutput(); err != nil {
+        if output, err := sys.Run(args[0], args[1:]...); err != nil {
             return fmt.Errorf("%v: %s: %s", args, err, output)
         }
     }
     return nil
 }
-
-func download(url, dest string) error {
-    var cmd *exec.Cmd
-    if _, err := exec.LookPath("wget"); err == nil {
-        cmd = exec.Command("wget", "-q", "-O", dest, url)
-    } else {
-        cmd = exec.Command("curl", "-sL", "-o", dest, url)
-    }
-    if output, err := cmd.CombinedOutput(); err != nil {
-        return fmt.Errorf("download %s: %s: %s", url, err, output)
-    }
-    return nil
-}
\ No newline at end of file
@@ -0,0 +1,38 @@
+package installer
+
+import (
+    "context"
+    "time"
+
+    "github.com/ripsline/virtual-private-node/internal/system"
+)
+
+const (
+    torSOCKSAddr = "127.0.0.1:9050"
+
+    downloadRetries = 5
+    // downloadTimeout bounds one attempt. A retry resumes from
+    // where the last attempt stopped, so a slow Tor circuit only
+    // costs time, not the bytes already fetched.
+    downloadTimeout = 10 * time.Minute
+)
+
+// downloads holds the settings every download() uses. Run sets
+// viaTor from the install choices and the progress screen sets
+// progress while it is open.
+var downloads struct {
+    viaTor   bool
+    progress func(done, total int64)
+}
+
+func download(url, dest string) error {
+    opts := system.DownloadOptions{
+        Timeout:  downloadTimeout,
+        Retries:  downloadRetries,
+        Progress: downloads.progress,
+    }
+    if downloads.viaTor {
+        opts.Proxy = torSOCKSAddr
+    }
+    return sys.Download(context.Background(), url, dest, opts)
+}
@@ -0,0 +1,1268 @@
+package installer
+
+import (
+    "bufio"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/ripsline/virtual-private-node/internal/bitcoinrpc"
+    "github.com/ripsline/virtual-private-node/internal/config"
+    "github.com/ripsline/virtual-private-node/internal/lndrest"
+    "github.com/ripsline/virtual-private-node/internal/paths"
+    "github.com/ripsline/virtual-private-node/internal/system"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata golden files")
+
+// syncthingDefaultConfig holds the parts of a freshly generated
+// config.xml that configureSyncthingAuth rewrites.
+const syncthingDefaultConfig = `<configuration>
+    <gui enabled="true" tls="false">
+        <address>0.0.0.0:8384</address>
+    </gui>
+    <options>
+        <listenAddress>default</listenAddress>
+        <globalAnnounceEnabled>true</globalAnnounceEnabled>
+        <localAnnounceEnabled>true</localAnnounceEnabled>
+        <relaysEnabled>true</relaysEnabled>
+        <natEnabled>true</natEnabled>
+    </options>
+</configuration>
+`
+
+// newTestRecorder returns a Recorder with the files and command
+// output a successful install reads back: downloaded release
+// files signed by test keys and a fresh host with no bitcoin user.
+func newTestRecorder(t *testing.T) *system.Recorder {
+    useTestKeys(t)
+    rec := system.NewRecorder()
+    rec.AddFile("/etc/default/ufw", "IPV6=yes\n")
+    addRelease(rec, "/tmp/SHA256SUMS", "bitcoin-"+bitcoinVersion+"-x86_64-linux-gnu.tar.gz")
+    rec.AddFile("/tmp/SHA256SUMS.asc", signManifest(t, rec, "/tmp/SHA256SUMS",
+        bitcoinCoreSigners[0].name, bitcoinCoreSigners[2].name))
+    addRelease(rec, "/tmp/manifest.txt", "lnd-linux-amd64-v"+lndVersion+".tar.gz")
+    rec.AddFile("/tmp/manifest-roasbeef-v"+lndVersion+".sig",
+        signManifest(t, rec, "/tmp/manifest.txt", lndSigner.name))
+    addRelease(rec, "/tmp/lit-manifest.txt", "lightning-terminal-linux-amd64-v"+litVersion+".tar.gz")
+    rec.AddFile("/tmp/manifest-ViktorT-11-v"+litVersion+".sig",
+        signManifest(t, rec, "/tmp/lit-manifest.txt", litSigner.name))
+    rec.AddFile("/tmp/bitcoin-"+bitcoinVersion+"/bin/bitcoin-cli", "")
+    rec.AddFile("/tmp/bitcoin-"+bitcoinVersion+"/bin/bitcoind", "")
+    rec.AddFile("/var/lib/tor/lnd-rest/hostname", "lndrest.onion\n")
+    rec.AddFile("/etc/syncthing/config.xml", syncthingDefaultConfig)
+    rec.AddFile("/etc/passwd", "root:x:0:0:root:/root:/bin/bash\n")
+
+    prev, prevIP := sys, detectPublicIP
+    sys = rec
+    detectPublicIP = func() string { return "" }
+    t.Cleanup(func() { sys, detectPublicIP = prev, prevIP })
+    return rec
+}
+
+// addRelease seeds /tmp/<name> and a manifest listing its hash,
+// as the download steps would leave them.
+func addRelease(rec *system.Recorder, manifest, name string) {
+    data := "release " + name
+    sum := sha256.Sum256([]byte(data))
+    rec.AddFile("/tmp/"+name, data)
+    rec.AddFile(manifest, hex.EncodeToString(sum[:])+"  "+name+"\n")
+}
+
+// fakeLND stands in for LND's REST API, answering each path with
+// its JSON body in the returned map, which tests may change.
+func fakeLND(t *testing.T, replies map[string]string) map[string]string {
+    srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        body, ok := replies[r.URL.Path]
+        if !ok {
+            http.NotFound(w, r)
+            return
+        }
+        w.Write([]byte(body))
+    }))
+    t.Cleanup(srv.Close)
+    prev := newLNDClient
+    newLNDClient = func(string) *lndrest.Client {
+        return &lndrest.Client{URL: srv.URL, HTTP: srv.Client()}
+    }
+    t.Cleanup(func() { newLNDClient = prev })
+    return replies
+}
+
+// fakeBitcoind serves replies by RPC method, as JSON results, and
+// stands in for bitcoind. Callers may change replies afterwards.
+func fakeBitcoind(t *testing.T, replies map[string]string) map[string]string {
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        var req struct {
+            Method string `json:"method"`
+        }
+        json.NewDecoder(r.Body).Decode(&req)
+        result, ok := replies[req.Method]
+        if !ok {
+            w.WriteHeader(http.StatusNotFound)
+            fmt.Fprintf(w, `{"result": null, "error": {"code": -32601, "message": "Method not found"}}`)
+            return
+        }
+        fmt.Fprintf(w, `{"result": %s, "error": null}`, result)
+    }))
+    t.Cleanup(srv.Close)
+    cookie := filepath.Join(t.TempDir(), ".cookie")
+    if err := os.WriteFile(cookie, []byte("__cookie__:secret"), 0600); err != nil {
+        t.Fatal(err)
+    }
+    prev := newBitcoinRPC
+    newBitcoinRPC = func(string) (*bitcoinrpc.Client, error) {
+        return &bitcoinrpc.Client{URL: srv.URL, CookiePath: cookie, HTTP: srv.Client()}, nil
+    }
+    t.Cleanup(func() { newBitcoinRPC = prev })
+    return replies
+}
+
+// runSteps runs every step in order, failing the test on the
+// first error.
+func runSteps(t *testing.T, steps []installStep) {
+    t.Helper()
+    for i, s := range steps {
+        if err := s.fn(); err != nil {
+            t.Fatalf("step %d %q: %v", i+1, s.name, err)
+        }
+    }
+}
+
+// checkGolden compares the recorded calls with
+// testdata/<name>.golden. Run `go test -update` to rewrite it.
+func checkGolden(t *testing.T, name string, rec *system.Recorder) {
+    t.Helper()
+    got := strings.Join(rec.Calls, "\n") + "\n"
+    path := filepath.Join("testdata", name+".golden")
+    if *update {
+        if err := os.MkdirAll("testdata", 0755); err != nil {
+            t.Fatal(err)
+        }
+        if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+            t.Fatal(err)
+        }
+        return
+    }
+    want, err := os.ReadFile(path)
+    if err != nil {
+        t.Fatalf("%v (run go test -update to create it)", err)
+    }
+    if got != string(want) {
+        gotLines := strings.Split(got, "\n")
+        wantLines := strings.Split(string(want), "\n")
+        for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+            var g, w string
+            if i < len(gotLines) {
+                g = gotLines[i]
+            }
+            if i < len(wantLines) {
+                w = wantLines[i]
+            }
+            if g != w {
+                t.Fatalf("%s: first difference at line %d:\n got: %s\nwant: %s",
+                    path, i+1, g, w)
+            }
+        }
+    }
+}
+
+func TestBuildSteps(t *testing.T) {
+    tests := []struct {
+        network    string
+        components string
+        p2pMode    string
+    }{
+        {"mainnet", "bitcoin", ""},
+        {"testnet4", "bitcoin", ""},
+        {"mainnet", "bitcoin+lnd", "tor"},
+        {"mainnet", "bitcoin+lnd", "hybrid"},
+        {"testnet4", "bitcoin+lnd", "tor"},
+        {"testnet4", "bitcoin+lnd", "hybrid"},
+        {"signet", "bitcoin", ""},
+        {"signet", "bitcoin+lnd", "tor"},
+        {"regtest", "bitcoin", ""},
+        {"regtest", "bitcoin+lnd", "tor"},
+    }
+    for _, tt := range tests {
+        name := "install-" + tt.network + "-" + strings.ReplaceAll(tt.components, "+", "-")
+        if tt.p2pMode != "" {
+            name += "-" + tt.p2pMode
+        }
+        t.Run(name, func(t *testing.T) {
+            rec := newTestRecorder(t)
+            network, err := NetworkConfigFromName(tt.network)
+            if err != nil {
+                t.Fatal(err)
+            }
+            cfg := &installConfig{
+                network:    network,
+                components: tt.components,
+                pruneSize:  25,
+                p2pMode:    tt.p2pMode,
+                publicIPv4: "203.0.113.10",
+            }
+            runSteps(t, buildSteps(cfg))
+            checkGolden(t, name, rec)
+        })
+    }
+}
+
+func TestLITSteps(t *testing.T) {
+    for _, network := range []string{"mainnet", "testnet4"} {
+        name := "lit-" + network
+        t.Run(name, func(t *testing.T) {
+            rec := newTestRecorder(t)
+            rec.AddFile("/etc/lnd/lnd.conf", "[Application Options]\nalias=test\n")
+            rec.AddFile("/etc/tor/torrc", "SOCKSPort 9050\n")
+            cfg := &config.AppConfig{Network: network, Components: "bitcoin+lnd"}
+            runSteps(t, litSteps(cfg, "password"))
+            checkGolden(t, name, rec)
+
+            conf, _ := rec.ReadFile("/etc/lnd/lnd.conf")
+            if !strings.Contains(string(conf), "rpcmiddleware.enable=true") {
+                t.Error("lnd.conf: rpcmiddleware not enabled")
+            }
+        })
+    }
+}
+
+func TestSyncthingSteps(t *testing.T) {
+    for _, network := range []string{"mainnet", "testnet4"} {
+        name := "syncthing-" + network
+        t.Run(name, func(t *testing.T) {
+            rec := newTestRecorder(t)
+            rec.AddFile("/etc/tor/torrc", "SOCKSPort 9050\n")
+            cfg := &config.AppConfig{Network: network, Components: "bitcoin+lnd"}
+            runSteps(t, syncthingSteps(cfg, "password"))
+            checkGolden(t, name, rec)
+        })
+    }
+}
+
+func TestCustomSignet(t *testing.T) {
+    rec := newTestRecorder(t)
+    a := &answers{
+        Network:         "signet",
+        SignetChallenge: "512103ad5e0edad18cb1f0fc0d28a3d4f1f3e445640337489abb10404f2d1e086be43051ae",
+        SignetSeedNode:  "203.0.113.20:38333",
+    }
+    r, err := a.toResult()
+    if err != nil {
+        t.Fatal(err)
+    }
+    cfg, err := newInstallConfig(r)
+    if err != nil {
+        t.Fatal(err)
+    }
+    runSteps(t, buildSteps(cfg))
+
+    btc, _ := rec.ReadFile("/etc/bitcoin/bitcoin.conf")
+    for _, want := range []string{"signet=1", "[signet]", "rpcport=38332",
+        "signetchallenge=" + a.SignetChallenge, "signetseednode=" + a.SignetSeedNode} {
+        if !strings.Contains(string(btc), want+"\n") {
+            t.Errorf("bitcoin.conf missing %q:\n%s", want, btc)
+        }
+    }
+    lnd, _ := rec.ReadFile("/etc/lnd/lnd.conf")
+    for _, want := range []string{"bitcoin.signet=true",
+        "bitcoin.signetchallenge=" + a.SignetChallenge, "bitcoin.signetseednode=" + a.SignetSeedNode,
+        "bitcoind.rpccookie=/var/lib/bitcoin/signet/.cookie"} {
+        if !strings.Contains(string(lnd), want+"\n") {
+            t.Errorf("lnd.conf missing %q:\n%s", want, lnd)
+        }
+    }
+
+    a.Network = "testnet4"
+    if _, err := a.toResult(); err == nil {
+        t.Error("signet_challenge accepted for testnet4")
+    }
+}
+
+func TestUnknownNetwork(t *testing.T) {
+    for _, name := range NetworkNames {
+        if n, err := NetworkConfigFromName(name); err != nil || n.Name != name {
+            t.Errorf("NetworkConfigFromName(%q) = %v, %v", name, n, err)
+        }
+    }
+    if _, err := NetworkConfigFromName("testnet3"); err == nil {
+        t.Error("testnet3 accepted")
+    }
+    st := &installState{Network: "testnet", Components: "bitcoin"}
+    if _, err := st.config(); err == nil {
+        t.Error("install state with an unknown network resumed")
+    }
+    cfg := &config.AppConfig{Network: "testnet", Components: "bitcoin"}
+    if _, err := buildSwitchPlan(cfg, "signet", "", ""); err == nil {
+        t.Error("switched away from an unknown network")
+    }
+}
+
+func TestNetworkSwitch(t *testing.T) {
+    cfg := &config.AppConfig{
+        Network: "testnet4", Components: "bitcoin+lnd", PruneSize: 25, P2PMode: "hybrid",
+        AutoUnlock: true, LITInstalled: true, LITPassword: "password", SyncthingInstalled: true,
+    }
+    setup := func(t *testing.T) (*system.Recorder, map[string]string) {
+        rec := newTestRecorder(t)
+        rec.AddFile("/etc/lnd/lnd.conf", "listen=0.0.0.0:9735\nexternalhosts=203.0.113.10:9735\n")
+        rec.AddFile("/var/lib/lnd/data/chain/bitcoin/testnet4/wallet.db", "")
+        lnd := fakeLND(t, map[string]string{
+            "/v1/balance/blockchain": `{"total_balance": "0"}`,
+            "/v1/getinfo":            `{"num_active_channels": 0, "num_inactive_channels": 0, "num_pending_channels": 0}`,
+            "/v1/channels/pending":   `{"total_limbo_balance": "0"}`,
+        })
+        return rec, lnd
+    }
+
+    t.Run("switch-testnet4-mainnet", func(t *testing.T) {
+        rec, _ := setup(t)
+        p, err := buildSwitchPlan(cfg, "mainnet", "", "")
+        if err != nil {
+            t.Fatal(err)
+        }
+        if !p.newWallet {
+            t.Error("mainnet has no wallet but newWallet is false")
+        }
+        runSteps(t, p.steps())
+        checkGolden(t, "switch-testnet4-mainnet", rec)
+
+        lnd, _ := rec.ReadFile("/etc/lnd/lnd.conf")
+        for _, want := range []string{"bitcoin.mainnet=true", "externalhosts=203.0.113.10:9735",
+            "rpcmiddleware.enable=true"} {
+            if !strings.Contains(string(lnd), want) {
+                t.Errorf("lnd.conf missing %q", want)
+            }
+        }
+        torrc, _ := rec.ReadFile("/etc/tor/torrc")
+        for _, want := range []string{"HiddenServicePort 8332 127.0.0.1:8332", "lnd-lit", "syncthing"} {
+            if !strings.Contains(string(torrc), want) {
+                t.Errorf("torrc missing %q", want)
+            }
+        }
+    })
+
+    t.Run("refuse-funded", func(t *testing.T) {
+        _, lnd := setup(t)
+        lnd["/v1/balance/blockchain"] = `{"total_balance": "1500"}`
+        _, err := buildSwitchPlan(cfg, "mainnet", "", "")
+        if err == nil || !strings.Contains(err.Error(), "1500 sats") {
+            t.Fatalf("err = %v, want refusal for funded wallet", err)
+        }
+    })
+
+    t.Run("custom-signet", func(t *testing.T) {
+        rec, _ := setup(t)
+        const challenge, seed = "512103ad5e0edad18cb1f0fc0d28a3d4f1f3e445640337489abb10404f2d1e086be43051ae", "203.0.113.20:38333"
+        if _, err := buildSwitchPlan(cfg, "mainnet", challenge, ""); err == nil {
+            t.Error("signet challenge accepted for mainnet")
+        }
+        if _, err := buildSwitchPlan(cfg, "signet", "not hex", ""); err == nil {
+            t.Error("bad signet challenge accepted")
+        }
+        p, err := buildSwitchPlan(cfg, "signet", challenge, seed)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if n := p.install.network; n.SignetChallenge != challenge || n.SignetSeedNode != seed ||
+            p.to.SignetChallenge != challenge {
+            t.Errorf("target network = %+v", n)
+        }
+        runSteps(t, p.steps())
+        conf, _ := rec.ReadFile("/etc/bitcoin/bitcoin.conf")
+        if !strings.Contains(string(conf), "signetchallenge="+challenge) {
+            t.Errorf("bitcoin.conf missing the challenge:\n%s", conf)
+        }
+
+        // leaving keeps the parameters, and coming back reuses them
+        onSignet := *p.to
+        back, err := buildSwitchPlan(&onSignet, "testnet4", "", "")
+        if err != nil {
+            t.Fatal(err)
+        }
+        p, err = buildSwitchPlan(back.to, "signet", "", "")
+        if err != nil {
+            t.Fatal(err)
+        }
+        if p.install.network.SignetChallenge != challenge {
+            t.Error("returning to signet dropped the custom challenge")
+        }
+
+        // the signet chain directory now holds the custom signet
+        rec.AddFile("/var/lib/bitcoin/signet/blocks/blk00000.dat", "")
+        if _, err := buildSwitchPlan(back.to, "signet", "51", ""); err == nil ||
+            !strings.Contains(err.Error(), "another signet") {
+            t.Errorf("err = %v, want refusal to mix signets", err)
+        }
+    })
+
+    t.Run("refuse-channels", func(t *testing.T) {
+        _, lnd := setup(t)
+        lnd["/v1/getinfo"] = `{"num_active_channels": 1, "num_inactive_channels": 0, "num_pending_channels": 1}`
+        _, err := buildSwitchPlan(cfg, "mainnet", "", "")
+        if err == nil || !strings.Contains(err.Error(), "2 channels") {
+            t.Fatalf("err = %v, want refusal for open channels", err)
+        }
+    })
+}
+
+func TestBitcoinUpgrade(t *testing.T) {
+    const version = "30.0"
+    setup := func(t *testing.T) *system.Recorder {
+        rec := newTestRecorder(t)
+        addRelease(rec, "/tmp/SHA256SUMS", "bitcoin-"+version+"-x86_64-linux-gnu.tar.gz")
+        rec.AddFile("/tmp/SHA256SUMS.asc", signManifest(t, rec, "/tmp/SHA256SUMS",
+            bitcoinCoreSigners[0].name, bitcoinCoreSigners[2].name))
+        for _, bin := range []string{"bitcoin-cli", "bitcoind"} {
+            rec.AddFile("/tmp/bitcoin-"+version+"/bin/"+bin, "")
+            rec.AddFile("/usr/local/bin/"+bin, "")
+            // install(1) is only recorded, so seed what it would copy
+            rec.AddFile("/opt/rlvpn/bitcoin-"+version+"/"+bin, "")
+        }
+        prev := healthPoll
+        healthPoll = 0
+        t.Cleanup(func() { healthPoll = prev })
+        return rec
+    }
+
+    t.Run("upgrade-bitcoin", func(t *testing.T) {
+        rec := setup(t)
+        fakeBitcoind(t, map[string]string{"getnetworkinfo": `{"version": 300000}`})
+        runSteps(t, bitcoinUpgradeSteps("mainnet", version, bitcoinVersion))
+        checkGolden(t, "upgrade-bitcoin", rec)
+    })
+
+    t.Run("rollback", func(t *testing.T) {
+        rec := setup(t)
+        for _, bin := range []string{"bitcoin-cli", "bitcoind"} {
+            rec.AddFile("/opt/rlvpn/bitcoin-"+bitcoinVersion+"/"+bin, "")
+        }
+        // The new release never answers as itself
+        fakeBitcoind(t, map[string]string{"getnetworkinfo": `{"version": 290300}`})
+        steps := bitcoinUpgradeSteps("mainnet", version, bitcoinVersion)
+        runSteps(t, steps[:len(steps)-1])
+        err := steps[len(steps)-1].fn()
+        if err == nil || !strings.Contains(err.Error(), "rolled back to "+bitcoinVersion) {
+            t.Fatalf("err = %v, want rollback", err)
+        }
+        cmds := rec.Commands()
+        want := "ln -sfn /opt/rlvpn/bitcoin-" + bitcoinVersion + "/bitcoind /usr/local/bin/bitcoind"
+        if last := cmds[len(cmds)-4:]; !strings.Contains(strings.Join(last, "\n"), want) {
+            t.Errorf("links not restored to %s; last commands:\n%s", bitcoinVersion, strings.Join(last, "\n"))
+        }
+    })
+
+    t.Run("versions", func(t *testing.T) {
+        for _, tt := range []struct {
+            version, previous, err string
+        }{
+            {"30.0", "29.3", ""},
+            {"29.3.1", "29.3", ""},
+            {"29.3", "29.3", "already installed"},
+            {"28.1", "29.3", "downgrades are not supported"},
+            {"29.10", "29.3", ""},
+            {"thirty", "29.3", "not a Bitcoin Core release"},
+        } {
+            err := checkBitcoinUpgrade(tt.version, tt.previous)
+            if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+                t.Errorf("checkBitcoinUpgrade(%q, %q) = %v, want %q", tt.version, tt.previous, err, tt.err)
+            }
+        }
+    })
+}
+
+func TestLNDUpgrade(t *testing.T) {
+    const version = "0.21.0-beta"
+    cfg := &config.AppConfig{Network: "testnet4", Components: "bitcoin+lnd", LITInstalled: true}
+    setup := func(t *testing.T, states ...string) *system.Recorder {
+        rec := newTestRecorder(t)
+        addRelease(rec, "/tmp/manifest.txt", "lnd-linux-amd64-v"+version+".tar.gz")
+        rec.AddFile("/tmp/manifest-roasbeef-v"+version+".sig",
+            signManifest(t, rec, "/tmp/manifest.txt", lndSigner.name))
+        rec.AddFile("/var/lib/lnd/data/graph/testnet4/channel.db", "channels")
+        for _, bin := range lndBinaries {
+            rec.AddFile("/usr/local/bin/"+bin, "")
+            rec.AddFile("/opt/rlvpn/lnd-"+version+"/"+bin, "")
+        }
+        prevPoll, prevTimeout, prevState := healthPoll, healthTimeout, lndState
+        healthPoll, healthTimeout = 0, 0
+        lndState = func() (string, error) {
+            st := states[0]
+            if len(states) > 1 {
+                states = states[1:]
+            }
+            return st, nil
+        }
+        t.Cleanup(func() { healthPoll, healthTimeout, lndState = prevPoll, prevTimeout, prevState })
+        return rec
+    }
+
+    t.Run("upgrade-lnd", func(t *testing.T) {
+        rec := setup(t, "NON_EXISTING")
+        runSteps(t, lndUpgradeSteps(cfg, version, lndVersion))
+        checkGolden(t, "upgrade-lnd", rec)
+    })
+
+    t.Run("rollback", func(t *testing.T) {
+        rec := setup(t, "WAITING_TO_START", "NON_EXISTING")
+        for _, bin := range lndBinaries {
+            rec.AddFile("/opt/rlvpn/lnd-"+lndVersion+"/"+bin, "")
+        }
+        rec.AddFile("/var/lib/lnd/upgrade-backup/"+lndVersion+"/channel.db", "channels")
+        steps := lndUpgradeSteps(cfg, version, lndVersion)
+        start := len(steps) - 2 // before restarting litd
+        runSteps(t, steps[:start])
+        err := steps[start].fn()
+        if err == nil || !strings.Contains(err.Error(), "rolled back to "+lndVersion) {
+            t.Fatalf("err = %v, want rollback", err)
+        }
+        cmds := strings.Join(rec.Commands(), "\n")
+        for _, want := range []string{
+            "cp -a /var/lib/lnd/upgrade-backup/" + lndVersion + "/channel.db /var/lib/lnd/data/graph/testnet4/channel.db",
+            "ln -sfn /opt/rlvpn/lnd-" + lndVersion + "/lnd /usr/local/bin/lnd",
+        } {
+            if !strings.Contains(cmds, want) {
+                t.Errorf("rollback did not run %q", want)
+            }
+        }
+    })
+
+    t.Run("versions", func(t *testing.T) {
+        for _, tt := range []struct {
+            version, previous, err string
+        }{
+            {"0.21.0-beta", "0.20.0-beta", ""},
+            {"0.20.10-beta", "0.20.9-beta", ""},
+            {"0.20.0-beta", "0.20.0-beta", "already installed"},
+            {"0.19.3-beta", "0.20.0-beta", "downgrades are not supported"},
+            {"0.21.0", "0.20.0-beta", "not an LND release"},
+        } {
+            err := checkLNDUpgrade(tt.version, tt.previous)
+            if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+                t.Errorf("checkLNDUpgrade(%q, %q) = %v, want %q", tt.version, tt.previous, err, tt.err)
+            }
+        }
+    })
+}
+
+func TestUnpruned(t *testing.T) {
+    t.Run("install", func(t *testing.T) {
+        rec := newTestRecorder(t)
+        rec.Respond("df --output=avail -BG /var/lib/bitcoin", "Avail\n 900G\n", nil)
+        r, err := (&answers{Network: "mainnet", Components: "bitcoin", Unpruned: true}).toResult()
+        if err != nil {
+            t.Fatal(err)
+        }
+        cfg, err := newInstallConfig(r)
+        if err != nil {
+            t.Fatal(err)
+        }
+        runSteps(t, buildSteps(cfg))
+
+        btc, _ := rec.ReadFile("/etc/bitcoin/bitcoin.conf")
+        for _, want := range []string{"txindex=1", "blockfilterindex=1", "peerblockfilters=1"} {
+            if !strings.Contains(string(btc), want+"\n") {
+                t.Errorf("bitcoin.conf missing %q:\n%s", want, btc)
+            }
+        }
+        if strings.Contains(string(btc), "prune=") {
+            t.Errorf("bitcoin.conf is pruned:\n%s", btc)
+        }
+    })
+
+    t.Run("refuse-small-disk", func(t *testing.T) {
+        rec := newTestRecorder(t)
+        rec.Respond("df --output=avail -BG /var/lib/bitcoin", "Avail\n 500G\n", nil)
+        cfg := &installConfig{network: Mainnet(), components: "bitcoin"}
+        for _, s := range buildSteps(cfg) {
+            if s.name != "Checking disk space" {
+                continue
+            }
+            if err := s.fn(); err == nil || !strings.Contains(err.Error(), "500 GB") {
+                t.Fatalf("err = %v, want refusal for a 500 GB disk", err)
+            }
+            return
+        }
+        t.Fatal("no disk space check for an unpruned install")
+    })
+
+    t.Run("unprune-testnet4", func(t *testing.T) {
+        rec := newTestRecorder(t)
+        rec.Respond("df --output=avail -BG /var/lib/bitcoin", "Avail\n 30G\n", nil)
+        cfg := &config.AppConfig{Network: "testnet4", Components: "bitcoin+lnd", PruneSize: 25}
+        runSteps(t, unpruneSteps(cfg, Testnet4()))
+        checkGolden(t, "unprune-testnet4", rec)
+    })
+
+    if _, err := (&answers{PruneSize: 25, Unpruned: true}).toResult(); err == nil {
+        t.Error("prune_size accepted together with unpruned")
+    }
+}
+
+func TestPruneResize(t *testing.T) {
+    cfg := &config.AppConfig{Network: "testnet4", Components: "bitcoin+lnd", PruneSize: 25}
+    setup := func(t *testing.T) (*system.Recorder, string) {
+        rec := newTestRecorder(t)
+        if err := writeBitcoinConfig(&installConfig{network: Testnet4(), pruneSize: 25}); err != nil {
+            t.Fatal(err)
+        }
+        conf, _ := rec.ReadFile("/etc/bitcoin/bitcoin.conf")
+        rec.Calls = nil
+        return rec, string(conf)
+    }
+
+    t.Run("grow", func(t *testing.T) {
+        rec, before := setup(t)
+        rec.Respond("df --output=avail -BG /var/lib/bitcoin", "Avail\n 60G\n", nil)
+        runSteps(t, pruneResizeSteps(cfg, 40))
+        after, _ := rec.ReadFile("/etc/bitcoin/bitcoin.conf")
+        if want := strings.Replace(before, "prune=25000\n", "prune=40000\n", 1); string(after) != want {
+            t.Errorf("bitcoin.conf:\n%s\nwant only the prune line changed:\n%s", after, want)
+        }
+        cmds := rec.Commands()
+        if last := cmds[len(cmds)-1]; last != "systemctl restart bitcoind" {
+            t.Errorf("last command %q, want bitcoind restart", last)
+        }
+    })
+
+    t.Run("refuse-small-disk", func(t *testing.T) {
+        rec, _ := setup(t)
+        rec.Respond("df --output=avail -BG /var/lib/bitcoin", "Avail\n 20G\n", nil)
+        steps := pruneResizeSteps(cfg, 40)
+        if err := steps[0].fn(); err == nil || !strings.Contains(err.Error(), "only 20 GB") {
+            t.Fatalf("err = %v, want refusal for a 20 GB disk", err)
+        }
+    })
+
+    t.Run("shrink", func(t *testing.T) {
+        rec, _ := setup(t)
+        runSteps(t, pruneResizeSteps(cfg, 12))
+        for _, c := range rec.Commands() {
+            if strings.HasPrefix(c, "df ") {
+                t.Errorf("shrinking checked disk space: %s", c)
+            }
+        }
+        after, _ := rec.ReadFile("/etc/bitcoin/bitcoin.conf")
+        if !strings.Contains(string(after), "\nprune=12000\n") {
+            t.Errorf("bitcoin.conf not updated:\n%s", after)
+        }
+    })
+}
+
+func TestBitcoinTuning(t *testing.T) {
+    t.Run("detect", func(t *testing.T) {
+        rec := newTestRecorder(t)
+        rec.AddFile("/proc/meminfo", "MemTotal:        8152876 kB\nMemFree:  1000 kB\n")
+        rec.AddFile("/proc/cpuinfo", "processor\t: 0\nmodel name\t: x\n\nprocessor\t: 1\n\nprocessor\t: 2\n\nprocessor\t: 3\n")
+        rec.AddFile("/sys/block/loop0/queue/rotational", "1\n")
+        // virtio disks say rotational regardless of the backing store
+        rec.AddFile("/sys/block/vda/queue/rotational", "1\n")
+        want := hardware{memMB: 7961, cpus: 4, ssd: true}
+        if got := detectHardware(); got != want {
+            t.Errorf("detectHardware() = %+v, want %+v", got, want)
+        }
+        rec.AddFile("/sys/block/sda/queue/rotational", "1\n")
+        want.ssd = false
+        if got := detectHardware(); got != want {
+            t.Errorf("with a spinning sda: detectHardware() = %+v, want %+v", got, want)
+        }
+    })
+
+    for _, tt := range []struct {
+        hw   hardware
+        want bitcoinTuning
+    }{
+        {hardware{memMB: 1024, cpus: 1, ssd: true}, bitcoinTuning{300, 384, 1, 40, 100}},
+        {defaultHardware, bitcoinTuning{512, 1536, 1, 125, 300}},
+        {hardware{memMB: 8192, cpus: 4, ssd: false}, bitcoinTuning{1024, 4096, 3, 125, 500}},
+        {hardware{memMB: 65536, cpus: 32, ssd: true}, bitcoinTuning{2048, 16384, 15, 125, 500}},
+    } {
+        if got := tuneBitcoin(tt.hw); got != tt.want {
+            t.Errorf("tuneBitcoin(%+v) = %+v, want %+v", tt.hw, got, tt.want)
+        }
+    }
+
+    t.Run("ibd-check", func(t *testing.T) {
+        rec := newTestRecorder(t)
+        if err := writeBitcoinConfig(&installConfig{network: Mainnet(), pruneSize: 25}); err != nil {
+            t.Fatal(err)
+        }
+        cfg := &config.AppConfig{Network: "mainnet"}
+        rpc := fakeBitcoind(t, map[string]string{"getblockchaininfo": `{"initialblockdownload": true}`})
+        if err := FinishIBDCache(cfg); err != nil {
+            t.Fatal(err)
+        }
+        conf, _ := rec.ReadFile("/etc/bitcoin/bitcoin.conf")
+        if !strings.Contains(string(conf), "\ndbcache=1536\n") {
+            t.Fatalf("cache changed during initial block download:\n%s", conf)
+        }
+
+        rpc["getblockchaininfo"] = `{"initialblockdownload": false}`
+        if err := FinishIBDCache(cfg); err != nil {
+            t.Fatal(err)
+        }
+        conf, _ = rec.ReadFile("/etc/bitcoin/bitcoin.conf")
+        if !strings.Contains(string(conf), "\ndbcache=512\npar=1\n") {
+            t.Errorf("cache not dropped after sync:\n%s", conf)
+        }
+        cmds := rec.Commands()
+        if got := strings.Join(cmds[len(cmds)-2:], "\n"); got !=
+            "systemctl restart bitcoind\nsystemctl disable --now bitcoind-ibd.timer" {
+            t.Errorf("last commands:\n%s", got)
+        }
+    })
+}
+
+func TestRPCAuth(t *testing.T) {
+    // rpcauth.py's output for user "sparrow", password "secret" and
+    // this salt
+    salt, _ := hex.DecodeString("00112233445566778899aabbccddeeff")
+    mac := hmac.New(sha256.New, []byte("00112233445566778899aabbccddeeff"))
+    mac.Write([]byte("secret"))
+    want := "sparrow:00112233445566778899aabbccddeeff$" + hex.EncodeToString(mac.Sum(nil))
+    if got := rpcAuthEntry(config.RPCUser{Name: "sparrow", Password: "secret"}, salt); got != want {
+        t.Errorf("rpcAuthEntry() = %s, want %s", got, want)
+    }
+
+    users := []config.RPCUser{{Name: "sparrow", Password: "p1"}, {Name: "sparrow-2", Password: "p2"}}
+    if got := nextRPCUserName(users); got != "sparrow-3" {
+        t.Errorf("nextRPCUserName() = %s, want sparrow-3", got)
+    }
+
+    t.Run("existing-node", func(t *testing.T) {
+        // bitcoin.conf from before wallet logins existed
+        rec := newTestRecorder(t)
+        rec.AddFile("/etc/bitcoin/bitcoin.conf", "# Virtual Private Node — Bitcoin Core\nserver=1\ntestnet4=1\nprune=25000\n\n[testnet4]\nrpcport=48332\n")
+        runSteps(t, rpcAuthSteps(users))
+
+        btc, _ := rec.ReadFile("/etc/bitcoin/bitcoin.conf")
+        if !strings.Contains(string(btc), "server=1\nrpcwhitelistdefault=0\nincludeconf=/etc/bitcoin/rpcauth.conf\ntestnet4=1\n") {
+            t.Errorf("bitcoin.conf does not include the logins before [testnet4]:\n%s", btc)
+        }
+        auth, _ := rec.ReadFile("/etc/bitcoin/rpcauth.conf")
+        for _, u := range users {
+            if !strings.Contains(string(auth), "\nrpcauth="+u.Name+":") ||
+                !strings.Contains(string(auth), "\nrpcwhitelist="+u.Name+":getblockchaininfo,") {
+                t.Errorf("rpcauth.conf missing %s:\n%s", u.Name, auth)
+            }
+        }
+        if strings.Contains(string(auth), "p1") {
+            t.Errorf("rpcauth.conf holds a plain password:\n%s", auth)
+        }
+        cmds := rec.Commands()
+        if last := cmds[len(cmds)-1]; last != "systemctl restart bitcoind" {
+            t.Errorf("last command %q, want bitcoind restart", last)
+        }
+
+        // A second run finds the include and leaves bitcoin.conf alone
+        rec.Calls = nil
+        runSteps(t, rpcAuthSteps(users[:1]))
+        for _, c := range rec.Calls {
+            if c == "write /etc/bitcoin/bitcoin.conf 0640" {
+                t.Error("bitcoin.conf rewritten with the include already present")
+            }
+        }
+    })
+
+    t.Run("keep-on-reconfigure", func(t *testing.T) {
+        rec := newTestRecorder(t)
+        rec.AddFile("/etc/bitcoin/rpcauth.conf", "rpcauth=sparrow:x$y\n")
+        if err := writeBitcoinConfig(&installConfig{network: Testnet4(), pruneSize: 25}); err != nil {
+            t.Fatal(err)
+        }
+        if auth, _ := rec.ReadFile("/etc/bitcoin/rpcauth.conf"); string(auth) != "rpcauth=sparrow:x$y\n" {
+            t.Errorf("writeBitcoinConfig replaced the logins:\n%s", auth)
+        }
+    })
+}
+
+func TestWalletRestore(t *testing.T) {
+    seed := strings.Repeat("abandon ", 23) + "Art"
+    words, err := parseSeed(seed)
+    if err != nil || len(words) != 24 || words[23] != "art" {
+        t.Fatalf("parseSeed() = %v, %v", words, err)
+    }
+    if _, err := parseSeed(strings.Repeat("abandon ", 12)); err == nil {
+        t.Error("12-word seed accepted")
+    }
+    if _, err := parseSeed(strings.Repeat("abandon ", 23) + "4rt"); err == nil {
+        t.Error("seed with a digit accepted")
+    }
+
+    rec := newTestRecorder(t)
+    if syncedChannelBackup() != "" {
+        t.Error("found a channel backup Syncthing has not synced")
+    }
+    rec.AddFile("/var/lib/syncthing/lnd-backup/channel.backup", "scb")
+    path := syncedChannelBackup()
+    backup, err := readChannelBackup(path)
+    if err != nil || string(backup) != "scb" {
+        t.Fatalf("readChannelBackup(%q) = %q, %v", path, backup, err)
+    }
+
+    prevPoll := recoveryPoll
+    recoveryPoll = 0
+    t.Cleanup(func() { recoveryPoll = prevPoll })
+    replies := fakeLND(t, map[string]string{
+        "/v1/state":           `{"state": "NON_EXISTING"}`,
+        "/v1/initwallet":      `{}`,
+        "/v1/getrecoveryinfo": `{"recovery_mode": true, "progress": 0.5}`,
+    })
+    cfg := &config.AppConfig{Network: "testnet4"}
+    req := lndrest.InitWalletRequest{Password: "password1", Mnemonic: words, RecoveryWindow: 2500}
+    var seen []float64
+    err = restoreWallet(cfg, req, func(info *lndrest.RecoveryInfo) {
+        seen = append(seen, info.Progress)
+        // the next poll finds the rescan done
+        replies["/v1/getrecoveryinfo"] = `{"recovery_mode": true, "recovery_finished": true, "progress": 1}`
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(seen) != 2 || seen[0] != 0.5 || seen[1] != 1 {
+        t.Errorf("progress reports = %v, want [0.5 1]", seen)
+    }
+
+    // an LND that never reports progress is given up on
+    prevTimeout := healthTimeout
+    healthTimeout = 50 * time.Millisecond
+    t.Cleanup(func() { healthTimeout = prevTimeout })
+    delete(replies, "/v1/getrecoveryinfo")
+    start := time.Now()
+    err = restoreWallet(cfg, req, func(*lndrest.RecoveryInfo) { t.Error("progress reported") })
+    if err == nil || !strings.Contains(err.Error(), "recovery progress") {
+        t.Errorf("err = %v, want recovery progress timeout", err)
+    }
+    if time.Since(start) < healthTimeout {
+        t.Errorf("gave up after %s, before healthTimeout", time.Since(start))
+    }
+
+    replies["/v1/state"] = `{"state": "LOCKED"}`
+    if err := restoreWallet(cfg, req, func(*lndrest.RecoveryInfo) {}); err == nil ||
+        !strings.Contains(err.Error(), "already has a wallet") {
+        t.Errorf("err = %v, want existing wallet refused", err)
+    }
+
+    // a seed pasted over several lines arrives whole
+    in := bufio.NewReader(strings.NewReader("abandon abandon\nabandon art\n\n"))
+    if a, b, c := readLine(in), readLine(in), readLine(in); a != "abandon abandon" || b != "abandon art" || c != "" {
+        t.Errorf("readLine() = %q, %q, %q", a, b, c)
+    }
+}
+
+func TestResumeInstall(t *testing.T) {
+    rec := newTestRecorder(t)
+    cfg := &installConfig{
+        network: Mainnet(), components: "bitcoin+lnd",
+        pruneSize: 25, p2pMode: "tor",
+    }
+    steps := buildSteps(cfg)
+    index := func(prefix string) int {
+        for i, s := range steps {
+            if strings.HasPrefix(s.name, prefix) {
+                return i
+            }
+        }
+        t.Fatalf("no step %q", prefix)
+        return -1
+    }
+
+    // the state file goes through sys, so --plan and --root see it
+    st := newInstallState(cfg, steps)
+    install := index("Installing Bitcoin Core")
+    for i := 0; i < install; i++ {
+        st.record(i, nil)
+    }
+    st.record(install, errors.New("tar: short read"))
+    if _, err := rec.ReadFile(paths.Node.InstallState()); err != nil {
+        t.Fatalf("state not written through sys: %v", err)
+    }
+    st, err := loadInstallState()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !strings.Contains(st.failedStep(), "Installing Bitcoin Core: tar: short read") {
+        t.Errorf("failedStep() = %q", st.failedStep())
+    }
+
+    // a resume inside the download group downloads and verifies again
+    first := st.apply(steps)
+    if want := index("Downloading Bitcoin Core"); first != want {
+        t.Errorf("apply() = %d (%s), want %d", first, steps[first].name, want)
+    }
+    if steps[first-1].status != stepDone || steps[first].status == stepDone {
+        t.Errorf("steps before %d not marked done", first)
+    }
+
+    // a resume after the group continues at the failed step
+    for i := range steps {
+        steps[i].status = stepPending
+    }
+    configure := index("Configuring Bitcoin Core")
+    for i := 0; i < configure; i++ {
+        st.record(i, nil)
+    }
+    if first := st.apply(steps); first != configure {
+        t.Errorf("apply() = %d (%s), want %d", first, steps[first].name, configure)
+    }
+
+    clearInstallState()
+    if _, err := rec.Stat(paths.Node.InstallState()); err == nil {
+        t.Error("state file not removed")
+    }
+}
+
+func TestStageInstall(t *testing.T) {
+    root := t.TempDir()
+    paths.SetRoot(root)
+    t.Cleanup(func() { paths.SetRoot("") })
+
+    cfg := &installConfig{
+        network:    Testnet4(),
+        components: "bitcoin+lnd",
+        pruneSize:  25,
+        p2pMode:    "tor",
+    }
+    var out strings.Builder
+    if err := stageInstall(&out, cfg, buildSteps(cfg)); err != nil {
+        t.Fatal(err)
+    }
+
+    for _, p := range []string{
+        "/etc/bitcoin/bitcoin.conf",
+        "/etc/lnd/lnd.conf",
+        "/etc/tor/torrc",
+        "/etc/systemd/system/bitcoind.service",
+        "/etc/systemd/system/lnd.service",
+        "/etc/rlvpn/config.json",
+    } {
+        if _, err := os.Stat(filepath.Join(root, p)); err != nil {
+            t.Errorf("%s not staged: %v", p, err)
+        }
+    }
+
+    // Generated files must point at the live system, not the
+    // staging directory they were written to.
+    conf, err := os.ReadFile(filepath.Join(root, "/etc/lnd/lnd.conf"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if strings.Contains(string(conf), root) {
+        t.Errorf("lnd.conf refers to the staging root:\n%s", conf)
+    }
+    if !strings.Contains(string(conf), "bitcoind.config=/etc/bitcoin/bitcoin.conf") {
+        t.Errorf("lnd.conf missing live bitcoin.conf path:\n%s", conf)
+    }
+}
+
+func TestPublicIP(t *testing.T) {
+    newTestRecorder(t)
+    lookups := 0
+    detected := ""
+    detectPublicIP = func() string {
+        lookups++
+        return detected
+    }
+
+    path := filepath.Join(t.TempDir(), "answers.json")
+    if err := os.WriteFile(path, []byte(`{"components": "bitcoin+lnd", "p2p_mode": "hybrid"}`), 0600); err != nil {
+        t.Fatal(err)
+    }
+    cfg, err := configFromAnswers(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if lookups != 0 || cfg.p2pMode != "hybrid" || cfg.publicIPv4 != "" {
+        t.Fatalf("answers looked up the address: %d lookups, %s %q", lookups, cfg.p2pMode, cfg.publicIPv4)
+    }
+
+    detected = "203.0.113.10"
+    c := *cfg
+    resolvePublicIP(&c)
+    if c.p2pMode != "hybrid" || c.publicIPv4 != detected {
+        t.Errorf("detected: %s %q", c.p2pMode, c.publicIPv4)
+    }
+    resolvePublicIP(&c)
+    if lookups != 1 {
+        t.Errorf("%d lookups with the address known, want 1", lookups)
+    }
+
+    detected = ""
+    c = *cfg
+    resolvePublicIP(&c)
+    if c.p2pMode != "tor" {
+        t.Errorf("undetected address left P2P %s", c.p2pMode)
+    }
+}
+
+func TestDownloadsViaTor(t *testing.T) {
+    rec := newTestRecorder(t)
+    downloads.viaTor = true
+    t.Cleanup(func() { downloads.viaTor = false })
+
+    cfg := &installConfig{
+        network:    Testnet4(),
+        components: "bitcoin+lnd",
+        pruneSize:  25,
+        p2pMode:    "tor",
+        downloads:  "tor",
+    }
+    runSteps(t, buildSteps(cfg))
+
+    torStarted := false
+    n := 0
+    for _, c := range rec.Calls {
+        if c == "$ systemctl restart tor" {
+            torStarted = true
+        }
+        if !strings.HasPrefix(c, "download ") {
+            continue
+        }
+        n++
+        if !torStarted {
+            t.Errorf("download before Tor is started: %s", c)
+        }
+        if !strings.HasSuffix(c, " via "+torSOCKSAddr) {
+            t.Errorf("download not routed through Tor: %s", c)
+        }
+    }
+    if n == 0 {
+        t.Fatal("no downloads recorded")
+    }
+}
+
+func TestVerifyChecksum(t *testing.T) {
+    name := "bitcoin-" + bitcoinVersion + "-x86_64-linux-gnu.tar.gz"
+    other := strings.Repeat("ab", 32) + "  bitcoin-" + bitcoinVersion + "-aarch64-linux-gnu.tar.gz\n"
+
+    t.Run("ok", func(t *testing.T) {
+        rec := newTestRecorder(t)
+        if err := verifyChecksum("/tmp/SHA256SUMS", "/tmp/"+name); err != nil {
+            t.Fatal(err)
+        }
+        data, err := rec.ReadFile("/etc/rlvpn/verified-hashes.json")
+        if err != nil {
+            t.Fatal(err)
+        }
+        sum := sha256.Sum256([]byte("release " + name))
+        if !strings.Contains(string(data), hex.EncodeToString(sum[:])) {
+            t.Errorf("verified hash not recorded:\n%s", data)
+        }
+    })
+
+    t.Run("missing", func(t *testing.T) {
+        rec := newTestRecorder(t)
+        rec.AddFile("/tmp/SHA256SUMS", other)
+        err := verifyChecksum("/tmp/SHA256SUMS", "/tmp/"+name)
+        if err == nil || !strings.Contains(err.Error(), "not listed") {
+            t.Fatalf("err = %v, want not listed", err)
+        }
+    })
+
+    t.Run("mismatch", func(t *testing.T) {
+        rec := newTestRecorder(t)
+        rec.AddFile("/tmp/"+name, "tampered")
+        err := verifyChecksum("/tmp/SHA256SUMS", "/tmp/"+name)
+        var ce *checksumError
+        if !errors.As(err, &ce) {
+            t.Fatalf("err = %v, want *checksumError", err)
+        }
+        if ce.file != name || ce.want == ce.got {
+            t.Errorf("unexpected error fields: %+v", ce)
+        }
+        if _, err := rec.ReadFile("/etc/rlvpn/verified-hashes.json"); err == nil {
+            t.Error("hash recorded for a file that failed verification")
+        }
+    })
+}
+
+func TestUninstall(t *testing.T) {
+    t.Run("plan", func(t *testing.T) {
+        rec := newTestRecorder(t)
+        for _, f := range []string{"/opt/rlvpn/lnd-0.19.0-beta/lnd", "/opt/rlvpn/lnd-" + lndVersion + "/lnd",
+            "/opt/rlvpn/bitcoin-" + bitcoinVersion + "/bitcoind",
+            "/usr/local/bin/bitcoind", "/usr/local/bin/bitcoin-cli", "/usr/local/bin/lncli"} {
+            rec.AddFile(f, "")
+        }
+        cfg := &config.AppConfig{Network: "mainnet", Components: "bitcoin+lnd", LITInstalled: true, SyncthingInstalled: true}
+        if _, err := buildUninstallPlan(cfg, "bitcoin", false); err == nil {
+            t.Error("unknown component accepted")
+        }
+        if _, err := buildUninstallPlan(&config.AppConfig{Components: "bitcoin"}, "lnd", false); err == nil {
+            t.Error("uninstalling LND that is not installed accepted")
+        }
+
+        // LIT runs on LND, and the backup watcher needs both LND and Syncthing
+        p, err := buildUninstallPlan(cfg, "lnd", true)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if !p.lnd || !p.lit || p.syncthing || p.bitcoin {
+            t.Errorf("lnd plan = lnd %v lit %v syncthing %v bitcoin %v", p.lnd, p.lit, p.syncthing, p.bitcoin)
+        }
+        units := strings.Join(p.units, " ")
+        for _, want := range []string{"litd.service", "lnd.service", "lnd-backup-watch.path"} {
+            if !strings.Contains(units, want) {
+                t.Errorf("units %q missing %s", units, want)
+            }
+        }
+        if len(p.kept) != 1 || p.kept[0] != paths.Live.LNDData() {
+            t.Errorf("kept = %v, want LND's data directory", p.kept)
+        }
+        removed := strings.Join(p.paths, " ")
+        for _, want := range []string{"/opt/rlvpn/lnd-0.19.0-beta", "/opt/rlvpn/lnd-" + lndVersion} {
+            if !strings.Contains(removed, want) {
+                t.Errorf("lnd plan paths %q missing %s", removed, want)
+            }
+        }
+        if strings.Contains(removed, "bitcoin") {
+            t.Errorf("lnd plan removes Bitcoin Core files: %s", removed)
+        }
+        if got := strings.Join(p.reverts(), ", "); got !=
+            "Tor control port in /etc/tor/torrc, lncli helper in /home/ripsline/.bashrc" {
+            t.Errorf("lnd plan reverts %s", got)
+        }
+        for _, path := range p.paths {
+            if path == paths.Live.LNDData() {
+                t.Error("--keep-data plan removes LND's data directory")
+            }
+        }
+
+        p, err = buildUninstallPlan(cfg, "lit", false)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if p.lnd || len(p.units) != 1 || p.reverts()[0] != "rpcmiddleware.enable in /etc/lnd/lnd.conf" {
+            t.Errorf("lit plan = units %v, reverts %v", p.units, p.reverts())
+        }
+
+        p, err = buildUninstallPlan(cfg, "all", false)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if !p.bitcoin || !p.lnd || !p.lit || !p.syncthing || len(p.kept) != 0 {
+            t.Errorf("all plan = %+v", p)
+        }
+        removed = strings.Join(p.paths, " ")
+        for _, want := range []string{"/usr/local/bin/bitcoind", "/usr/local/bin/bitcoin-cli", "/opt/rlvpn"} {
+            if !strings.Contains(removed, want) {
+                t.Errorf("all plan paths %q missing %s", removed, want)
+            }
+        }
+        names := make([]string, len(p.steps()))
+        for i, s := range p.steps() {
+            names[i] = s.name
+        }
+        if !strings.Contains(strings.Join(names, "\n"), "Removing shell helpers") {
+            t.Errorf("all plan steps %v do not remove the shell helpers", names)
+        }
+    })
+
+    t.Run("tor", func(t *testing.T) {
+        rec := newTestRecorder(t)
+        cfg := &installConfig{network: Mainnet(), components: "bitcoin+lnd"}
+        if err := writeTorConfig(cfg); err != nil {
+            t.Fatal(err)
+        }
+        torrc, _ := rec.ReadFile(paths.Node.TorConfig())
+        got := removeTorBlocks(string(torrc), []string{"lnd-grpc", "lnd-rest"})
+        for _, gone := range []string{"lnd-grpc", "lnd-rest", "# LND gRPC", "# LND REST", "10009", "\n\n\n"} {
+            if strings.Contains(got, gone) {
+                t.Errorf("torrc still has %q:\n%s", gone, got)
+            }
+        }
+        for _, kept := range []string{"# Bitcoin Core RPC", "bitcoin-rpc/", "bitcoin-p2p/", "ControlPort 9051"} {
+            if !strings.Contains(got, kept) {
+                t.Errorf("torrc lost %q:\n%s", kept, got)
+            }
+        }
+
+        // without LND the torrc is the one a bitcoin-only install writes
+        if err := writeTorConfig(&installConfig{network: Mainnet(), components: "bitcoin"}); err != nil {
+            t.Fatal(err)
+        }
+        want, _ := rec.ReadFile(paths.Node.TorConfig())
+        if got = removeTorControlPort(got); got != string(want) {
+            t.Errorf("torrc without LND:\n%s\nwant:\n%s", got, want)
+        }
+    })
+
+    t.Run("shell", func(t *testing.T) {
+        const before = "# ~/.bashrc\nalias ll='ls -l'\n"
+        const after = "\n# added by the admin\nexport EDITOR=vim\n"
+        for _, components := range []string{"bitcoin", "bitcoin+lnd"} {
+            rec := newTestRecorder(t)
+            rec.AddFile(paths.Node.AdminBashrc(), before)
+            cfg := &installConfig{network: Testnet4(), components: components}
+            if err := setupShellEnvironment(cfg); err != nil {
+                t.Fatal(err)
+            }
+            rec.AppendFile(paths.Node.AdminBashrc(), []byte(after), 0644)
+            if err := removeShellEnvironment(); err != nil {
+                t.Fatal(err)
+            }
+            if got, _ := rec.ReadFile(paths.Node.AdminBashrc()); string(got) != before+after {
+                t.Errorf("%s: .bashrc = %q, want %q", components, got, before+after)
+            }
+        }
+
+        // blocks written before the end marker existed
+        legacy := before + "\n" + shellBlockStart + `
+bitcoin-cli() {
+    sudo -u bitcoin /usr/local/bin/bitcoin-cli \
+        "$@"
+}
+export -f bitcoin-cli
+
+lncli() {
+    sudo -u bitcoin /usr/local/bin/lncli \
+        "$@"
+}
+export -f lncli
+` + after
+        if got := removeShellBlock(legacy); got != before+after {
+            t.Errorf("legacy block: got %q, want %q", got, before+after)
+        }
+        if got := removeShellBlock(before); got != before {
+            t.Errorf("no block: got %q", got)
+        }
+
+        // removing LND leaves the block a bitcoin-only install writes
+        blocks := make(map[string]string)
+        for _, components := range []string{"bitcoin", "bitcoin+lnd"} {
+            rec := newTestRecorder(t)
+            rec.AddFile(paths.Node.AdminBashrc(), before)
+            if err := setupShellEnvironment(&installConfig{network: Testnet4(), components: components}); err != nil {
+                t.Fatal(err)
+            }
+            rec.AppendFile(paths.Node.AdminBashrc(), []byte(after), 0644)
+            if components == "bitcoin+lnd" {
+                if err := removeLNCLIHelper(); err != nil {
+                    t.Fatal(err)
+                }
+            }
+            data, _ := rec.ReadFile(paths.Node.AdminBashrc())
+            blocks[components] = string(data)
+        }
+        if blocks["bitcoin+lnd"] != blocks["bitcoin"] {
+            t.Errorf("without lncli:\n%s\nwant:\n%s", blocks["bitcoin+lnd"], blocks["bitcoin"])
+        }
+        if got := removeLNCLIFunc(legacy); strings.Contains(got, "lncli") || !strings.Contains(got, "export -f bitcoin-cli") {
+            t.Errorf("legacy block without lncli: %q", got)
+        }
+        if got := removeShellBlock(removeLNCLIFunc(legacy)); got != before+after {
+            t.Errorf("legacy block after lncli removal: got %q", got)
+        }
+    })
+}
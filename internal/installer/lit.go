@@ -3,10 +3,10 @@ package installer
 import (
     "fmt"
     "os"
-    "os/exec"
     "strings"
 
     "github.com/ripsline/virtual-private-node/internal/config"
+    "github.com/ripsline/virtual-private-node/internal/paths"
 )
 
 func downloadLIT(version string) error {
@@ -17,44 +17,34 @@ func downloadLIT(version string) error {
     manifestURL := fmt.Sprintf(
         "https://github.com/lightninglabs/lightning-terminal/releases/download/v%s/manifest-v%s.txt",
         version, version)
-    if err := download(url, "/tmp/"+filename); err != nil {
+    if err := download(url, paths.Node.Tmp(filename)); err != nil {
         return err
     }
     // Hard-fail if manifest download fails
-    if err := download(manifestURL, "/tmp/lit-manifest.txt"); err != nil {
+    if err := download(manifestURL, paths.Node.Tmp("lit-manifest.txt")); err != nil {
         return fmt.Errorf("download LIT manifest: %w", err)
     }
     return nil
 }
 
 func verifyLIT(version string) error {
-    if _, err := os.Stat("/tmp/lit-manifest.txt"); err != nil {
-        return fmt.Errorf("LIT manifest not found")
-    }
-    cmd := exec.Command("sha256sum", "--ignore-missing",
-        "--check", "lit-manifest.txt")
-    cmd.Dir = "/tmp"
-    if output, err := cmd.CombinedOutput(); err != nil {
-        return fmt.Errorf("checksum: %s: %s", err, output)
-    }
-    return nil
+    filename := fmt.Sprintf("lightning-terminal-linux-amd64-v%s.tar.gz", version)
+    return verifyChecksum(paths.Node.Tmp("lit-manifest.txt"), paths.Node.Tmp(filename))
 }
 
 func extractAndInstallLIT(version string) error {
     filename := fmt.Sprintf("lightning-terminal-linux-amd64-v%s.tar.gz", version)
-    cmd := exec.Command("tar", "-xzf", "/tmp/"+filename, "-C", "/tmp")
-    if output, err := cmd.CombinedOutput(); err != nil {
+    if output, err := sys.Run("tar", "-xzf", paths.Live.Tmp(filename), "-C", paths.Live.Tmp("")); err != nil {
         return fmt.Errorf("extract: %s: %s", err, output)
     }
-    extractDir := fmt.Sprintf("/tmp/lightning-terminal-linux-amd64-v%s", version)
-    cmd = exec.Command("install", "-m", "0755", "-o", "root", "-g", "root",
-        extractDir+"/litd", "/usr/local/bin/")
-    if output, err := cmd.CombinedOutput(); err != nil {
+    extractDir := fmt.Sprintf("lightning-terminal-linux-amd64-v%s", version)
+    if output, err := sys.Run("install", "-m", "0755", "-o", "root", "-g", "root",
+        paths.Live.Tmp(extractDir+"/litd"), paths.Live.Bin("")+"/"); err != nil {
         return fmt.Errorf("install: %s: %s", err, output)
     }
-    os.Remove("/tmp/" + filename)
-    os.Remove("/tmp/lit-manifest.txt")
-    os.RemoveAll(extractDir)
+    sys.Remove(paths.Node.Tmp(filename))
+    sys.Remove(paths.Node.Tmp("lit-manifest.txt"))
+    sys.RemoveAll(paths.Node.Tmp(extractDir))
     return nil
 }
 
@@ -64,23 +54,23 @@ func createLITDirs() error {
         owner string
         mode  os.FileMode
     }{
-        {"/etc/lit", "root:" + systemUser, 0750},
-        {"/var/lib/lit", systemUser + ":" + systemUser, 0750},
+        {paths.Live.LITConfDir(), "root:" + systemUser, 0750},
+        {paths.Live.LITData(), systemUser + ":" + systemUser, 0750},
     }
     for _, d := range dirs {
-        if err := os.MkdirAll(d.path, d.mode); err != nil {
+        if err := sys.MkdirAll(paths.Node.Path(d.path), d.mode); err != nil {
             return err
         }
-        if output, err := exec.Command("chown", d.owner, d.path).CombinedOutput(); err != nil {
+        if output, err := sys.Run("chown", d.owner, d.path); err != nil {
             return fmt.Errorf("chown %s: %s: %s", d.path, err, output)
         }
-        os.Chmod(d.path, d.mode)
+        sys.Chmod(paths.Node.Path(d.path), d.mode)
     }
     return nil
 }
 
 func enableRPCMiddleware() error {
-    data, err := os.ReadFile("/etc/lnd/lnd.conf")
+    data, err := sys.ReadFile(paths.Node.LNDConf())
     if err != nil {
         return err
     }
@@ -98,10 +88,10 @@ func enableRPCMiddleware() error {
     } else {
         content += addition
     }
-    if err := os.WriteFile("/etc/lnd/lnd.conf", []byte(content), 0640); err != nil {
+    if err := sys.WriteFile(paths.Node.LNDConf(), []byte(content), 0640); err != nil {
         return err
     }
-    if output, err := exec.Command("chown", "root:"+systemUser, "/etc/lnd/lnd.conf").CombinedOutput(); err != nil {
+    if output, err := sys.Run("chown", "root:"+systemUser, paths.Live.LNDConf()); err != nil {
         return fmt.Errorf("chown lnd.conf: %s: %s", err, output)
     }
     return nil
@@ -112,17 +102,16 @@ func writeLITConfig(cfg *config.AppConfig, uiPassword string) error {
     if cfg.IsMainnet() {
         network = "mainnet"
     }
-    macaroonPath := fmt.Sprintf(
-        "/var/lib/lnd/data/chain/bitcoin/%s/admin.macaroon", network)
+    macaroonPath := paths.Live.LNDMacaroon(network)
     content := fmt.Sprintf(`# Virtual Private Node — Lightning Terminal
 uipassword=%s
 lnd-mode=remote
 network=%s
-lit-dir=/var/lib/lit
+lit-dir=%s
 
 remote.lnd.rpcserver=localhost:10009
 remote.lnd.macaroonpath=%s
-remote.lnd.tlscertpath=/var/lib/lnd/tls.cert
+remote.lnd.tlscertpath=%s
 
 faraday-mode=disable
 loop-mode=disable
@@ -132,12 +121,13 @@ taproot-assets-mode=disable
 autopilot.disable=true
 
 httpslisten=127.0.0.1:8443
-`, uiPassword, cfg.Network, macaroonPath)
+`, uiPassword, cfg.Network, paths.Live.LITData(), macaroonPath,
+        paths.Live.LNDTLSCert())
 
-    if err := os.WriteFile("/etc/lit/lit.conf", []byte(content), 0640); err != nil {
+    if err := sys.WriteFile(paths.Node.LITConf(), []byte(content), 0640); err != nil {
         return err
     }
-    if output, err := exec.Command("chown", "root:"+systemUser, "/etc/lit/lit.conf").CombinedOutput(); err != nil {
+    if output, err := sys.Run("chown", "root:"+systemUser, paths.Live.LITConf()); err != nil {
         return fmt.Errorf("chown lit.conf: %s: %s", err, output)
     }
     return nil
@@ -153,7 +143,7 @@ Wants=lnd.service
 Type=simple
 User=%s
 Group=%s
-ExecStart=/usr/local/bin/litd --configfile=/etc/lit/lit.conf
+ExecStart=%s --configfile=%s
 Restart=on-failure
 RestartSec=30
 TimeoutStopSec=120
@@ -163,12 +153,12 @@ NoNewPrivileges=true
 
 [Install]
 WantedBy=multi-user.target
-`, username, username)
-    return os.WriteFile("/etc/systemd/system/litd.service", []byte(content), 0644)
+`, username, username, paths.Live.Bin("litd"), paths.Live.LITConf())
+    return sys.WriteFile(paths.Node.Unit("litd.service"), []byte(content), 0644)
 }
 
 func addLITTorService() error {
-    data, err := os.ReadFile("/etc/tor/torrc")
+    data, err := sys.ReadFile(paths.Node.TorConfig())
     if err != nil {
         return err
     }
@@ -177,10 +167,11 @@ func addLITTorService() error {
     }
     addition := `
 # Lightning Terminal web UI (Tor only)
-HiddenServiceDir /var/lib/tor/lnd-lit/
+HiddenServiceDir %s/
 HiddenServicePort 8443 127.0.0.1:8443
 `
-    return os.WriteFile("/etc/tor/torrc", append(data, []byte(addition)...), 0644)
+    addition = fmt.Sprintf(addition, paths.Live.OnionDir("lnd-lit"))
+    return sys.WriteFile(paths.Node.TorConfig(), append(data, []byte(addition)...), 0644)
 }
 
 func startLITD() error {
@@ -189,8 +180,7 @@ func startLITD() error {
         {"systemctl", "enable", "litd"},
         {"systemctl", "start", "litd"},
     } {
-        cmd := exec.Command(args[0], args[1:]...)
-        if output, err := cmd.CombinedOutput(); err != nil {
+        if output, err := sys.Run(args[0], args[1:]...); err != nil {
             return fmt.Errorf("%v: %s: %s", args, err, output)
         }
     }
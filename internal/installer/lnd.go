@@ -1,14 +1,13 @@
 package installer
 
 import (
-    "crypto/tls"
-    "crypto/x509"
+    "context"
     "fmt"
-    "net/http"
-    "os"
-    "os/exec"
     "strings"
     "time"
+
+    "github.com/ripsline/virtual-private-node/internal/lndrest"
+    "github.com/ripsline/virtual-private-node/internal/paths"
 )
 
 func downloadLND(version string) error {
@@ -17,51 +16,42 @@ func downloadLND(version string) error {
         version, filename)
     manifestURL := fmt.Sprintf("https://github.com/lightningnetwork/lnd/releases/download/v%s/manifest-v%s.txt",
         version, version)
-    if err := download(url, "/tmp/"+filename); err != nil {
+    if err := download(url, paths.Node.Tmp(filename)); err != nil {
         return err
     }
     // Hard-fail if manifest download fails
-    if err := download(manifestURL, "/tmp/manifest.txt"); err != nil {
+    if err := download(manifestURL, paths.Node.Tmp("manifest.txt")); err != nil {
         return fmt.Errorf("download LND manifest: %w", err)
     }
     return nil
 }
 
 func verifyLND(version string) error {
-    if _, err := os.Stat("/tmp/manifest.txt"); err != nil {
-        return fmt.Errorf("LND manifest not found")
-    }
-    cmd := exec.Command("sha256sum", "--ignore-missing", "--check", "manifest.txt")
-    cmd.Dir = "/tmp"
-    if output, err := cmd.CombinedOutput(); err != nil {
-        return fmt.Errorf("checksum failed: %s: %s", err, output)
-    }
-    return nil
+    filename := fmt.Sprintf("lnd-linux-amd64-v%s.tar.gz", version)
+    return verifyChecksum(paths.Node.Tmp("manifest.txt"), paths.Node.Tmp(filename))
 }
 
 func extractAndInstallLND(version string) error {
     filename := fmt.Sprintf("lnd-linux-amd64-v%s.tar.gz", version)
-    cmd := exec.Command("tar", "-xzf", "/tmp/"+filename, "-C", "/tmp")
-    if output, err := cmd.CombinedOutput(); err != nil {
+    if output, err := sys.Run("tar", "-xzf", paths.Live.Tmp(filename), "-C", paths.Live.Tmp("")); err != nil {
         return fmt.Errorf("extract: %s: %s", err, output)
     }
-    extractDir := fmt.Sprintf("/tmp/lnd-linux-amd64-v%s", version)
+    extractDir := fmt.Sprintf("lnd-linux-amd64-v%s", version)
     for _, bin := range []string{"lnd", "lncli"} {
-        src := fmt.Sprintf("%s/%s", extractDir, bin)
-        cmd = exec.Command("install", "-m", "0755", "-o", "root", "-g", "root",
-            src, "/usr/local/bin/")
-        if output, err := cmd.CombinedOutput(); err != nil {
+        src := paths.Live.Tmp(extractDir + "/" + bin)
+        if output, err := sys.Run("install", "-m", "0755", "-o", "root", "-g", "root",
+            src, paths.Live.Bin("")+"/"); err != nil {
             return fmt.Errorf("install %s: %s: %s", bin, err, output)
         }
     }
-    os.Remove("/tmp/" + filename)
-    os.Remove("/tmp/manifest.txt")
-    os.RemoveAll(extractDir)
+    sys.Remove(paths.Node.Tmp(filename))
+    sys.Remove(paths.Node.Tmp("manifest.txt"))
+    sys.RemoveAll(paths.Node.Tmp(extractDir))
     return nil
 }
 
 func writeLNDConfig(cfg *installConfig) error {
-    restOnion := strings.TrimSpace(readFileOrDefault("/var/lib/tor/lnd-rest/hostname", ""))
+    restOnion := strings.TrimSpace(readFileOrDefault(paths.Node.OnionHostname("lnd-rest"), ""))
     listenLine := "listen=localhost:9735"
     externalLine := ""
     if cfg.p2pMode == "hybrid" && cfg.publicIPv4 != "" {
@@ -72,11 +62,11 @@ func writeLNDConfig(cfg *installConfig) error {
     if restOnion != "" {
         tlsExtraDomain = fmt.Sprintf("tlsextradomain=%s", restOnion)
     }
-    cookiePath := fmt.Sprintf("/var/lib/bitcoin/%s", cfg.network.CookiePath)
+    cookiePath := paths.Live.BitcoinCookie(cfg.network.Name)
 
     content := fmt.Sprintf(`# Virtual Private Node — LND
 [Application Options]
-lnddir=/var/lib/lnd
+lnddir=%s
 %s
 rpclisten=localhost:10009
 restlisten=localhost:8080
@@ -87,11 +77,11 @@ debuglevel=info
 [Bitcoin]
 bitcoin.active=true
 %s
-bitcoin.node=bitcoind
+%sbitcoin.node=bitcoind
 
 [Bitcoind]
-bitcoind.dir=/var/lib/bitcoin
-bitcoind.config=/etc/bitcoin/bitcoin.conf
+bitcoind.dir=%s
+bitcoind.config=%s
 bitcoind.rpccookie=%s
 bitcoind.rpchost=127.0.0.1:%d
 bitcoind.zmqpubrawblock=tcp://127.0.0.1:%d
@@ -104,14 +94,15 @@ tor.control=127.0.0.1:9051
 tor.targetipaddress=127.0.0.1
 tor.v3=true
 tor.streamisolation=true
-`, listenLine, externalLine, tlsExtraDomain,
-        cfg.network.LNDBitcoinFlag, cookiePath,
+`, paths.Live.LNDData(), listenLine, externalLine, tlsExtraDomain,
+        cfg.network.LNDBitcoinFlag, cfg.network.lndExtra(), paths.Live.BitcoinData(),
+        paths.Live.BitcoinConf(), cookiePath,
         cfg.network.RPCPort, cfg.network.ZMQBlockPort, cfg.network.ZMQTxPort)
 
-    if err := os.WriteFile("/etc/lnd/lnd.conf", []byte(content), 0640); err != nil {
+    if err := sys.WriteFile(paths.Node.LNDConf(), []byte(content), 0640); err != nil {
         return err
     }
-    if output, err := exec.Command("chown", "root:"+systemUser, "/etc/lnd/lnd.conf").CombinedOutput(); err != nil {
+    if output, err := sys.Run("chown", "root:"+systemUser, paths.Live.LNDConf()); err != nil {
         return fmt.Errorf("chown lnd.conf: %s: %s", err, output)
     }
     return nil
@@ -127,7 +118,7 @@ Wants=bitcoind.service
 Type=simple
 User=%s
 Group=%s
-ExecStart=/usr/local/bin/lnd --configfile=/etc/lnd/lnd.conf
+ExecStart=%s --configfile=%s
 Restart=on-failure
 RestartSec=30
 TimeoutStopSec=300
@@ -137,8 +128,8 @@ NoNewPrivileges=true
 
 [Install]
 WantedBy=multi-user.target
-`, username, username)
-    return os.WriteFile("/etc/systemd/system/lnd.service", []byte(content), 0644)
+`, username, username, paths.Live.Bin("lnd"), paths.Live.LNDConf())
+    return sys.WriteFile(paths.Node.Unit("lnd.service"), []byte(content), 0644)
 }
 
 func startLND() error {
@@ -147,8 +138,7 @@ func startLND() error {
         {"systemctl", "enable", "lnd"},
         {"systemctl", "start", "lnd"},
     } {
-        cmd := exec.Command(args[0], args[1:]...)
-        if output, err := cmd.CombinedOutput(); err != nil {
+        if output, err := sys.Run(args[0], args[1:]...); err != nil {
             return fmt.Errorf("%v: %s: %s", args, err, output)
         }
     }
@@ -156,11 +146,10 @@ func startLND() error {
 }
 
 func setupAutoUnlock(password string) error {
-    passwordFile := "/var/lib/lnd/wallet_password"
-    if err := os.WriteFile(passwordFile, []byte(password), 0400); err != nil {
+    if err := sys.WriteFile(paths.Node.LNDWalletPassword(), []byte(password), 0400); err != nil {
         return err
     }
-    exec.Command("chown", systemUser+":"+systemUser, passwordFile).Run()
+    sys.Run("chown", systemUser+":"+systemUser, paths.Live.LNDWalletPassword())
 
     content := fmt.Sprintf(`[Unit]
 Description=LND Lightning Network Daemon
@@ -171,7 +160,7 @@ Wants=bitcoind.service
 Type=simple
 User=%s
 Group=%s
-ExecStart=/usr/local/bin/lnd --configfile=/etc/lnd/lnd.conf --wallet-unlock-password-file=/var/lib/lnd/wallet_password
+ExecStart=%s --configfile=%s --wallet-unlock-password-file=%s
 Restart=on-failure
 RestartSec=30
 TimeoutStopSec=300
@@ -181,31 +170,38 @@ NoNewPrivileges=true
 
 [Install]
 WantedBy=multi-user.target
-`, systemUser, systemUser)
+`, systemUser, systemUser, paths.Live.Bin("lnd"), paths.Live.LNDConf(),
+        paths.Live.LNDWalletPassword())
 
-    if err := os.WriteFile("/etc/systemd/system/lnd.service", []byte(content), 0644); err != nil {
+    if err := sys.WriteFile(paths.Node.Unit("lnd.service"), []byte(content), 0644); err != nil {
         return err
     }
     for _, args := range [][]string{
         {"systemctl", "daemon-reload"},
         {"systemctl", "restart", "lnd"},
     } {
-        cmd := exec.Command(args[0], args[1:]...)
-        if output, err := cmd.CombinedOutput(); err != nil {
+        if output, err := sys.Run(args[0], args[1:]...); err != nil {
             return fmt.Errorf("%v: %s: %s", args, err, output)
         }
     }
     return nil
 }
 
-// waitForLND polls LND's REST endpoint with TLS cert pinning.
-// Falls back to insecure if cert doesn't exist yet (first start race).
+// newLNDClient returns a REST client for LND, authenticated for
+// network's wallet. A variable so tests can stand in for LND.
+var newLNDClient = func(network string) *lndrest.Client {
+    return lndrest.New(paths.Node.LNDTLSCert(), paths.Node.LNDMacaroon(network))
+}
+
+// waitForLND polls LND's REST endpoint. The client pins LND's
+// cert once LND has written it on first start.
 func waitForLND() error {
+    lnd := newLNDClient("")
     for i := 0; i < 60; i++ {
-        client := buildLNDClient()
-        resp, err := client.Get("https://localhost:8080/v1/state")
+        ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+        _, err := lnd.State(ctx)
+        cancel()
         if err == nil {
-            resp.Body.Close()
             return nil
         }
         time.Sleep(2 * time.Second)
@@ -213,21 +209,11 @@ func waitForLND() error {
     return fmt.Errorf("LND did not respond after 120 seconds")
 }
 
-// buildLNDClient creates an HTTP client that pins LND's TLS cert
-// if available, otherwise falls back to InsecureSkipVerify.
-func buildLNDClient() *http.Client {
-    tlsConfig := &tls.Config{InsecureSkipVerify: true}
-
-    certData, err := os.ReadFile("/var/lib/lnd/tls.cert")
-    if err == nil {
-        pool := x509.NewCertPool()
-        if pool.AppendCertsFromPEM(certData) {
-            tlsConfig = &tls.Config{RootCAs: pool}
-        }
-    }
-
-    return &http.Client{
-        Transport: &http.Transport{TLSClientConfig: tlsConfig},
-        Timeout:   5 * time.Second,
-    }
+// lndState returns LND's state from /v1/state, such as LOCKED or
+// RPC_ACTIVE. State needs no macaroon, so no network is given.
+// A variable so tests can stand in for LND.
+var lndState = func() (string, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    return newLNDClient("").State(ctx)
 }
\ No newline at end of file
@@ -0,0 +1,123 @@
+package installer
+
+import (
+    "bufio"
+    "bytes"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "github.com/ripsline/virtual-private-node/internal/paths"
+)
+
+// checksumError reports a release file whose SHA256 differs from
+// the one listed in its signed manifest.
+type checksumError struct {
+    file     string
+    manifest string
+    want     string
+    got      string
+}
+
+func (e *checksumError) Error() string {
+    return fmt.Sprintf("checksum mismatch for %s: %s lists %s, file is %s",
+        e.file, filepath.Base(e.manifest), e.want, e.got)
+}
+
+// parseManifest reads sha256sum-style lines ("<hex>  <name>", or
+// "<hex> *<name>" for binary mode) into a name → hash map. Blank
+// lines and lines that are not checksums, such as the PGP armor
+// some manifests carry, are skipped.
+func parseManifest(data []byte) map[string]string {
+    sums := make(map[string]string)
+    sc := bufio.NewScanner(bytes.NewReader(data))
+    for sc.Scan() {
+        fields := strings.Fields(sc.Text())
+        if len(fields) != 2 || !isSHA256(fields[0]) {
+            continue
+        }
+        name := strings.TrimPrefix(fields[1], "*")
+        sums[name] = strings.ToLower(fields[0])
+    }
+    return sums
+}
+
+func isSHA256(s string) bool {
+    if len(s) != sha256.Size*2 {
+        return false
+    }
+    _, err := hex.DecodeString(s)
+    return err == nil
+}
+
+// verifyChecksum hashes file and compares it with the entry for
+// its base name in manifest. A manifest without that exact entry
+// is an error, unlike `sha256sum --ignore-missing`. On success
+// the hash is recorded in paths.Node.VerifiedHashes().
+func verifyChecksum(manifest, file string) error {
+    data, err := sys.ReadFile(manifest)
+    if err != nil {
+        return fmt.Errorf("read manifest: %w", err)
+    }
+    name := filepath.Base(file)
+    want, ok := parseManifest(data)[name]
+    if !ok {
+        return fmt.Errorf("%s is not listed in %s", name, filepath.Base(manifest))
+    }
+    got, err := sha256File(file)
+    if err != nil {
+        return err
+    }
+    if got != want {
+        return &checksumError{file: name, manifest: manifest, want: want, got: got}
+    }
+    return recordVerifiedHash(name, got, filepath.Base(manifest))
+}
+
+func sha256File(path string) (string, error) {
+    f, err := sys.Open(path)
+    if err != nil {
+        return "", fmt.Errorf("open %s: %w", path, err)
+    }
+    defer f.Close()
+    h := sha256.New()
+    if _, err := io.Copy(h, f); err != nil {
+        return "", fmt.Errorf("hash %s: %w", path, err)
+    }
+    return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifiedHash is one entry in verified-hashes.json.
+type verifiedHash struct {
+    SHA256     string    `json:"sha256"`
+    Manifest   string    `json:"manifest"`
+    Signers    []string  `json:"signers,omitempty"`
+    VerifiedAt time.Time `json:"verified_at"`
+}
+
+// recordVerifiedHash adds name to verified-hashes.json, keeping
+// entries for other files so the record covers every release the
+// node has installed.
+func recordVerifiedHash(name, sum, manifest string) error {
+    hashes := make(map[string]verifiedHash)
+    if data, err := sys.ReadFile(paths.Node.VerifiedHashes()); err == nil {
+        json.Unmarshal(data, &hashes)
+    }
+    hashes[name] = verifiedHash{
+        SHA256: sum, Manifest: manifest, Signers: manifestSigners[manifest],
+        VerifiedAt: time.Now().UTC(),
+    }
+    data, err := json.MarshalIndent(hashes, "", "  ")
+    if err != nil {
+        return err
+    }
+    if err := sys.MkdirAll(paths.Node.RLVPNDir(), 0755); err != nil {
+        return err
+    }
+    return sys.WriteFile(paths.Node.VerifiedHashes(), data, 0644)
+}
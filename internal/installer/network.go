@@ -1,5 +1,16 @@
 package installer
 
+import (
+    "encoding/hex"
+    "fmt"
+    "net"
+    "strings"
+)
+
+// NetworkNames are the networks a node can run, and the values
+// accepted by `rlvpn network switch`.
+var NetworkNames = []string{"mainnet", "testnet4", "signet", "regtest"}
+
 type NetworkConfig struct {
     Name           string
     BitcoinFlag    string
@@ -9,8 +20,16 @@ type NetworkConfig struct {
     ZMQBlockPort   int
     ZMQTxPort      int
     LNCLINetwork   string
-    CookiePath     string
     DataSubdir     string
+
+    // ChainSizeGB is roughly what an unpruned node with txindex
+    // and block filters needs for this chain today.
+    ChainSizeGB int
+
+    // SignetChallenge and SignetSeedNode select a custom signet.
+    // Both empty means the default public signet.
+    SignetChallenge string
+    SignetSeedNode  string
 }
 
 func Mainnet() *NetworkConfig {
@@ -19,7 +38,7 @@ func Mainnet() *NetworkConfig {
         LNDBitcoinFlag: "bitcoin.mainnet=true",
         RPCPort: 8332, P2PPort: 8333,
         ZMQBlockPort: 28332, ZMQTxPort: 28333,
-        LNCLINetwork: "mainnet", CookiePath: ".cookie",
+        LNCLINetwork: "mainnet", ChainSizeGB: 800,
     }
 }
 
@@ -29,13 +48,105 @@ func Testnet4() *NetworkConfig {
         LNDBitcoinFlag: "bitcoin.testnet4=true",
         RPCPort: 48332, P2PPort: 48333,
         ZMQBlockPort: 28334, ZMQTxPort: 28335,
-        LNCLINetwork: "testnet4", CookiePath: "testnet4/.cookie",
+        LNCLINetwork: "testnet4", DataSubdir: "testnet4", ChainSizeGB: 40,
+    }
+}
+
+func Signet() *NetworkConfig {
+    return &NetworkConfig{
+        Name: "signet", BitcoinFlag: "signet=1",
+        LNDBitcoinFlag: "bitcoin.signet=true",
+        RPCPort: 38332, P2PPort: 38333,
+        ZMQBlockPort: 28336, ZMQTxPort: 28337,
+        LNCLINetwork: "signet", DataSubdir: "signet", ChainSizeGB: 30,
+    }
+}
+
+// Regtest is a private chain for development: no peers, blocks
+// are mined on demand from the dashboard.
+func Regtest() *NetworkConfig {
+    return &NetworkConfig{
+        Name: "regtest", BitcoinFlag: "regtest=1",
+        LNDBitcoinFlag: "bitcoin.regtest=true",
+        RPCPort: 18443, P2PPort: 18444,
+        ZMQBlockPort: 28338, ZMQTxPort: 28339,
+        LNCLINetwork: "regtest", DataSubdir: "regtest", ChainSizeGB: 1,
+    }
+}
+
+// CustomSignet is Signet with the challenge script and seed node
+// of a private signet.
+func CustomSignet(challenge, seedNode string) *NetworkConfig {
+    n := Signet()
+    n.SignetChallenge = challenge
+    n.SignetSeedNode = seedNode
+    return n
+}
+
+// NetworkConfigFromName returns the network called name, which
+// must be one of NetworkNames.
+func NetworkConfigFromName(name string) (*NetworkConfig, error) {
+    switch name {
+    case "mainnet":
+        return Mainnet(), nil
+    case "testnet4":
+        return Testnet4(), nil
+    case "signet":
+        return Signet(), nil
+    case "regtest":
+        return Regtest(), nil
+    }
+    return nil, fmt.Errorf("unknown network %q (want one of %s)",
+        name, strings.Join(NetworkNames, ", "))
+}
+
+// networkConfig is NetworkConfigFromName plus the custom signet
+// parameters, which only apply when name is "signet".
+func networkConfig(name, signetChallenge, signetSeedNode string) (*NetworkConfig, error) {
+    if name == "signet" {
+        return CustomSignet(signetChallenge, signetSeedNode), nil
     }
+    return NetworkConfigFromName(name)
 }
 
-func NetworkConfigFromName(name string) *NetworkConfig {
-    if name == "mainnet" {
-        return Mainnet()
+// validateSignet checks custom signet parameters; empty ones are
+// not set.
+func validateSignet(challenge, seedNode string) error {
+    if challenge != "" {
+        if _, err := hex.DecodeString(challenge); err != nil {
+            return fmt.Errorf("signet_challenge: %q is not a hex script", challenge)
+        }
+    }
+    if seedNode != "" {
+        if _, _, err := net.SplitHostPort(seedNode); err != nil {
+            return fmt.Errorf("signet_seednode: %q is not host:port", seedNode)
+        }
     }
-    return Testnet4()
-}
\ No newline at end of file
+    return nil
+}
+
+// bitcoinExtra returns the lines appended to the network's
+// section of bitcoin.conf for a custom signet.
+func (n *NetworkConfig) bitcoinExtra() string {
+    var s string
+    if n.SignetChallenge != "" {
+        s += fmt.Sprintf("signetchallenge=%s\n", n.SignetChallenge)
+    }
+    if n.SignetSeedNode != "" {
+        s += fmt.Sprintf("signetseednode=%s\n", n.SignetSeedNode)
+    }
+    return s
+}
+
+// lndExtra returns the lines appended to lnd.conf's [Bitcoin]
+// section for a custom signet.
+func (n *NetworkConfig) lndExtra() string {
+    var s string
+    if n.SignetChallenge != "" {
+        s += fmt.Sprintf("bitcoin.signetchallenge=%s\n", n.SignetChallenge)
+    }
+    if n.SignetSeedNode != "" {
+        s += fmt.Sprintf("bitcoin.signetseednode=%s\n", n.SignetSeedNode)
+    }
+    return s
+}
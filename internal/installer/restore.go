@@ -0,0 +1,273 @@
+package installer
+
+import (
+    "bufio"
+    "context"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/ripsline/virtual-private-node/internal/config"
+    "github.com/ripsline/virtual-private-node/internal/lndrest"
+    "github.com/ripsline/virtual-private-node/internal/paths"
+)
+
+// A restore hands LND an existing aezeed seed instead of letting
+// lncli create a new one. LND then rescans the chain for the
+// wallet's funds and, given a channel.backup, asks every channel
+// peer to force close so those funds come back on-chain too.
+
+// defaultRecoveryWindow is how many addresses per account LND
+// looks ahead while rescanning, the default lncli uses.
+const defaultRecoveryWindow = 2500
+
+// recoveryPoll is how often the rescan's progress is checked. A
+// variable so tests need not wait.
+var recoveryPoll = 5 * time.Second
+
+// parseSeed splits a typed seed into its 24 words.
+func parseSeed(s string) ([]string, error) {
+    words := strings.Fields(strings.ToLower(s))
+    if len(words) != 24 {
+        return nil, fmt.Errorf("got %d words, an aezeed seed has 24", len(words))
+    }
+    for i, w := range words {
+        for _, r := range w {
+            if r < 'a' || r > 'z' {
+                return nil, fmt.Errorf("word %d, %q, is not a seed word", i+1, w)
+            }
+        }
+    }
+    return words, nil
+}
+
+// syncedChannelBackup returns the channel.backup Syncthing keeps
+// a copy of, or "" if there is none.
+func syncedChannelBackup() string {
+    path := paths.Node.SyncthingBackupDir() + "/channel.backup"
+    if _, err := sys.Stat(path); err != nil {
+        return ""
+    }
+    return path
+}
+
+// readChannelBackup reads a channel.backup file. LND checks the
+// contents itself, since they are encrypted with the seed.
+func readChannelBackup(path string) ([]byte, error) {
+    data, err := sys.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    if len(data) == 0 {
+        return nil, fmt.Errorf("%s is empty", path)
+    }
+    return data, nil
+}
+
+// restoreWallet creates the wallet from req and waits for the
+// rescan to finish, passing each progress report to progress.
+// LND answers with errors until the new wallet is unlocked, which
+// is tolerated for up to healthTimeout.
+func restoreWallet(cfg *config.AppConfig, req lndrest.InitWalletRequest, progress func(*lndrest.RecoveryInfo)) error {
+    network, err := NetworkConfigFromName(cfg.Network)
+    if err != nil {
+        return err
+    }
+    lnd := newLNDClient(network.LNCLINetwork)
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    state, err := lnd.State(ctx)
+    cancel()
+    if err != nil {
+        return err
+    }
+    if state != "NON_EXISTING" {
+        return fmt.Errorf("LND already has a wallet (state %s)", state)
+    }
+    ctx, cancel = context.WithTimeout(context.Background(), 2*time.Minute)
+    err = lnd.InitWallet(ctx, req)
+    cancel()
+    if err != nil {
+        return err
+    }
+
+    failingSince := time.Now()
+    for {
+        ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+        info, err := lnd.RecoveryInfo(ctx)
+        cancel()
+        if err == nil {
+            failingSince = time.Now()
+            progress(info)
+            if !info.RecoveryMode || info.RecoveryFinished {
+                return nil
+            }
+        } else if time.Since(failingSince) > healthTimeout {
+            return fmt.Errorf("recovery progress: %w", err)
+        }
+        if _, err := sys.Run("systemctl", "is-active", "--quiet", "lnd"); err != nil {
+            return fmt.Errorf("lnd stopped running")
+        }
+        time.Sleep(recoveryPoll)
+    }
+}
+
+// progressBar draws a fraction from 0 to 1 as a bar.
+func progressBar(fraction float64) string {
+    const width = 30
+    n := min(max(int(fraction*width), 0), width)
+    return "[" + strings.Repeat("█", n) + strings.Repeat("░", width-n) + "]"
+}
+
+// RunWalletRestore walks through restoring LND's wallet from a
+// seed and, optionally, a channel backup, then shows the rescan's
+// progress until it finishes.
+func RunWalletRestore(cfg *config.AppConfig) error {
+    info := setupTitleStyle.Render("Restore Your LND Wallet") + "\n\n" +
+        setupTextStyle.Render("You will need:") + "\n\n" +
+        setupTextStyle.Render("  1. Your 24-word aezeed seed phrase") + "\n" +
+        setupTextStyle.Render("  2. Its cipher seed passphrase, if you set one") + "\n" +
+        setupTextStyle.Render("  3. A new wallet password (min 8 characters)") + "\n" +
+        setupTextStyle.Render("  4. Optionally, a channel.backup file") + "\n\n" +
+        setupTextStyle.Render("With a channel backup, LND asks every peer to") + "\n" +
+        setupTextStyle.Render("force close, and the funds come back on-chain.") + "\n\n" +
+        setupWarnStyle.Render("WARNING: Stop any other node using this seed first.") + "\n" +
+        setupWarnStyle.Render("WARNING: Two nodes with one seed can lose funds.") + "\n"
+    if cfg.IsPruned() {
+        info += "\n" + setupWarnStyle.Render("This node is pruned: funds received before the") + "\n" +
+            setupWarnStyle.Render("oldest kept block cannot be found by the rescan.") + "\n"
+    }
+    info += "\n" + setupDimStyle.Render("Enter to proceed • backspace to cancel")
+    if !showConfirmBox(info) {
+        return nil
+    }
+    return reportFailure("Restore", runWalletRestore(cfg, stdin))
+}
+
+func runWalletRestore(cfg *config.AppConfig, in *bufio.Reader) error {
+    header := func() {
+        fmt.Print("\033[2J\033[H")
+        fmt.Println("\n  ═══════════════════════════════════════════")
+        fmt.Println("    LND Wallet Restore")
+        fmt.Print("  ═══════════════════════════════════════════\n\n")
+    }
+    header()
+    fmt.Println("  Waiting for LND...")
+    if err := waitForLND(); err != nil {
+        return err
+    }
+    fmt.Print("  ✓ LND is ready\n\n")
+
+    fmt.Println("  Enter your 24 seed words, separated by spaces.")
+    fmt.Println("  Several lines are fine; an empty line ends the seed.")
+    var typed []string
+    for len(typed) < 24 {
+        fmt.Printf("  [%d/24] ", len(typed))
+        line := readLine(in)
+        if line == "" {
+            break
+        }
+        typed = append(typed, strings.Fields(line)...)
+    }
+    seed, err := parseSeed(strings.Join(typed, " "))
+    if err != nil {
+        return err
+    }
+    // keep the seed off the screen from here on
+    header()
+    fmt.Print("  ✓ 24 seed words entered\n\n")
+
+    fmt.Print("  Cipher seed passphrase (Enter if none): ")
+    passphrase := readPassword()
+    fmt.Println()
+    fmt.Print("  New wallet password: ")
+    password := readPassword()
+    fmt.Println()
+    if len(password) < 8 {
+        return fmt.Errorf("the wallet password must be at least 8 characters")
+    }
+    fmt.Print("  Confirm wallet password: ")
+    if readPassword() != password {
+        fmt.Println()
+        return fmt.Errorf("the passwords do not match")
+    }
+    fmt.Print("\n\n")
+
+    window := defaultRecoveryWindow
+    fmt.Printf("  Addresses to scan per account [%d]: ", defaultRecoveryWindow)
+    if line := readLine(in); line != "" {
+        if window, err = strconv.Atoi(line); err != nil || window < 1 {
+            return fmt.Errorf("%q is not a number of addresses", line)
+        }
+    }
+
+    var backup []byte
+    backupPath := syncedChannelBackup()
+    if backupPath != "" {
+        fmt.Printf("  channel.backup [%s], 'none' to skip: ", backupPath)
+    } else {
+        fmt.Print("  Path to a channel.backup, Enter to skip: ")
+    }
+    switch line := readLine(in); line {
+    case "none":
+        backupPath = ""
+    case "":
+    default:
+        backupPath = line
+    }
+    if backupPath != "" {
+        if backup, err = readChannelBackup(backupPath); err != nil {
+            return err
+        }
+    }
+
+    fmt.Println()
+    fmt.Printf("  Network:         %s\n", cfg.Network)
+    fmt.Printf("  Recovery window: %d addresses\n", window)
+    if backupPath != "" {
+        fmt.Printf("  Channel backup:  %s (%d bytes)\n", backupPath, len(backup))
+        fmt.Println("\n  Every channel in the backup will be force closed.")
+    } else {
+        fmt.Println("  Channel backup:  none, on-chain funds only")
+    }
+    fmt.Print("\n  Type 'restore' to continue, anything else cancels: ")
+    if readLine(in) != "restore" {
+        fmt.Println("\n  Cancelled.")
+        fmt.Print("\n  Press Enter to return...")
+        readLine(in)
+        return nil
+    }
+
+    fmt.Println("\n  Restoring wallet...")
+    err = restoreWallet(cfg, lndrest.InitWalletRequest{
+        Password: password, Mnemonic: seed, Passphrase: passphrase,
+        RecoveryWindow: window, ChannelBackup: backup,
+    }, func(info *lndrest.RecoveryInfo) {
+        fmt.Printf("\r  Rescanning %s %5.1f%%", progressBar(info.Progress), info.Progress*100)
+    })
+    fmt.Println()
+    if err != nil {
+        return err
+    }
+    fmt.Print("  ✓ Wallet restored and rescan finished\n\n")
+    if backupPath != "" {
+        fmt.Println("  LND is asking your peers to force close. The Channels")
+        fmt.Println("  screen shows when each channel's funds come back.")
+        fmt.Println()
+    }
+
+    // Auto-unlock restarts LND, so it waits for the rescan, which
+    // only runs in the session the wallet was restored in.
+    if err := setupAutoUnlock(password); err != nil {
+        fmt.Printf("  Warning: %v\n", err)
+    } else {
+        fmt.Println("  ✓ Auto-unlock configured")
+        cfg.AutoUnlock = true
+        if err := config.Save(cfg); err != nil {
+            return err
+        }
+    }
+    fmt.Print("\n  Press Enter to return...")
+    readLine(in)
+    return nil
+}
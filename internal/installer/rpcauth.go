@@ -0,0 +1,204 @@
+package installer
+
+import (
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "slices"
+    "strings"
+
+    "github.com/ripsline/virtual-private-node/internal/config"
+    "github.com/ripsline/virtual-private-node/internal/paths"
+)
+
+// walletRPCMethods are the calls Sparrow makes against Bitcoin
+// Core: chain and mempool queries, its watch-only wallets, fee
+// estimates and broadcasting. Wallet logins can call nothing else.
+var walletRPCMethods = []string{
+    "getblockchaininfo", "getnetworkinfo", "uptime",
+    "getblock", "getblockhash", "getblockheader", "getblockcount", "getbestblockhash",
+    "getrawtransaction", "getmempoolentry", "getmempoolinfo", "getrawmempool",
+    "estimatesmartfee", "sendrawtransaction", "testmempoolaccept",
+    "listwallets", "listwalletdir", "createwallet", "loadwallet", "unloadwallet",
+    "getwalletinfo", "getdescriptorinfo", "listdescriptors", "importdescriptors", "importmulti",
+    "listsinceblock", "listtransactions", "listunspent", "gettransaction",
+    "rescanblockchain", "abortrescan",
+}
+
+// rpcAuthLines pulls the wallet logins into bitcoin.conf. The
+// logins are whitelisted; rpcwhitelistdefault=0 keeps the cookie,
+// which LND and local tools use, unrestricted.
+func rpcAuthLines() string {
+    return "rpcwhitelistdefault=0\nincludeconf=" + paths.Live.BitcoinRPCAuthConf() + "\n"
+}
+
+// rpcAuthEntry renders the rpcauth= value for user, as Bitcoin
+// Core's share/rpcauth/rpcauth.py does: a hex salt and the
+// HMAC-SHA256 of the password keyed with it.
+func rpcAuthEntry(user config.RPCUser, salt []byte) string {
+    s := hex.EncodeToString(salt)
+    mac := hmac.New(sha256.New, []byte(s))
+    mac.Write([]byte(user.Password))
+    return user.Name + ":" + s + "$" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// writeRPCAuthConf replaces rpcauth.conf with one whitelisted
+// login per user. Salts are fresh on every write; passwords stay
+// the same.
+func writeRPCAuthConf(users []config.RPCUser) error {
+    var b strings.Builder
+    b.WriteString("# Virtual Private Node — wallet RPC logins, managed by rlvpn\n")
+    for _, u := range users {
+        salt := make([]byte, 16)
+        if _, err := rand.Read(salt); err != nil {
+            return fmt.Errorf("generate salt: %w", err)
+        }
+        fmt.Fprintf(&b, "rpcauth=%s\n", rpcAuthEntry(u, salt))
+        fmt.Fprintf(&b, "rpcwhitelist=%s:%s\n", u.Name, strings.Join(walletRPCMethods, ","))
+    }
+    if err := sys.WriteFile(paths.Node.BitcoinRPCAuthConf(), []byte(b.String()), 0640); err != nil {
+        return err
+    }
+    if output, err := sys.Run("chown", "root:"+systemUser, paths.Live.BitcoinRPCAuthConf()); err != nil {
+        return fmt.Errorf("chown rpcauth.conf: %s: %s", err, output)
+    }
+    return nil
+}
+
+// ensureRPCAuthConf creates an empty rpcauth.conf, since bitcoind
+// refuses to start when an includeconf file is missing. Existing
+// logins are kept.
+func ensureRPCAuthConf() error {
+    if _, err := sys.Stat(paths.Node.BitcoinRPCAuthConf()); err == nil {
+        return nil
+    }
+    return writeRPCAuthConf(nil)
+}
+
+// ensureRPCAuthInclude adds rpcAuthLines to a bitcoin.conf written
+// before wallet logins existed. They go straight after server=1 so
+// they stay outside any network section.
+func ensureRPCAuthInclude() error {
+    data, err := sys.ReadFile(paths.Node.BitcoinConf())
+    if err != nil {
+        return err
+    }
+    if strings.Contains(string(data), "includeconf="+paths.Live.BitcoinRPCAuthConf()) {
+        return nil
+    }
+    head, tail, found := strings.Cut(string(data), "server=1\n")
+    if !found {
+        return fmt.Errorf("no server=1 line in %s", paths.Live.BitcoinConf())
+    }
+    content := head + "server=1\n" + rpcAuthLines() + tail
+    if err := sys.WriteFile(paths.Node.BitcoinConf(), []byte(content), 0640); err != nil {
+        return err
+    }
+    if output, err := sys.Run("chown", "root:"+systemUser, paths.Live.BitcoinConf()); err != nil {
+        return fmt.Errorf("chown bitcoin.conf: %s: %s", err, output)
+    }
+    return nil
+}
+
+// nextRPCUserName picks "sparrow", then "sparrow-2" and so on,
+// skipping names already in use.
+func nextRPCUserName(users []config.RPCUser) string {
+    taken := func(name string) bool {
+        return slices.ContainsFunc(users, func(u config.RPCUser) bool { return u.Name == name })
+    }
+    name := "sparrow"
+    for n := 2; taken(name); n++ {
+        name = fmt.Sprintf("sparrow-%d", n)
+    }
+    return name
+}
+
+// rpcAuthSteps installs users as the complete set of wallet
+// logins and restarts bitcoind to load them.
+func rpcAuthSteps(users []config.RPCUser) []installStep {
+    return []installStep{
+        {name: "Writing wallet logins", fn: func() error { return writeRPCAuthConf(users) }},
+        {name: "Configuring Bitcoin Core", fn: ensureRPCAuthInclude},
+        {name: "Restarting Bitcoin Core", fn: func() error {
+            if output, err := sys.Run("systemctl", "restart", "bitcoind"); err != nil {
+                return fmt.Errorf("restart bitcoind: %s: %s", err, output)
+            }
+            return nil
+        }},
+    }
+}
+
+// RunRPCUserAdd creates a wallet login with a random password,
+// after asking for confirmation. An empty name picks the next
+// free "sparrow" name.
+func RunRPCUserAdd(cfg *config.AppConfig, name string) error {
+    if name == "" {
+        name = nextRPCUserName(cfg.RPCUsers)
+    }
+    if strings.ContainsAny(name, ":,= \t\n") {
+        return fmt.Errorf("login name %q may not contain spaces, ':', ',' or '='", name)
+    }
+    if slices.ContainsFunc(cfg.RPCUsers, func(u config.RPCUser) bool { return u.Name == name }) {
+        return fmt.Errorf("login %s already exists", name)
+    }
+    confirmMsg := setupTitleStyle.Render("New Wallet Login: "+name) + "\n\n" +
+        setupTextStyle.Render("This will:") + "\n\n" +
+        setupTextStyle.Render("  • Create an RPC login for Sparrow with a random password") + "\n" +
+        setupTextStyle.Render("  • Allow it only the calls a wallet needs") + "\n" +
+        setupTextStyle.Render("  • Restart bitcoind") + "\n\n" +
+        setupDimStyle.Render("The password does not change when bitcoind restarts.") + "\n\n" +
+        setupDimStyle.Render("Enter to proceed • backspace to cancel")
+    if !showConfirmBox(confirmMsg) {
+        return nil
+    }
+
+    passBytes := make([]byte, 16)
+    if _, err := rand.Read(passBytes); err != nil {
+        return fmt.Errorf("generate password: %w", err)
+    }
+    users := append(slices.Clone(cfg.RPCUsers), config.RPCUser{Name: name, Password: hex.EncodeToString(passBytes)})
+
+    if err := runInstallTUI(rpcAuthSteps(users), appVersion, nil); err != nil {
+        return err
+    }
+    cfg.RPCUsers = users
+    return config.Save(cfg)
+}
+
+// RunRPCUserRevoke removes a wallet login, after asking for
+// confirmation. Wallets using it lose their connection when
+// bitcoind restarts.
+func RunRPCUserRevoke(cfg *config.AppConfig, name string) error {
+    i := slices.IndexFunc(cfg.RPCUsers, func(u config.RPCUser) bool { return u.Name == name })
+    if i < 0 {
+        return fmt.Errorf("no wallet login named %s", name)
+    }
+    confirmMsg := setupTitleStyle.Render("Revoke Wallet Login: "+name) + "\n\n" +
+        setupTextStyle.Render("This will:") + "\n\n" +
+        setupTextStyle.Render("  • Remove the RPC login "+name) + "\n" +
+        setupTextStyle.Render("  • Restart bitcoind") + "\n\n" +
+        setupWarnStyle.Render("Wallets using this login can no longer connect.") + "\n\n" +
+        setupDimStyle.Render("Enter to proceed • backspace to cancel")
+    if !showConfirmBox(confirmMsg) {
+        return nil
+    }
+    users := slices.Delete(slices.Clone(cfg.RPCUsers), i, i+1)
+    if err := runInstallTUI(rpcAuthSteps(users), appVersion, nil); err != nil {
+        return err
+    }
+    cfg.RPCUsers = users
+    return config.Save(cfg)
+}
+
+// RunRPCUserAddPrompt is RunRPCUserAdd for the dashboard, which
+// needs a failure held on screen.
+func RunRPCUserAddPrompt(cfg *config.AppConfig) error {
+    return reportFailure("New login", RunRPCUserAdd(cfg, ""))
+}
+
+// RunRPCUserRevokePrompt is RunRPCUserRevoke for the dashboard.
+func RunRPCUserRevokePrompt(cfg *config.AppConfig, name string) error {
+    return reportFailure("Revoke", RunRPCUserRevoke(cfg, name))
+}
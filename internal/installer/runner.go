@@ -0,0 +1,127 @@
+package installer
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "github.com/ripsline/virtual-private-node/internal/system"
+)
+
+// sys performs the side effects of install steps. Tests replace
+// it with a system.Recorder, `rlvpn install --plan` swaps in
+// planRunner to show what would happen without touching the
+// system, and `--root` uses stageRunner to write the files into a
+// staging directory.
+var sys system.Runner = system.Host{}
+
+// planRunner prints each action instead of performing it.
+// Commands report success with no output. Reads still go to
+// the host so the plan reflects the current files.
+type planRunner struct {
+    system.Host
+    w io.Writer
+}
+
+func (p planRunner) Run(name string, args ...string) ([]byte, error) {
+    fmt.Fprintf(p.w, "    $ %s\n", system.CommandLine(name, args...))
+    return nil, nil
+}
+
+func (p planRunner) RunIn(dir, name string, args ...string) ([]byte, error) {
+    fmt.Fprintf(p.w, "    $ cd %s && %s\n", dir, system.CommandLine(name, args...))
+    return nil, nil
+}
+
+func (p planRunner) Download(ctx context.Context, url, dest string, opts system.DownloadOptions) error {
+    via := ""
+    if opts.Proxy != "" {
+        via = " (via Tor)"
+    }
+    fmt.Fprintf(p.w, "    download %s → %s%s\n", url, dest, via)
+    return nil
+}
+
+func (p planRunner) WriteFile(path string, data []byte, perm os.FileMode) error {
+    fmt.Fprintf(p.w, "    write %s (mode %04o)\n", path, perm)
+    p.printContent(data)
+    return nil
+}
+
+func (p planRunner) AppendFile(path string, data []byte, perm os.FileMode) error {
+    fmt.Fprintf(p.w, "    append %s\n", path)
+    p.printContent(data)
+    return nil
+}
+
+func (p planRunner) MkdirAll(path string, perm os.FileMode) error {
+    fmt.Fprintf(p.w, "    mkdir -p %s (mode %04o)\n", path, perm)
+    return nil
+}
+
+func (p planRunner) Chmod(path string, perm os.FileMode) error {
+    fmt.Fprintf(p.w, "    chmod %04o %s\n", perm, path)
+    return nil
+}
+
+func (p planRunner) Remove(path string) error {
+    fmt.Fprintf(p.w, "    rm -f %s\n", path)
+    return nil
+}
+
+func (p planRunner) RemoveAll(path string) error {
+    fmt.Fprintf(p.w, "    rm -rf %s\n", path)
+    return nil
+}
+
+// stageRunner renders a node into the directory given with
+// `rlvpn install --root`. Files and directories are created for
+// real, since every step writes through paths.Node; commands are
+// only listed, as they would act on the host rather than the
+// staging tree.
+type stageRunner struct {
+    planRunner
+}
+
+func (s stageRunner) WriteFile(path string, data []byte, perm os.FileMode) error {
+    fmt.Fprintf(s.w, "    write %s (mode %04o)\n", path, perm)
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+        return err
+    }
+    return s.Host.WriteFile(path, data, perm)
+}
+
+func (s stageRunner) AppendFile(path string, data []byte, perm os.FileMode) error {
+    fmt.Fprintf(s.w, "    append %s\n", path)
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+        return err
+    }
+    return s.Host.AppendFile(path, data, perm)
+}
+
+func (s stageRunner) MkdirAll(path string, perm os.FileMode) error {
+    return s.Host.MkdirAll(path, perm)
+}
+
+func (s stageRunner) Chmod(path string, perm os.FileMode) error {
+    return s.Host.Chmod(path, perm)
+}
+
+func (s stageRunner) Remove(path string) error {
+    return s.Host.Remove(path)
+}
+
+func (s stageRunner) RemoveAll(path string) error {
+    return s.Host.RemoveAll(path)
+}
+
+func (p planRunner) printContent(data []byte) {
+    fmt.Fprintln(p.w, "    ┌────")
+    for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+        fmt.Fprintf(p.w, "    │ %s\n", line)
+    }
+    fmt.Fprintln(p.w, "    └────")
+}
@@ -1,10 +1,14 @@
 package installer
 
 import (
+    "bufio"
     "context"
     "crypto/rand"
     "encoding/hex"
     "fmt"
+    "io"
+    "net"
+    "net/http"
     "os"
     "os/exec"
     "strings"
@@ -15,6 +19,7 @@ import (
     "golang.org/x/term"
 
     "github.com/ripsline/virtual-private-node/internal/config"
+    "github.com/ripsline/virtual-private-node/internal/paths"
 )
 
 const (
@@ -33,10 +38,11 @@ type installConfig struct {
     pruneSize  int
     p2pMode    string
     publicIPv4 string
+    downloads  string
 }
 
 func NeedsInstall() bool {
-    _, err := os.Stat("/etc/rlvpn/config.json")
+    _, err := os.Stat(paths.Node.Config())
     return err != nil
 }
 
@@ -56,15 +62,27 @@ type installStep struct {
     fn     func() error
     status stepStatus
     err    error
+    // group names steps that only make sense together, such as
+    // downloading, verifying and installing one release. A resume
+    // that lands inside a group starts again at its first step,
+    // so nothing is installed from files verified in an earlier
+    // run.
+    group string
 }
 
 type stepDoneMsg struct{ index int; err error }
 
+// downloadProgressMsg reports bytes fetched by the running step.
+type downloadProgressMsg struct{ done, total int64 }
+
 type installModel struct {
     steps         []installStep
     current       int
     done, failed  bool
     version       string
+    state         *installState
+    dlDone        int64
+    dlTotal       int64
     width, height int
 }
 
@@ -83,7 +101,7 @@ var (
     progGoodStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Bold(true)
 )
 
-func (m installModel) Init() tea.Cmd { return m.runStep(0) }
+func (m installModel) Init() tea.Cmd { return m.runStep(m.current) }
 
 func (m installModel) runStep(i int) tea.Cmd {
     return func() tea.Msg {
@@ -106,8 +124,19 @@ func (m installModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
         if msg.String() == "ctrl+c" {
             return m, tea.Quit
         }
+        if msg.String() == "r" && m.failed {
+            m.failed = false
+            m.done = false
+            m.steps[m.current].status = stepRunning
+            m.steps[m.current].err = nil
+            return m, m.runStep(m.current)
+        }
+    case downloadProgressMsg:
+        m.dlDone, m.dlTotal = msg.done, msg.total
     case stepDoneMsg:
+        m.dlDone, m.dlTotal = 0, 0
         if msg.index < len(m.steps) {
+            m.state.record(msg.index, msg.err)
             if msg.err != nil {
                 m.steps[msg.index].status = stepFailed
                 m.steps[msg.index].err = msg.err
@@ -151,6 +180,10 @@ func (m installModel) View() string {
         }
         lines = append(lines, sty.Render(fmt.Sprintf("  %s [%d/%d] %s",
             ind, i+1, len(m.steps), s.name)))
+        if s.status == stepRunning && m.dlDone > 0 {
+            lines = append(lines, progDimStyle.Render(
+                "      "+formatProgress(m.dlDone, m.dlTotal)))
+        }
         if s.status == stepFailed && s.err != nil {
             lines = append(lines, progFailStyle.Render(
                 fmt.Sprintf("      Error: %v", s.err)))
@@ -161,7 +194,7 @@ func (m installModel) View() string {
     if m.done && !m.failed {
         footer = progGoodStyle.Render("  ✓ Complete — press Enter to continue  ")
     } else if m.failed {
-        footer = progFailStyle.Render("  Failed. Press ctrl+c to exit.  ")
+        footer = progFailStyle.Render("  Failed. Press r to retry this step, ctrl+c to exit.  ")
     } else {
         footer = progDimStyle.Render("  Installing... please wait  ")
     }
@@ -169,13 +202,19 @@ func (m installModel) View() string {
     return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, full)
 }
 
-func runInstallTUI(steps []installStep, version string) error {
-    if len(steps) == 0 {
+// runInstallTUI runs the steps that are not already marked done.
+// When state is non-nil each step's outcome is persisted so the
+// install can be resumed later.
+func runInstallTUI(steps []installStep, version string, state *installState) error {
+    start := firstPending(steps)
+    if start == len(steps) {
         return nil
     }
-    steps[0].status = stepRunning
-    m := installModel{steps: steps, version: version}
+    steps[start].status = stepRunning
+    m := installModel{steps: steps, current: start, version: version, state: state}
     p := tea.NewProgram(m, tea.WithAltScreen())
+    downloads.progress = progressSender(p)
+    defer func() { downloads.progress = nil }()
     result, err := p.Run()
     if err != nil {
         return err
@@ -191,6 +230,65 @@ func runInstallTUI(steps []installStep, version string) error {
     return nil
 }
 
+// runInstallPlain runs the steps with line-by-line output for
+// provisioning scripts and other sessions without a TTY.
+func runInstallPlain(steps []installStep, state *installState) error {
+    for i, s := range steps {
+        if s.status == stepDone {
+            continue
+        }
+        fmt.Printf("  [%d/%d] %s\n", i+1, len(steps), s.name)
+        err := s.fn()
+        state.record(i, err)
+        if err != nil {
+            fmt.Printf("  ✗ %s: %v\n", s.name, err)
+            return fmt.Errorf("%s: %w", s.name, err)
+        }
+    }
+    fmt.Println("  ✓ Complete")
+    return nil
+}
+
+// progressSender forwards download progress to the progress
+// screen, at most once per 256 KiB so large files don't flood it.
+func progressSender(p *tea.Program) func(done, total int64) {
+    var last int64 = -1
+    return func(done, total int64) {
+        if last >= 0 && done >= last && done-last < 256<<10 && done != total {
+            return
+        }
+        last = done
+        p.Send(downloadProgressMsg{done: done, total: total})
+    }
+}
+
+// formatProgress renders fetched bytes as a bar when the size is
+// known, or a plain byte count when it is not.
+func formatProgress(done, total int64) string {
+    const mb = 1 << 20
+    if total <= 0 {
+        return fmt.Sprintf("%.1f MB", float64(done)/mb)
+    }
+    const width = 24
+    filled := int(done * width / total)
+    return fmt.Sprintf("%s%s %5.1f / %.1f MB",
+        strings.Repeat("█", filled), strings.Repeat("░", width-filled),
+        float64(done)/mb, float64(total)/mb)
+}
+
+func firstPending(steps []installStep) int {
+    for i, s := range steps {
+        if s.status != stepDone {
+            return i
+        }
+    }
+    return len(steps)
+}
+
+func isTerminal() bool {
+    return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
 // ── Info and Confirm boxes ───────────────────────────────
 
 var (
@@ -270,65 +368,241 @@ func showConfirmBox(content string) bool {
     return result.(confirmBoxModel).confirmed
 }
 
+// reportFailure holds a failed action's error on screen before
+// the dashboard comes back.
+func reportFailure(action string, err error) error {
+    if err != nil {
+        fmt.Printf("\n  %s failed: %v\n", action, err)
+        fmt.Print("\n  Press Enter to return...")
+        fmt.Scanln()
+    }
+    return err
+}
+
 // ── Main install flow ────────────────────────────────────
 
-func Run() error {
-    if err := checkOS(); err != nil {
-        return err
+// Options controls how Run collects answers. With an empty
+// AnswersFile the interactive questionnaire is shown.
+type Options struct {
+    AnswersFile string
+    // Plan prints every step with the files and commands it
+    // would touch instead of installing.
+    Plan bool
+}
+
+// staging reports whether --root points the install at a staging
+// directory rather than the live system.
+func staging() bool {
+    return paths.Node.Root != ""
+}
+
+func Run(opts Options) error {
+    if !opts.Plan && !staging() {
+        if err := checkOS(); err != nil {
+            return err
+        }
     }
-    cfg, err := RunTUI(appVersion)
-    if err != nil {
-        return err
+    var state *installState
+    if !opts.Plan && !staging() {
+        state, _ = loadInstallState()
+    }
+
+    var cfg *installConfig
+    var err error
+    if state != nil && opts.AnswersFile == "" {
+        if showConfirmBox(resumeMessage(state)) {
+            if cfg, err = state.config(); err != nil {
+                return err
+            }
+        } else {
+            state = nil
+        }
     }
     if cfg == nil {
-        return nil
+        if opts.AnswersFile != "" {
+            cfg, err = configFromAnswers(opts.AnswersFile)
+        } else {
+            cfg, err = RunTUI(appVersion)
+        }
+        if err != nil {
+            return err
+        }
+        if cfg == nil {
+            return nil
+        }
+        // The plan makes no network call; the address is looked
+        // up when the install runs
+        if !opts.Plan {
+            resolvePublicIP(cfg)
+        }
+        // An answers file resumes only if it asks for the same node
+        if state != nil && !state.matches(cfg) {
+            state = nil
+        }
     }
+
+    downloads.viaTor = cfg.downloads == "tor"
     steps := buildSteps(cfg)
-    if err := runInstallTUI(steps, appVersion); err != nil {
+    if opts.Plan {
+        printPlan(os.Stdout, cfg, steps)
+        return nil
+    }
+    if staging() {
+        return stageInstall(os.Stdout, cfg, steps)
+    }
+    if state != nil {
+        start := state.apply(steps)
+        if !isTerminal() && start < len(steps) {
+            fmt.Printf("  Resuming at step %d/%d\n", start+1, len(steps))
+        }
+    } else {
+        state = newInstallState(cfg, steps)
+    }
+    state.save()
+
+    if isTerminal() {
+        err = runInstallTUI(steps, appVersion, state)
+    } else {
+        err = runInstallPlain(steps, state)
+    }
+    if err != nil {
+        return err
+    }
+    if err := finishInstall(cfg); err != nil {
         return err
     }
+    clearInstallState()
+    return nil
+}
+
+func resumeMessage(st *installState) string {
+    return setupTitleStyle.Render("Resume Installation") + "\n\n" +
+        setupTextStyle.Render("A previous install did not finish:") + "\n\n" +
+        setupWarnStyle.Render(st.failedStep()) + "\n\n" +
+        setupTextStyle.Render("Resuming keeps the same choices ("+st.Network+", "+
+            st.Components+") and continues from that step.") + "\n\n" +
+        setupDimStyle.Render("Enter to resume • backspace to start over")
+}
+
+// finishInstall sets up the admin shell and records the install
+// choices. config.json existing is what marks the node installed.
+func finishInstall(cfg *installConfig) error {
     if err := setupShellEnvironment(cfg); err != nil {
         fmt.Printf("  Warning: shell setup failed: %v\n", err)
     }
     appCfg := &config.AppConfig{
         Network: cfg.network.Name, Components: cfg.components,
         PruneSize: cfg.pruneSize, P2PMode: cfg.p2pMode,
+        Downloads: cfg.downloads, BitcoinVersion: bitcoinVersion,
+        SignetChallenge: cfg.network.SignetChallenge, SignetSeedNode: cfg.network.SignetSeedNode,
+    }
+    if cfg.components == "bitcoin+lnd" {
+        appCfg.LNDVersion = lndVersion
+    }
+    data, err := config.Encode(appCfg)
+    if err != nil {
+        return err
+    }
+    if err := sys.MkdirAll(paths.Node.RLVPNDir(), 0755); err != nil {
+        return err
     }
-    return config.Save(appCfg)
+    return sys.WriteFile(paths.Node.Config(), data, 0600)
+}
+
+// printPlan runs every step against planRunner so the files and
+// commands are printed rather than applied. Steps that need the
+// output of an earlier step (downloaded tarballs, imported keys)
+// stop at that point and say so.
+func printPlan(w io.Writer, cfg *installConfig, steps []installStep) {
+    prev := sys
+    sys = planRunner{w: w}
+    defer func() { sys = prev }()
+
+    storage := fmt.Sprintf("prune %d GB", cfg.pruneSize)
+    if cfg.pruneSize == 0 {
+        storage = "unpruned"
+    }
+    fmt.Fprintf(w, "Install plan: %s, %s, %s",
+        cfg.network.Name, cfg.components, storage)
+    if cfg.components == "bitcoin+lnd" {
+        fmt.Fprintf(w, ", P2P %s", cfg.p2pMode)
+        if cfg.p2pMode == "hybrid" && cfg.publicIPv4 == "" {
+            fmt.Fprint(w, " (public IPv4 detected at install)")
+        }
+    }
+    if cfg.network.SignetChallenge != "" {
+        fmt.Fprint(w, ", custom signet")
+    }
+    if cfg.downloads == "tor" {
+        fmt.Fprint(w, ", downloads via Tor")
+    }
+    fmt.Fprintln(w)
+    for i, s := range steps {
+        fmt.Fprintf(w, "\n[%d/%d] %s\n", i+1, len(steps), s.name)
+        if err := s.fn(); err != nil {
+            fmt.Fprintf(w, "    · rest of step depends on earlier results: %v\n", err)
+        }
+    }
+    fmt.Fprintln(w, "\n[finish] Shell environment and configuration")
+    finishInstall(cfg)
+}
+
+// stageInstall renders the node under paths.Node.Root. Generated
+// files are written into the tree and commands are listed, as in
+// printPlan. Steps that need downloads stop early and say so.
+func stageInstall(w io.Writer, cfg *installConfig, steps []installStep) error {
+    prev := sys
+    sys = stageRunner{planRunner{w: w}}
+    defer func() { sys = prev }()
+
+    fmt.Fprintf(w, "Staging %s, %s into %s\n",
+        cfg.network.Name, cfg.components, paths.Node.Root)
+    for i, s := range steps {
+        fmt.Fprintf(w, "\n[%d/%d] %s\n", i+1, len(steps), s.name)
+        if err := s.fn(); err != nil {
+            fmt.Fprintf(w, "    · rest of step depends on earlier results: %v\n", err)
+        }
+    }
+    fmt.Fprintln(w, "\n[finish] Shell environment and configuration")
+    return finishInstall(cfg)
 }
 
 func buildSteps(cfg *installConfig) []installStep {
     steps := []installStep{
         {name: "Creating system user", fn: func() error { return createSystemUser(systemUser) }},
         {name: "Creating directories", fn: func() error { return createDirs(systemUser, cfg) }},
+    }
+    if cfg.pruneSize == 0 {
+        steps = append(steps,
+            installStep{name: "Checking disk space", fn: func() error { return checkChainSpace(cfg.network, 0) }})
+    }
+    steps = append(steps, []installStep{
         {name: "Disabling IPv6", fn: disableIPv6},
         {name: "Configuring firewall", fn: func() error { return configureFirewall(cfg) }},
-        {name: "Installing GPG", fn: ensureGPG},
-        {name: "Importing Bitcoin Core signing keys", fn: importBitcoinCoreKeys},
         {name: "Installing Tor", fn: installTor},
         {name: "Configuring Tor", fn: func() error { return writeTorConfig(cfg) }},
         {name: "Adding user to debian-tor group", fn: func() error { return addUserToTorGroup(systemUser) }},
         {name: "Starting Tor", fn: restartTor},
-        {name: "Downloading Bitcoin Core " + bitcoinVersion, fn: func() error { return downloadBitcoin(bitcoinVersion) }},
-        {name: "Downloading Bitcoin Core signatures", fn: func() error { return downloadBitcoinSigFile(bitcoinVersion) }},
-        {name: "Verifying Bitcoin Core signatures (2/5)", fn: func() error { return verifyBitcoinCoreSigs(2) }},
-        {name: "Verifying Bitcoin Core checksum", fn: func() error { return verifyBitcoin(bitcoinVersion) }},
-        {name: "Installing Bitcoin Core", fn: func() error { return extractAndInstallBitcoin(bitcoinVersion) }},
+        {name: "Downloading Bitcoin Core " + bitcoinVersion, group: "bitcoin", fn: func() error { return downloadBitcoin(bitcoinVersion) }},
+        {name: "Downloading Bitcoin Core signatures", group: "bitcoin", fn: func() error { return downloadBitcoinSigFile(bitcoinVersion) }},
+        {name: "Verifying Bitcoin Core signatures (2/5)", group: "bitcoin", fn: func() error { return verifyBitcoinCoreSigs(2) }},
+        {name: "Verifying Bitcoin Core checksum", group: "bitcoin", fn: func() error { return verifyBitcoin(bitcoinVersion) }},
+        {name: "Installing Bitcoin Core", group: "bitcoin", fn: func() error { return extractAndInstallBitcoin(bitcoinVersion) }},
         {name: "Configuring Bitcoin Core", fn: func() error { return writeBitcoinConfig(cfg) }},
         {name: "Creating bitcoind service", fn: func() error { return writeBitcoindService(systemUser) }},
         {name: "Starting Bitcoin Core", fn: startBitcoind},
+        {name: "Scheduling post-sync cache reduction", fn: scheduleIBDCheck},
         {name: "Installing unattended-upgrades", fn: installUnattendedUpgrades},
         {name: "Configuring auto-security-updates", fn: configureUnattendedUpgrades},
         {name: "Installing fail2ban", fn: installFail2ban},
         {name: "Configuring fail2ban", fn: configureFail2ban},
-    }
+    }...)
     if cfg.components == "bitcoin+lnd" {
         steps = append(steps,
-            installStep{name: "Importing LND signing key", fn: importLNDKey},
-            installStep{name: "Downloading LND " + lndVersion, fn: func() error { return downloadLND(lndVersion) }},
-            installStep{name: "Verifying LND signature", fn: func() error { return verifyLNDSig(lndVersion) }},
-            installStep{name: "Verifying LND checksum", fn: func() error { return verifyLND(lndVersion) }},
-            installStep{name: "Installing LND", fn: func() error { return extractAndInstallLND(lndVersion) }},
+            installStep{name: "Downloading LND " + lndVersion, group: "lnd", fn: func() error { return downloadLND(lndVersion) }},
+            installStep{name: "Verifying LND signature", group: "lnd", fn: func() error { return verifyLNDSig(lndVersion) }},
+            installStep{name: "Verifying LND checksum", group: "lnd", fn: func() error { return verifyLND(lndVersion) }},
+            installStep{name: "Installing LND", group: "lnd", fn: func() error { return extractAndInstallLND(lndVersion) }},
             installStep{name: "Configuring LND", fn: func() error { return writeLNDConfig(cfg) }},
             installStep{name: "Creating LND service", fn: func() error { return writeLNDServiceInitial(systemUser) }},
             installStep{name: "Starting LND", fn: startLND},
@@ -340,7 +614,10 @@ func buildSteps(cfg *installConfig) []installStep {
 // ── Wallet creation ──────────────────────────────────────
 
 func RunWalletCreation(networkName string) error {
-    net := NetworkConfigFromName(networkName)
+    net, err := NetworkConfigFromName(networkName)
+    if err != nil {
+        return err
+    }
     info := setupTitleStyle.Render("Create Your LND Wallet") + "\n\n" +
         setupTextStyle.Render("LND will ask you to:") + "\n\n" +
         setupTextStyle.Render("  1. Enter a wallet password (min 8 characters)") + "\n" +
@@ -359,15 +636,15 @@ func RunWalletCreation(networkName string) error {
     fmt.Print("\033[2J\033[H")
     fmt.Println("\n  ═══════════════════════════════════════════")
     fmt.Println("    LND Wallet Creation")
-    fmt.Println("  ═══════════════════════════════════════════\n")
+    fmt.Print("  ═══════════════════════════════════════════\n\n")
     fmt.Println("  Waiting for LND...")
     if err := waitForLND(); err != nil {
         return err
     }
-    fmt.Println("  ✓ LND is ready\n")
+    fmt.Print("  ✓ LND is ready\n\n")
 
     cmd := exec.Command("sudo", "-u", systemUser, "lncli",
-        "--lnddir=/var/lib/lnd", "--network="+net.LNCLINetwork, "create")
+        "--lnddir="+paths.Live.LNDData(), "--network="+net.LNCLINetwork, "create")
     cmd.Stdin = os.Stdin
     cmd.Stdout = os.Stdout
     cmd.Stderr = os.Stderr
@@ -393,7 +670,7 @@ func RunWalletCreation(networkName string) error {
     fmt.Print("\033[2J\033[H")
     fmt.Println("\n  ═══════════════════════════════════════════")
     fmt.Println("    Auto-Unlock Password")
-    fmt.Println("  ═══════════════════════════════════════════\n")
+    fmt.Print("  ═══════════════════════════════════════════\n\n")
     fmt.Print("  Re-enter your wallet password: ")
     pw := readPassword()
     fmt.Println()
@@ -416,6 +693,7 @@ func RunWalletCreation(networkName string) error {
 // ── LIT installation ─────────────────────────────────────
 
 func RunLITInstall(cfg *config.AppConfig) error {
+    downloads.viaTor = cfg.DownloadsViaTor()
     confirmMsg := setupTitleStyle.Render("Install Lightning Terminal") + "\n\n" +
         setupTextStyle.Render("This will:") + "\n\n" +
         setupTextStyle.Render("  • Download Lightning Terminal v" + litVersion) + "\n" +
@@ -434,8 +712,16 @@ func RunLITInstall(cfg *config.AppConfig) error {
     }
     litPassword := hex.EncodeToString(passBytes)
 
-    steps := []installStep{
-        {name: "Importing LIT signing key", fn: importLITKey},
+    if err := runInstallTUI(litSteps(cfg, litPassword), appVersion, nil); err != nil {
+        return err
+    }
+    cfg.LITInstalled = true
+    cfg.LITPassword = litPassword
+    return config.Save(cfg)
+}
+
+func litSteps(cfg *config.AppConfig, litPassword string) []installStep {
+    return []installStep{
         {name: "Downloading Lightning Terminal " + litVersion,
             fn: func() error { return downloadLIT(litVersion) }},
         {name: "Verifying LIT signature",
@@ -446,7 +732,7 @@ func RunLITInstall(cfg *config.AppConfig) error {
             fn: func() error { return extractAndInstallLIT(litVersion) }},
         {name: "Enabling RPC middleware in LND", fn: enableRPCMiddleware},
         {name: "Restarting LND",
-            fn: func() error { return exec.Command("systemctl", "restart", "lnd").Run() }},
+            fn: func() error { _, err := sys.Run("systemctl", "restart", "lnd"); return err }},
         {name: "Creating LIT directories", fn: createLITDirs},
         {name: "Creating LIT configuration",
             fn: func() error { return writeLITConfig(cfg, litPassword) }},
@@ -456,17 +742,12 @@ func RunLITInstall(cfg *config.AppConfig) error {
         {name: "Restarting Tor", fn: restartTor},
         {name: "Starting Lightning Terminal", fn: startLITD},
     }
-    if err := runInstallTUI(steps, appVersion); err != nil {
-        return err
-    }
-    cfg.LITInstalled = true
-    cfg.LITPassword = litPassword
-    return config.Save(cfg)
 }
 
 // ── Syncthing installation ───────────────────────────────
 
 func RunSyncthingInstall(cfg *config.AppConfig) error {
+    downloads.viaTor = cfg.DownloadsViaTor()
     confirmMsg := setupTitleStyle.Render("Install Syncthing") + "\n\n" +
         setupTextStyle.Render("This will:") + "\n\n" +
         setupTextStyle.Render("  • Install Syncthing from official repository") + "\n" +
@@ -484,7 +765,16 @@ func RunSyncthingInstall(cfg *config.AppConfig) error {
     }
     syncPassword := hex.EncodeToString(passBytes)
 
-    steps := []installStep{
+    if err := runInstallTUI(syncthingSteps(cfg, syncPassword), appVersion, nil); err != nil {
+        return err
+    }
+    cfg.SyncthingInstalled = true
+    cfg.SyncthingPassword = syncPassword
+    return config.Save(cfg)
+}
+
+func syncthingSteps(cfg *config.AppConfig, syncPassword string) []installStep {
+    return []installStep{
         {name: "Adding Syncthing repository", fn: installSyncthingRepo},
         {name: "Installing Syncthing", fn: installSyncthingPackage},
         {name: "Creating Syncthing directories", fn: createSyncthingDirs},
@@ -497,16 +787,22 @@ func RunSyncthingInstall(cfg *config.AppConfig) error {
         {name: "Setting up channel backup watcher",
             fn: func() error { return setupChannelBackupWatcher(cfg) }},
     }
-    if err := runInstallTUI(steps, appVersion); err != nil {
-        return err
-    }
-    cfg.SyncthingInstalled = true
-    cfg.SyncthingPassword = syncPassword
-    return config.Save(cfg)
 }
 
 // ── Helpers ──────────────────────────────────────────────
 
+// stdin is shared by every plain-terminal prompt. A reader of its
+// own per prompt would drop whatever it had buffered past its line,
+// such as the rest of a pasted seed.
+var stdin = bufio.NewReader(os.Stdin)
+
+// readLine reads one line from in without its newline or
+// surrounding spaces.
+func readLine(in *bufio.Reader) string {
+    line, _ := in.ReadString('\n')
+    return strings.TrimSpace(line)
+}
+
 // readPassword uses golang.org/x/term for robust password input.
 // Terminal echo is always restored even if the process crashes.
 func readPassword() string {
@@ -517,33 +813,75 @@ func readPassword() string {
     return string(pw)
 }
 
-func detectPublicIP() string {
-    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-    defer cancel()
-    cmd := exec.CommandContext(ctx, "curl", "-4", "-s", "--max-time", "5", "ifconfig.me")
-    output, err := cmd.CombinedOutput()
+// publicIPURL answers with the caller's address as plain text.
+const publicIPURL = "https://ifconfig.me/ip"
+
+// detectPublicIP asks publicIPURL, over IPv4, for the address the
+// server is reached on. It returns "" if that fails. A variable so
+// tests make no network call.
+var detectPublicIP = func() string {
+    dialer := &net.Dialer{Timeout: 5 * time.Second}
+    client := &http.Client{
+        Timeout: 5 * time.Second,
+        Transport: &http.Transport{
+            DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+                return dialer.DialContext(ctx, "tcp4", addr)
+            },
+        },
+    }
+    resp, err := client.Get(publicIPURL)
     if err != nil {
         return ""
     }
-    ip := strings.TrimSpace(string(output))
-    if len(strings.Split(ip, ".")) != 4 {
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
         return ""
     }
-    return ip
+    body, err := io.ReadAll(io.LimitReader(resp.Body, 64))
+    if err != nil {
+        return ""
+    }
+    ip := net.ParseIP(strings.TrimSpace(string(body))).To4()
+    if ip == nil {
+        return ""
+    }
+    return ip.String()
+}
+
+// resolvePublicIP fills in the address LND advertises in hybrid
+// mode when the answers did not give one. Without it P2P falls
+// back to Tor only.
+func resolvePublicIP(cfg *installConfig) {
+    if cfg.p2pMode != "hybrid" || cfg.publicIPv4 != "" {
+        return
+    }
+    cfg.publicIPv4 = detectPublicIP()
+    if cfg.publicIPv4 == "" {
+        fmt.Println("  Warning: public IPv4 not detected, using Tor only P2P")
+        cfg.p2pMode = "tor"
+    }
 }
 
 func readFileOrDefault(path, def string) string {
-    data, err := os.ReadFile(path)
+    data, err := sys.ReadFile(path)
     if err != nil {
         return def
     }
     return string(data)
 }
 
+// shellBlockStart and shellBlockEnd mark the lines
+// setupShellEnvironment appends to .bashrc, so
+// removeShellEnvironment takes out that block and nothing else.
+const (
+    shellBlockStart = "# ── Virtual Private Node ──────────────────────"
+    shellBlockEnd   = "# ── End Virtual Private Node ──────────────────"
+)
+
 func setupShellEnvironment(cfg *installConfig) error {
     btcNetFlag := ""
-    if cfg.network.Name == "testnet4" {
-        btcNetFlag = "\n        -testnet4 \\"
+    if cfg.network.Name != "mainnet" {
+        btcNetFlag = "\n        -" + cfg.network.Name + " \\"
     }
 
     lndBlock := ""
@@ -555,33 +893,29 @@ func setupShellEnvironment(cfg *installConfig) error {
         }
         lndBlock = fmt.Sprintf(`
 lncli() {
-    sudo -u bitcoin /usr/local/bin/lncli \
-        --lnddir=/var/lib/lnd \%s
-        --macaroonpath=/var/lib/lnd/data/chain/bitcoin/%s/admin.macaroon \
-        --tlscertpath=/var/lib/lnd/tls.cert \
+    sudo -u bitcoin %s \
+        --lnddir=%s \%s
+        --macaroonpath=%s \
+        --tlscertpath=%s \
         "$@"
 }
 export -f lncli
-`, lndNetFlag, cfg.network.LNCLINetwork)
+`, paths.Live.Bin("lncli"), paths.Live.LNDData(), lndNetFlag,
+            paths.Live.LNDMacaroon(cfg.network.LNCLINetwork), paths.Live.LNDTLSCert())
     }
 
     content := fmt.Sprintf(`
-# ── Virtual Private Node ──────────────────────
+%s
 bitcoin-cli() {
-    sudo -u bitcoin /usr/local/bin/bitcoin-cli \
-        -datadir=/var/lib/bitcoin \
-        -conf=/etc/bitcoin/bitcoin.conf \%s
+    sudo -u bitcoin %s \
+        -datadir=%s \
+        -conf=%s \%s
         "$@"
 }
 export -f bitcoin-cli
-%s`, btcNetFlag, lndBlock)
+%s%s
+`, shellBlockStart, paths.Live.Bin("bitcoin-cli"), paths.Live.BitcoinData(),
+        paths.Live.BitcoinConf(), btcNetFlag, lndBlock, shellBlockEnd)
 
-    f, err := os.OpenFile("/home/ripsline/.bashrc",
-        os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
-    if err != nil {
-        return err
-    }
-    defer f.Close()
-    _, err = f.WriteString(content)
-    return err
+    return sys.AppendFile(paths.Node.AdminBashrc(), []byte(content), 0644)
 }
\ No newline at end of file
@@ -0,0 +1,154 @@
+package installer
+
+import (
+    "encoding/json"
+    "fmt"
+
+    "github.com/ripsline/virtual-private-node/internal/paths"
+)
+
+// installState records the install choices and per-step progress
+// so a failed or interrupted install can resume at the step that
+// did not finish. It is removed once config.json is written.
+type installState struct {
+    Network    string       `json:"network"`
+    Components string       `json:"components"`
+    PruneSize  int          `json:"prune_size"`
+    P2PMode    string       `json:"p2p_mode"`
+    PublicIPv4 string       `json:"public_ipv4,omitempty"`
+    Downloads  string       `json:"downloads,omitempty"`
+
+    SignetChallenge string `json:"signet_challenge,omitempty"`
+    SignetSeedNode  string `json:"signet_seednode,omitempty"`
+
+    Steps      []stepRecord `json:"steps"`
+}
+
+type stepRecord struct {
+    Name   string `json:"name"`
+    Done   bool   `json:"done"`
+    Failed bool   `json:"failed,omitempty"`
+    Error  string `json:"error,omitempty"`
+}
+
+func newInstallState(cfg *installConfig, steps []installStep) *installState {
+    st := &installState{
+        Network: cfg.network.Name, Components: cfg.components,
+        PruneSize: cfg.pruneSize, P2PMode: cfg.p2pMode,
+        PublicIPv4: cfg.publicIPv4, Downloads: cfg.downloads,
+        SignetChallenge: cfg.network.SignetChallenge, SignetSeedNode: cfg.network.SignetSeedNode,
+    }
+    for _, s := range steps {
+        st.Steps = append(st.Steps, stepRecord{Name: s.name})
+    }
+    return st
+}
+
+func loadInstallState() (*installState, error) {
+    data, err := sys.ReadFile(paths.Node.InstallState())
+    if err != nil {
+        return nil, err
+    }
+    var st installState
+    if err := json.Unmarshal(data, &st); err != nil {
+        return nil, fmt.Errorf("parse %s: %w", paths.Node.InstallState(), err)
+    }
+    return &st, nil
+}
+
+func (st *installState) save() error {
+    if err := sys.MkdirAll(paths.Node.RLVPNDir(), 0755); err != nil {
+        return err
+    }
+    data, err := json.MarshalIndent(st, "", "  ")
+    if err != nil {
+        return err
+    }
+    return sys.WriteFile(paths.Node.InstallState(), data, 0600)
+}
+
+func clearInstallState() {
+    sys.Remove(paths.Node.InstallState())
+}
+
+// config rebuilds the installConfig the state was created with.
+func (st *installState) config() (*installConfig, error) {
+    network, err := networkConfig(st.Network, st.SignetChallenge, st.SignetSeedNode)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", paths.Node.InstallState(), err)
+    }
+    return &installConfig{
+        network: network, components: st.Components,
+        pruneSize: st.PruneSize, p2pMode: st.P2PMode, publicIPv4: st.PublicIPv4,
+        downloads: st.Downloads,
+    }, nil
+}
+
+// record stores the outcome of step i and writes the file.
+// Persisting is best effort; a failed write only costs the
+// ability to resume.
+func (st *installState) record(i int, err error) {
+    if st == nil || i >= len(st.Steps) {
+        return
+    }
+    st.Steps[i].Done = err == nil
+    st.Steps[i].Failed = err != nil
+    st.Steps[i].Error = ""
+    if err != nil {
+        st.Steps[i].Error = err.Error()
+    }
+    st.save()
+}
+
+// apply marks steps that already completed as done, matching by
+// name so a state file from a different step list is harmless.
+// It returns the index of the first step still to run, moved back
+// to the start of that step's group.
+func (st *installState) apply(steps []installStep) int {
+    done := make(map[string]bool)
+    for _, r := range st.Steps {
+        if r.Done {
+            done[r.Name] = true
+        }
+    }
+    first := 0
+    for first < len(steps) && done[steps[first].name] {
+        first++
+    }
+    for first > 0 && first < len(steps) && steps[first].group != "" &&
+        steps[first-1].group == steps[first].group {
+        first--
+    }
+    st.Steps = st.Steps[:0]
+    for i := range steps {
+        rec := stepRecord{Name: steps[i].name}
+        if i < first {
+            steps[i].status = stepDone
+            rec.Done = true
+        }
+        st.Steps = append(st.Steps, rec)
+    }
+    return first
+}
+
+// failedStep describes where the previous attempt stopped.
+func (st *installState) failedStep() string {
+    for i, r := range st.Steps {
+        if r.Failed {
+            return fmt.Sprintf("[%d/%d] %s: %s", i+1, len(st.Steps), r.Name, r.Error)
+        }
+        if !r.Done {
+            return fmt.Sprintf("[%d/%d] %s (interrupted)", i+1, len(st.Steps), r.Name)
+        }
+    }
+    return ""
+}
+
+// matches reports whether cfg has the same choices as the state.
+func (st *installState) matches(cfg *installConfig) bool {
+    return st.Network == cfg.network.Name && st.Components == cfg.components &&
+        st.PruneSize == cfg.pruneSize && st.P2PMode == cfg.p2pMode &&
+        st.Downloads == cfg.downloads &&
+        st.SignetChallenge == cfg.network.SignetChallenge &&
+        st.SignetSeedNode == cfg.network.SignetSeedNode
+}
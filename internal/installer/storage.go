@@ -0,0 +1,276 @@
+package installer
+
+import (
+    "context"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/ripsline/virtual-private-node/internal/config"
+    "github.com/ripsline/virtual-private-node/internal/paths"
+)
+
+// diskHeadroomGB is kept free beyond the chain itself for LND,
+// logs and chain growth while the node syncs.
+const diskHeadroomGB = 10
+
+// diskFreeGB reports the space available to unprivileged users
+// on the filesystem holding path, in whole gigabytes.
+func diskFreeGB(path string) (int, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+    out, err := sys.Output(ctx, "df", "--output=avail", "-BG", path)
+    if err != nil {
+        return 0, fmt.Errorf("df %s: %w", path, err)
+    }
+    // A header line, then the figure with a G suffix
+    fields := strings.Fields(string(out))
+    if len(fields) < 2 {
+        return 0, fmt.Errorf("df %s: unexpected output %q", path, out)
+    }
+    n, err := strconv.Atoi(strings.TrimSuffix(fields[len(fields)-1], "G"))
+    if err != nil {
+        return 0, fmt.Errorf("df %s: unexpected output %q", path, out)
+    }
+    return n, nil
+}
+
+// checkChainSpace refuses an unpruned node when the bitcoin data
+// disk cannot hold the network's full chain. reclaimGB is space
+// that will be freed before the download, such as pruned blocks
+// about to be deleted.
+func checkChainSpace(network *NetworkConfig, reclaimGB int) error {
+    free, err := diskFreeGB(paths.Live.BitcoinData())
+    if err != nil {
+        return err
+    }
+    need := network.ChainSizeGB + diskHeadroomGB
+    if free+reclaimGB < need {
+        return fmt.Errorf("an unpruned %s node needs about %d GB in %s, but only %d GB is available",
+            network.Name, need, paths.Live.BitcoinData(), free+reclaimGB)
+    }
+    return nil
+}
+
+// bitcoinChainDirs hold a network's blocks and everything derived
+// from them. Removing them makes bitcoind download the chain from
+// scratch; wallets, peers and settings are left alone.
+var bitcoinChainDirs = []string{"blocks", "chainstate", "indexes"}
+
+// unpruneUnits are the services stopped while the chain is reset,
+// in stop order. LND and litd are started again afterwards and
+// wait for bitcoind to finish syncing.
+func unpruneUnits(cfg *config.AppConfig) []string {
+    var u []string
+    if cfg.LITInstalled {
+        u = append(u, "litd")
+    }
+    if cfg.HasLND() {
+        u = append(u, "lnd")
+    }
+    return append(u, "bitcoind")
+}
+
+// unpruneSteps turns a pruned node into an unpruned one. Pruned
+// blocks cannot be fetched back into place, so the block data is
+// removed and the whole chain is downloaded again.
+func unpruneSteps(cfg *config.AppConfig, network *NetworkConfig) []installStep {
+    install := &installConfig{
+        network: network, components: cfg.Components,
+        p2pMode: cfg.P2PMode, downloads: cfg.Downloads,
+    }
+    steps := []installStep{
+        // The pruned blocks and chainstate are about to go
+        {name: "Checking disk space", fn: func() error { return checkChainSpace(network, cfg.PruneSize) }},
+        {name: "Stopping services", fn: func() error {
+            for _, u := range unpruneUnits(cfg) {
+                if output, err := sys.Run("systemctl", "stop", u); err != nil {
+                    return fmt.Errorf("stop %s: %s: %s", u, err, output)
+                }
+            }
+            return nil
+        }},
+        {name: "Configuring Bitcoin Core", fn: func() error { return writeBitcoinConfig(install) }},
+        {name: "Removing pruned block data", fn: func() error {
+            for _, d := range bitcoinChainDirs {
+                if err := sys.RemoveAll(paths.Node.BitcoinChainData(cfg.Network) + "/" + d); err != nil {
+                    return fmt.Errorf("remove %s: %w", d, err)
+                }
+            }
+            return nil
+        }},
+        {name: "Starting Bitcoin Core", fn: startBitcoind},
+        {name: "Scheduling post-sync cache reduction", fn: scheduleIBDCheck},
+    }
+    if cfg.HasLND() {
+        steps = append(steps, installStep{name: "Starting LND", fn: startLND})
+    }
+    if cfg.LITInstalled {
+        steps = append(steps, installStep{name: "Starting Lightning Terminal", fn: startLITD})
+    }
+    return steps
+}
+
+// RunUnprune converts a pruned node to an unpruned one with
+// txindex and block filters, after checking the disk can hold
+// the full chain and asking for confirmation.
+func RunUnprune(cfg *config.AppConfig) error {
+    if !cfg.IsPruned() {
+        return fmt.Errorf("node is already unpruned")
+    }
+    network, err := networkConfig(cfg.Network, cfg.SignetChallenge, cfg.SignetSeedNode)
+    if err != nil {
+        return err
+    }
+    confirmMsg := setupTitleStyle.Render("Convert to Full Node") + "\n\n" +
+        setupTextStyle.Render("This will:") + "\n\n" +
+        setupTextStyle.Render("  • Stop "+strings.Join(unpruneUnits(cfg), ", ")) + "\n" +
+        setupTextStyle.Render("  • Turn off pruning and enable txindex and block filters") + "\n" +
+        setupTextStyle.Render("  • Delete the pruned "+cfg.Network+" blocks and chainstate") + "\n" +
+        setupTextStyle.Render("  • Start the services and download the whole chain again") + "\n\n" +
+        setupDimStyle.Render(fmt.Sprintf("The %s chain needs about %d GB. Until the download",
+            cfg.Network, network.ChainSizeGB)) + "\n" +
+        setupDimStyle.Render("finishes, which can take days, LND cannot see new blocks.") + "\n"
+    if cfg.HasLND() {
+        confirmMsg += "\n" + setupWarnStyle.Render("WARNING: open channels are not watched until the sync") + "\n" +
+            setupWarnStyle.Render("completes. Close them first if they hold real funds.") + "\n"
+    }
+    confirmMsg += "\n" + setupDimStyle.Render("Enter to proceed • backspace to cancel")
+    if !showConfirmBox(confirmMsg) {
+        return nil
+    }
+    if err := runInstallTUI(unpruneSteps(cfg, network), appVersion, nil); err != nil {
+        return err
+    }
+    cfg.PruneSize = 0
+    return config.Save(cfg)
+}
+
+// RunUnprunePrompt is RunUnprune for the dashboard, which needs a
+// failure held on screen.
+func RunUnprunePrompt(cfg *config.AppConfig) error {
+    return reportFailure("Conversion", RunUnprune(cfg))
+}
+
+// ── Prune target ─────────────────────────────────────────
+
+// minPruneGB is bitcoind's smallest prune target (550 MiB),
+// rounded up to whole gigabytes.
+const minPruneGB = 1
+
+// setPruneTarget rewrites the prune= line of bitcoin.conf and
+// leaves the rest of the file as it is.
+func setPruneTarget(sizeGB int) error {
+    data, err := sys.ReadFile(paths.Node.BitcoinConf())
+    if err != nil {
+        return err
+    }
+    lines := strings.Split(string(data), "\n")
+    found := false
+    for i, line := range lines {
+        if strings.HasPrefix(line, "prune=") {
+            lines[i] = fmt.Sprintf("prune=%d", sizeGB*1000)
+            found = true
+        }
+    }
+    if !found {
+        return fmt.Errorf("no prune= line in %s", paths.Live.BitcoinConf())
+    }
+    if err := sys.WriteFile(paths.Node.BitcoinConf(), []byte(strings.Join(lines, "\n")), 0640); err != nil {
+        return err
+    }
+    if output, err := sys.Run("chown", "root:"+systemUser, paths.Live.BitcoinConf()); err != nil {
+        return fmt.Errorf("chown bitcoin.conf: %s: %s", err, output)
+    }
+    return nil
+}
+
+// pruneResizeSteps moves the prune target from cfg.PruneSize to
+// sizeGB. Growing it needs the extra space free on the data disk;
+// shrinking frees space once bitcoind has pruned down.
+func pruneResizeSteps(cfg *config.AppConfig, sizeGB int) []installStep {
+    var steps []installStep
+    if grow := sizeGB - cfg.PruneSize; grow > 0 {
+        steps = append(steps, installStep{name: "Checking disk space", fn: func() error {
+            free, err := diskFreeGB(paths.Live.BitcoinData())
+            if err != nil {
+                return err
+            }
+            if free < grow+diskHeadroomGB {
+                return fmt.Errorf("a %d GB prune target needs %d GB more in %s, but only %d GB is available",
+                    sizeGB, grow+diskHeadroomGB, paths.Live.BitcoinData(), free)
+            }
+            return nil
+        }})
+    }
+    return append(steps,
+        installStep{name: "Updating prune target", fn: func() error { return setPruneTarget(sizeGB) }},
+        installStep{name: "Restarting Bitcoin Core", fn: func() error {
+            if output, err := sys.Run("systemctl", "restart", "bitcoind"); err != nil {
+                return fmt.Errorf("restart bitcoind: %s: %s", err, output)
+            }
+            return nil
+        }},
+    )
+}
+
+// RunPruneResize changes how much block data a pruned node keeps,
+// after asking for confirmation.
+func RunPruneResize(cfg *config.AppConfig, sizeGB int) error {
+    if !cfg.IsPruned() {
+        return fmt.Errorf("node is unpruned; its txindex cannot be kept on a pruned node")
+    }
+    if sizeGB < minPruneGB {
+        return fmt.Errorf("prune target must be at least %d GB", minPruneGB)
+    }
+    if sizeGB == cfg.PruneSize {
+        return fmt.Errorf("prune target is already %d GB", sizeGB)
+    }
+    confirmMsg := setupTitleStyle.Render(fmt.Sprintf("Prune Target: %d GB → %d GB", cfg.PruneSize, sizeGB)) + "\n\n" +
+        setupTextStyle.Render("This will:") + "\n\n"
+    if sizeGB > cfg.PruneSize {
+        confirmMsg += setupTextStyle.Render(fmt.Sprintf("  • Check %d GB more is free", sizeGB-cfg.PruneSize)) + "\n"
+    }
+    confirmMsg += setupTextStyle.Render(fmt.Sprintf("  • Set prune=%d in bitcoin.conf", sizeGB*1000)) + "\n" +
+        setupTextStyle.Render("  • Restart bitcoind") + "\n\n"
+    if sizeGB > cfg.PruneSize {
+        confirmMsg += setupWarnStyle.Render("Blocks already pruned do not come back; only new") + "\n" +
+            setupWarnStyle.Render("blocks are kept longer.") + "\n\n"
+    } else {
+        confirmMsg += setupDimStyle.Render("Older blocks are deleted once bitcoind prunes down.") + "\n\n"
+    }
+    confirmMsg += setupDimStyle.Render("Enter to proceed • backspace to cancel")
+    if !showConfirmBox(confirmMsg) {
+        return nil
+    }
+    if err := runInstallTUI(pruneResizeSteps(cfg, sizeGB), appVersion, nil); err != nil {
+        return err
+    }
+    cfg.PruneSize = sizeGB
+    return config.Save(cfg)
+}
+
+// RunPruneResizePrompt asks for the new prune target on the plain
+// terminal, then runs RunPruneResize. Any whole number of
+// gigabytes is accepted, not only the install-time choices.
+func RunPruneResizePrompt(cfg *config.AppConfig) error {
+    fmt.Print("\033[2J\033[H")
+    fmt.Println("\n  ═══════════════════════════════════════════")
+    fmt.Println("    Change Prune Target")
+    fmt.Print("  ═══════════════════════════════════════════\n\n")
+    fmt.Printf("  Current: %d GB\n", cfg.PruneSize)
+    if free, err := diskFreeGB(paths.Live.BitcoinData()); err == nil {
+        fmt.Printf("  Free:    %d GB\n", free)
+    }
+    fmt.Print("\n  New size in GB (10, 25, 50 or any other), empty to cancel: ")
+    line := strings.TrimSuffix(strings.ToUpper(readLine(stdin)), "GB")
+    if line == "" {
+        return nil
+    }
+    size, err := strconv.Atoi(strings.TrimSpace(line))
+    if err != nil {
+        return reportFailure("Prune change", fmt.Errorf("%q is not a whole number of GB", line))
+    }
+    return reportFailure("Prune change", RunPruneResize(cfg, size))
+}
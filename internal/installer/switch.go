@@ -0,0 +1,297 @@
+package installer
+
+import (
+    "context"
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/ripsline/virtual-private-node/internal/config"
+    "github.com/ripsline/virtual-private-node/internal/paths"
+)
+
+// switchPlan moves an installed node from one network to another.
+// Only configuration is rewritten: chain data, LND wallets and
+// onion keys stay where they are, so switching back picks up the
+// old chain and wallet again.
+type switchPlan struct {
+    from, to    *config.AppConfig
+    fromNetwork *NetworkConfig
+    install     *installConfig
+    // newWallet is set when the target network has no LND wallet
+    // yet; auto-unlock is turned off until one is created.
+    newWallet bool
+}
+
+// buildSwitchPlan plans the move to network name. challenge and
+// seedNode select a custom signet; without them a switch to signet
+// rejoins the signet the node last ran, whose parameters stay in
+// config.json while the node is on another network.
+func buildSwitchPlan(cfg *config.AppConfig, name, challenge, seedNode string) (*switchPlan, error) {
+    if _, err := NetworkConfigFromName(name); err != nil {
+        return nil, err
+    }
+    from, err := networkConfig(cfg.Network, cfg.SignetChallenge, cfg.SignetSeedNode)
+    if err != nil {
+        return nil, fmt.Errorf("config.json: %w", err)
+    }
+    custom := challenge != "" || seedNode != ""
+    if custom && name != "signet" {
+        return nil, fmt.Errorf("--signet-challenge and --signet-seednode need signet")
+    }
+    if err := validateSignet(challenge, seedNode); err != nil {
+        return nil, err
+    }
+
+    to := *cfg
+    to.Network = name
+    if custom {
+        to.SignetChallenge, to.SignetSeedNode = challenge, seedNode
+    }
+    sameSignet := to.SignetChallenge == cfg.SignetChallenge && to.SignetSeedNode == cfg.SignetSeedNode
+    if name == cfg.Network && sameSignet {
+        return nil, fmt.Errorf("node is already on %s", name)
+    }
+    // Every signet shares one chain directory, as in Bitcoin Core
+    if name == "signet" && !sameSignet {
+        if _, err := sys.Stat(paths.Node.BitcoinChainData("signet")); err == nil {
+            return nil, fmt.Errorf("%s holds the chain of another signet; move it away before joining a different one",
+                paths.Live.BitcoinChainData("signet"))
+        }
+    }
+    if err := checkWalletEmpty(cfg); err != nil {
+        return nil, err
+    }
+
+    network, err := networkConfig(name, to.SignetChallenge, to.SignetSeedNode)
+    if err != nil {
+        return nil, err
+    }
+    p := &switchPlan{from: cfg, to: &to, fromNetwork: from}
+    p.install = &installConfig{
+        network:    network,
+        components: cfg.Components,
+        pruneSize: cfg.PruneSize, p2pMode: cfg.P2PMode,
+        publicIPv4: currentExternalIP(), downloads: cfg.Downloads,
+    }
+    if cfg.HasLND() {
+        _, err := sys.Stat(paths.Node.LNDWalletDB(name))
+        p.newWallet = err != nil
+    }
+    return p, nil
+}
+
+// checkWalletEmpty refuses to leave a network whose LND wallet
+// still holds on-chain funds or has channels in any state. The
+// wallet has to be running and unlocked to be checked.
+func checkWalletEmpty(cfg *config.AppConfig) error {
+    if !cfg.HasLND() {
+        return nil
+    }
+    if _, err := sys.Stat(paths.Node.LNDWalletDB(cfg.Network)); err != nil {
+        return nil
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+    lnd := newLNDClient(cfg.Network)
+    unreachable := func(err error) error {
+        return fmt.Errorf("cannot check the %s wallet (is LND running and unlocked?): %w", cfg.Network, err)
+    }
+    balance, err := lnd.WalletBalance(ctx)
+    if err != nil {
+        return unreachable(err)
+    }
+    info, err := lnd.GetInfo(ctx)
+    if err != nil {
+        return unreachable(err)
+    }
+    pending, err := lnd.PendingChannels(ctx)
+    if err != nil {
+        return unreachable(err)
+    }
+    if n := info.NumActiveChannels + info.NumInactiveChannels + info.NumPendingChannels; n > 0 {
+        return fmt.Errorf("the %s wallet has %d channels; close them before switching", cfg.Network, n)
+    }
+    if pending.TotalLimboBalance > 0 {
+        return fmt.Errorf("the %s wallet has %d sats in closing channels; wait for them to confirm",
+            cfg.Network, pending.TotalLimboBalance)
+    }
+    if balance.Total > 0 {
+        return fmt.Errorf("the %s wallet holds %d sats; move them out before switching",
+            cfg.Network, balance.Total)
+    }
+    return nil
+}
+
+// currentExternalIP reads the hybrid-mode public address back out
+// of lnd.conf, since config.json does not record it.
+func currentExternalIP() string {
+    data, err := sys.ReadFile(paths.Node.LNDConf())
+    if err != nil {
+        return ""
+    }
+    for _, line := range strings.Split(string(data), "\n") {
+        if v, ok := strings.CutPrefix(line, "externalhosts="); ok {
+            return strings.TrimSuffix(v, ":9735")
+        }
+    }
+    return ""
+}
+
+// units lists the services stopped while configuration changes,
+// in stop order.
+func (p *switchPlan) units() []string {
+    var u []string
+    if p.from.LITInstalled {
+        u = append(u, "litd")
+    }
+    if p.from.HasLND() {
+        u = append(u, "lnd")
+    }
+    return append(u, "bitcoind")
+}
+
+func (p *switchPlan) confirmMessage() string {
+    from, to := p.fromNetwork, p.install.network
+    var b strings.Builder
+    b.WriteString(setupTitleStyle.Render("Switch Network: "+p.from.Network+" → "+p.to.Network) + "\n\n")
+    b.WriteString(setupTextStyle.Render("This will:") + "\n\n")
+    lines := []string{
+        "Stop " + strings.Join(p.units(), ", "),
+        "Rewrite bitcoin.conf and the admin shell helpers",
+        fmt.Sprintf("Move the Bitcoin RPC/P2P onion ports from %d/%d to %d/%d",
+            from.RPCPort, from.P2PPort, to.RPCPort, to.P2PPort),
+    }
+    if p.from.HasLND() {
+        lines = append(lines, "Rewrite lnd.conf")
+    }
+    if p.from.LITInstalled {
+        lines = append(lines, "Rewrite lit.conf")
+    }
+    if p.from.SyncthingInstalled {
+        lines = append(lines, "Point the channel backup watcher at "+p.to.Network)
+    }
+    lines = append(lines, "Restart Tor and start the services again")
+    for _, l := range lines {
+        b.WriteString(setupTextStyle.Render("  • "+l) + "\n")
+    }
+    b.WriteString("\n" + setupDimStyle.Render("Chain data and wallets for "+p.from.Network+
+        " are kept for switching back.") + "\n")
+    if p.newWallet {
+        b.WriteString(setupDimStyle.Render("There is no LND wallet on "+p.to.Network+
+            " yet; create one from the dashboard.") + "\n")
+        if p.from.AutoUnlock {
+            b.WriteString(setupDimStyle.Render("Auto-unlock is turned off until then.") + "\n")
+        }
+    }
+    if p.to.Network == "mainnet" {
+        b.WriteString("\n" + setupWarnStyle.Render("WARNING: mainnet uses real bitcoin.") + "\n")
+    }
+    b.WriteString("\n" + setupDimStyle.Render("Enter to switch • backspace to cancel"))
+    return b.String()
+}
+
+func (p *switchPlan) steps() []installStep {
+    cfg := p.install
+    steps := []installStep{
+        {name: "Stopping services", fn: func() error {
+            for _, u := range p.units() {
+                if output, err := sys.Run("systemctl", "stop", u); err != nil {
+                    return fmt.Errorf("stop %s: %s: %s", u, err, output)
+                }
+            }
+            return nil
+        }},
+        {name: "Configuring Bitcoin Core", fn: func() error { return writeBitcoinConfig(cfg) }},
+        {name: "Configuring Tor", fn: p.writeTorConfig},
+    }
+    if p.from.HasLND() {
+        steps = append(steps,
+            installStep{name: "Configuring LND", fn: func() error {
+                if err := writeLNDConfig(cfg); err != nil {
+                    return err
+                }
+                if p.from.LITInstalled {
+                    return enableRPCMiddleware()
+                }
+                return nil
+            }})
+        if p.newWallet {
+            steps = append(steps,
+                installStep{name: "Creating LND service", fn: func() error { return writeLNDServiceInitial(systemUser) }})
+        }
+    }
+    if p.from.LITInstalled {
+        steps = append(steps,
+            installStep{name: "Configuring Lightning Terminal",
+                fn: func() error { return writeLITConfig(p.to, p.from.LITPassword) }})
+    }
+    if p.from.SyncthingInstalled {
+        steps = append(steps,
+            installStep{name: "Updating channel backup watcher", fn: func() error {
+                if err := setupChannelBackupWatcher(p.to); err != nil {
+                    return err
+                }
+                // start is a no-op on a running path unit
+                _, err := sys.Run("systemctl", "restart", "lnd-backup-watch.path")
+                return err
+            }})
+    }
+    steps = append(steps,
+        installStep{name: "Updating shell helpers", fn: func() error {
+            if err := removeShellEnvironment(); err != nil {
+                return err
+            }
+            return setupShellEnvironment(cfg)
+        }},
+        installStep{name: "Restarting Tor", fn: restartTor},
+        installStep{name: "Starting Bitcoin Core", fn: startBitcoind},
+        installStep{name: "Scheduling post-sync cache reduction", fn: scheduleIBDCheck},
+    )
+    if p.from.HasLND() {
+        steps = append(steps, installStep{name: "Starting LND", fn: startLND})
+    }
+    if p.from.LITInstalled {
+        steps = append(steps, installStep{name: "Starting Lightning Terminal", fn: startLITD})
+    }
+    return steps
+}
+
+// writeTorConfig regenerates torrc for the new ports and adds
+// back the add-on hidden services. Their key directories are
+// untouched, so every onion address stays the same.
+func (p *switchPlan) writeTorConfig() error {
+    if err := writeTorConfig(p.install); err != nil {
+        return err
+    }
+    if p.from.LITInstalled {
+        if err := addLITTorService(); err != nil {
+            return err
+        }
+    }
+    if p.from.SyncthingInstalled {
+        if err := addSyncthingTorService(); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// RunNetworkSwitch moves the node to network name after checking
+// the current LND wallet is empty and asking for confirmation.
+func RunNetworkSwitch(cfg *config.AppConfig, name, signetChallenge, signetSeedNode string) error {
+    p, err := buildSwitchPlan(cfg, name, signetChallenge, signetSeedNode)
+    if err != nil {
+        return err
+    }
+    if !showConfirmBox(p.confirmMessage()) {
+        return nil
+    }
+    if err := runInstallTUI(p.steps(), appVersion, nil); err != nil {
+        return err
+    }
+    if p.newWallet {
+        p.to.AutoUnlock = false
+    }
+    return config.Save(p.to)
+}
@@ -3,34 +3,30 @@ package installer
 import (
     "fmt"
     "os"
-    "os/exec"
     "strings"
 
     "golang.org/x/crypto/bcrypt"
 
     "github.com/ripsline/virtual-private-node/internal/config"
+    "github.com/ripsline/virtual-private-node/internal/paths"
 )
 
 func installSyncthingRepo() error {
-    os.MkdirAll("/etc/apt/keyrings", 0755)
-    cmd := exec.Command("curl", "-L", "-o",
-        "/etc/apt/keyrings/syncthing-archive-keyring.gpg",
-        "https://syncthing.net/release-key.gpg")
-    if output, err := cmd.CombinedOutput(); err != nil {
-        return fmt.Errorf("download key: %s: %s", err, output)
+    sys.MkdirAll(paths.Node.Path("/etc/apt/keyrings"), 0755)
+    if err := download("https://syncthing.net/release-key.gpg",
+        paths.Node.Path("/etc/apt/keyrings/syncthing-archive-keyring.gpg")); err != nil {
+        return fmt.Errorf("download key: %w", err)
     }
     repoLine := `deb [signed-by=/etc/apt/keyrings/syncthing-archive-keyring.gpg] https://apt.syncthing.net/ syncthing stable-v2`
-    return os.WriteFile("/etc/apt/sources.list.d/syncthing.list",
+    return sys.WriteFile(paths.Node.Path("/etc/apt/sources.list.d/syncthing.list"),
         []byte(repoLine+"\n"), 0644)
 }
 
 func installSyncthingPackage() error {
-    cmd := exec.Command("apt-get", "update", "-qq")
-    if output, err := cmd.CombinedOutput(); err != nil {
+    if output, err := sys.Run("apt-get", "update", "-qq"); err != nil {
         return fmt.Errorf("apt update: %s: %s", err, output)
     }
-    cmd = exec.Command("apt-get", "install", "-y", "-qq", "syncthing")
-    if output, err := cmd.CombinedOutput(); err != nil {
+    if output, err := sys.Run("apt-get", "install", "-y", "-qq", "syncthing"); err != nil {
         return fmt.Errorf("install: %s: %s", err, output)
     }
     return nil
@@ -42,18 +38,18 @@ func createSyncthingDirs() error {
         owner string
         mode  os.FileMode
     }{
-        {"/etc/syncthing", systemUser + ":" + systemUser, 0750},
-        {"/var/lib/syncthing", systemUser + ":" + systemUser, 0750},
-        {"/var/lib/syncthing/lnd-backup", systemUser + ":" + systemUser, 0750},
+        {paths.Live.SyncthingConfDir(), systemUser + ":" + systemUser, 0750},
+        {paths.Live.SyncthingData(), systemUser + ":" + systemUser, 0750},
+        {paths.Live.SyncthingBackupDir(), systemUser + ":" + systemUser, 0750},
     }
     for _, d := range dirs {
-        if err := os.MkdirAll(d.path, d.mode); err != nil {
+        if err := sys.MkdirAll(paths.Node.Path(d.path), d.mode); err != nil {
             return err
         }
-        if output, err := exec.Command("chown", d.owner, d.path).CombinedOutput(); err != nil {
+        if output, err := sys.Run("chown", d.owner, d.path); err != nil {
             return fmt.Errorf("chown %s: %s: %s", d.path, err, output)
         }
-        os.Chmod(d.path, d.mode)
+        sys.Chmod(paths.Node.Path(d.path), d.mode)
     }
     return nil
 }
@@ -68,7 +64,7 @@ Wants=network-online.target
 Type=simple
 User=%s
 Group=%s
-ExecStart=/usr/bin/syncthing serve --no-browser --no-restart --config=/etc/syncthing --data=/var/lib/syncthing
+ExecStart=/usr/bin/syncthing serve --no-browser --no-restart --config=%s --data=%s
 Restart=on-failure
 RestartSec=10
 SuccessExitStatus=3 4
@@ -76,24 +72,22 @@ RestartForceExitStatus=3 4
 
 [Install]
 WantedBy=multi-user.target
-`, systemUser, systemUser)
-    return os.WriteFile("/etc/systemd/system/syncthing.service",
+`, systemUser, systemUser, paths.Live.SyncthingConfDir(), paths.Live.SyncthingData())
+    return sys.WriteFile(paths.Node.Unit("syncthing.service"),
         []byte(content), 0644)
 }
 
 func configureSyncthingAuth(password string) error {
-    exec.Command("chown", systemUser+":"+systemUser,
-        "/etc/syncthing").Run()
+    sys.Run("chown", systemUser+":"+systemUser,
+        paths.Live.SyncthingConfDir())
 
-    cmd := exec.Command("sudo", "-u", systemUser, "syncthing",
-        "generate", "--home=/etc/syncthing")
-    if output, err := cmd.CombinedOutput(); err != nil {
+    if output, err := sys.Run("sudo", "-u", systemUser, "syncthing",
+        "generate", "--home="+paths.Live.SyncthingConfDir()); err != nil {
         return fmt.Errorf("syncthing generate: %s: %s",
             err, output)
     }
 
-    configPath := "/etc/syncthing/config.xml"
-    data, err := os.ReadFile(configPath)
+    data, err := sys.ReadFile(paths.Node.SyncthingConfig())
     if err != nil {
         return fmt.Errorf("read config: %w", err)
     }
@@ -143,19 +137,19 @@ func configureSyncthingAuth(password string) error {
         addrTag, string(hash))
     content = strings.Replace(content, addrTag, injection, 1)
 
-    if err := os.WriteFile(configPath,
+    if err := sys.WriteFile(paths.Node.SyncthingConfig(),
         []byte(content), 0640); err != nil {
         return err
     }
-    if output, err := exec.Command("chown",
+    if output, err := sys.Run("chown",
         systemUser+":"+systemUser,
-        configPath).CombinedOutput(); err != nil {
+        paths.Live.SyncthingConfig()); err != nil {
         return fmt.Errorf("chown syncthing config: %s: %s",
             err, output)
     }
 
     // Verify config
-    verify, err := os.ReadFile(configPath)
+    verify, err := sys.ReadFile(paths.Node.SyncthingConfig())
     if err != nil {
         return fmt.Errorf("verify syncthing config: %w", err)
     }
@@ -198,9 +192,8 @@ func setupChannelBackupWatcher(cfg *config.AppConfig) error {
     if cfg.IsMainnet() {
         network = "mainnet"
     }
-    backupSource := fmt.Sprintf(
-        "/var/lib/lnd/data/chain/bitcoin/%s/channel.backup", network)
-    backupDest := "/var/lib/syncthing/lnd-backup/channel.backup"
+    backupSource := paths.Live.LNDChannelBackup(network)
+    backupDest := paths.Live.SyncthingBackupDir() + "/channel.backup"
 
     pathUnit := fmt.Sprintf(`[Unit]
 Description=Watch LND channel backup
@@ -212,7 +205,7 @@ Unit=lnd-backup-copy.service
 [Install]
 WantedBy=multi-user.target
 `, backupSource)
-    if err := os.WriteFile("/etc/systemd/system/lnd-backup-watch.path",
+    if err := sys.WriteFile(paths.Node.Unit("lnd-backup-watch.path"),
         []byte(pathUnit), 0644); err != nil {
         return err
     }
@@ -225,7 +218,7 @@ Type=oneshot
 User=%s
 ExecStart=/bin/cp %s %s
 `, systemUser, backupSource, backupDest)
-    if err := os.WriteFile("/etc/systemd/system/lnd-backup-copy.service",
+    if err := sys.WriteFile(paths.Node.Unit("lnd-backup-copy.service"),
         []byte(copyService), 0644); err != nil {
         return err
     }
@@ -235,21 +228,20 @@ ExecStart=/bin/cp %s %s
         {"systemctl", "enable", "lnd-backup-watch.path"},
         {"systemctl", "start", "lnd-backup-watch.path"},
     } {
-        cmd := exec.Command(args[0], args[1:]...)
-        if output, err := cmd.CombinedOutput(); err != nil {
+        if output, err := sys.Run(args[0], args[1:]...); err != nil {
             return fmt.Errorf("%v: %s: %s", args, err, output)
         }
     }
 
-    if _, err := os.Stat(backupSource); err == nil {
-        exec.Command("cp", backupSource, backupDest).Run()
-        exec.Command("chown", systemUser+":"+systemUser, backupDest).Run()
+    if _, err := sys.Stat(paths.Node.LNDChannelBackup(network)); err == nil {
+        sys.Run("cp", backupSource, backupDest)
+        sys.Run("chown", systemUser+":"+systemUser, backupDest)
     }
     return nil
 }
 
 func addSyncthingTorService() error {
-    data, err := os.ReadFile("/etc/tor/torrc")
+    data, err := sys.ReadFile(paths.Node.TorConfig())
     if err != nil {
         return err
     }
@@ -258,14 +250,16 @@ func addSyncthingTorService() error {
     }
     addition := `
 # Syncthing web UI (Tor only, HTTP)
-HiddenServiceDir /var/lib/tor/syncthing/
+HiddenServiceDir %s/
 HiddenServicePort 8384 127.0.0.1:8384
 
 # Syncthing sync protocol (Tor only)
-HiddenServiceDir /var/lib/tor/syncthing-sync/
+HiddenServiceDir %s/
 HiddenServicePort 22000 127.0.0.1:22000
 `
-    return os.WriteFile("/etc/tor/torrc",
+    addition = fmt.Sprintf(addition, paths.Live.OnionDir("syncthing"),
+        paths.Live.OnionDir("syncthing-sync"))
+    return sys.WriteFile(paths.Node.TorConfig(),
         append(data, []byte(addition)...), 0644)
 }
 
@@ -275,8 +269,7 @@ func startSyncthing() error {
         {"systemctl", "enable", "syncthing"},
         {"systemctl", "start", "syncthing"},
     } {
-        cmd := exec.Command(args[0], args[1:]...)
-        if output, err := cmd.CombinedOutput(); err != nil {
+        if output, err := sys.Run(args[0], args[1:]...); err != nil {
             return fmt.Errorf("%v: %s: %s", args, err, output)
         }
     }
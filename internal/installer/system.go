@@ -3,9 +3,9 @@ package installer
 import (
     "fmt"
     "os"
-    "os/exec"
-    "os/user"
     "strings"
+
+    "github.com/ripsline/virtual-private-node/internal/paths"
 )
 
 // checkOS verifies we're running on Debian.
@@ -23,15 +23,17 @@ func checkOS() error {
 // createSystemUser creates the non-login system user that runs
 // bitcoind, lnd, and litd services.
 func createSystemUser(username string) error {
-    if _, err := user.Lookup(username); err == nil {
-        return nil
+    passwd, _ := sys.ReadFile(paths.Node.Path("/etc/passwd"))
+    for _, line := range strings.Split(string(passwd), "\n") {
+        if strings.HasPrefix(line, username+":") {
+            return nil
+        }
     }
-    cmd := exec.Command("adduser",
+    if output, err := sys.Run("adduser",
         "--system", "--group",
-        "--home", "/var/lib/bitcoin",
+        "--home", paths.Live.BitcoinData(),
         "--shell", "/usr/sbin/nologin",
-        username)
-    if output, err := cmd.CombinedOutput(); err != nil {
+        username); err != nil {
         return fmt.Errorf("%s: %s", err, output)
     }
     return nil
@@ -44,8 +46,8 @@ func createDirs(username string, cfg *installConfig) error {
         owner string
         mode  os.FileMode
     }{
-        {"/etc/bitcoin", "root:" + username, 0750},
-        {"/var/lib/bitcoin", username + ":" + username, 0750},
+        {paths.Live.BitcoinConfDir(), "root:" + username, 0750},
+        {paths.Live.BitcoinData(), username + ":" + username, 0750},
     }
 
     if cfg.components == "bitcoin+lnd" {
@@ -54,24 +56,23 @@ func createDirs(username string, cfg *installConfig) error {
                 path  string
                 owner string
                 mode  os.FileMode
-            }{"/etc/lnd", "root:" + username, 0750},
+            }{paths.Live.LNDConfDir(), "root:" + username, 0750},
             struct {
                 path  string
                 owner string
                 mode  os.FileMode
-            }{"/var/lib/lnd", username + ":" + username, 0750},
+            }{paths.Live.LNDData(), username + ":" + username, 0750},
         )
     }
 
     for _, d := range dirs {
-        if err := os.MkdirAll(d.path, d.mode); err != nil {
+        if err := sys.MkdirAll(paths.Node.Path(d.path), d.mode); err != nil {
             return fmt.Errorf("mkdir %s: %w", d.path, err)
         }
-        cmd := exec.Command("chown", d.owner, d.path)
-        if output, err := cmd.CombinedOutput(); err != nil {
+        if output, err := sys.Run("chown", d.owner, d.path); err != nil {
             return fmt.Errorf("chown %s: %s: %s", d.path, err, output)
         }
-        if err := os.Chmod(d.path, d.mode); err != nil {
+        if err := sys.Chmod(paths.Node.Path(d.path), d.mode); err != nil {
             return fmt.Errorf("chmod %s: %w", d.path, err)
         }
     }
@@ -85,27 +86,24 @@ net.ipv6.conf.all.disable_ipv6 = 1
 net.ipv6.conf.default.disable_ipv6 = 1
 net.ipv6.conf.lo.disable_ipv6 = 1
 `
-    if err := os.WriteFile("/etc/sysctl.d/99-disable-ipv6.conf", []byte(content), 0644); err != nil {
+    if err := sys.WriteFile(paths.Node.Path("/etc/sysctl.d/99-disable-ipv6.conf"), []byte(content), 0644); err != nil {
         return err
     }
-    cmd := exec.Command("sysctl", "--system")
-    cmd.Stdout = nil
-    cmd.Stderr = nil
-    return cmd.Run()
+    _, err := sys.Run("sysctl", "--system")
+    return err
 }
 
 // configureFirewall sets up UFW. Only SSH is always open.
 // Port 9735 opens only for LND hybrid P2P mode.
 func configureFirewall(cfg *installConfig) error {
-    cmd := exec.Command("apt-get", "install", "-y", "-qq", "ufw")
-    if output, err := cmd.CombinedOutput(); err != nil {
+    if output, err := sys.Run("apt-get", "install", "-y", "-qq", "ufw"); err != nil {
         return fmt.Errorf("install ufw: %s: %s", err, output)
     }
 
-    ufwDefault, err := os.ReadFile("/etc/default/ufw")
+    ufwDefault, err := sys.ReadFile(paths.Node.Path("/etc/default/ufw"))
     if err == nil {
         content := strings.ReplaceAll(string(ufwDefault), "IPV6=yes", "IPV6=no")
-        os.WriteFile("/etc/default/ufw", []byte(content), 0644)
+        sys.WriteFile(paths.Node.Path("/etc/default/ufw"), []byte(content), 0644)
     }
 
     commands := [][]string{
@@ -121,8 +119,7 @@ func configureFirewall(cfg *installConfig) error {
     commands = append(commands, []string{"ufw", "--force", "enable"})
 
     for _, args := range commands {
-        cmd := exec.Command(args[0], args[1:]...)
-        if output, err := cmd.CombinedOutput(); err != nil {
+        if output, err := sys.Run(args[0], args[1:]...); err != nil {
             return fmt.Errorf("%v: %s: %s", args, err, output)
         }
     }
@@ -132,9 +129,8 @@ func configureFirewall(cfg *installConfig) error {
 // installUnattendedUpgrades installs and configures automatic
 // security updates for the Debian system.
 func installUnattendedUpgrades() error {
-    cmd := exec.Command("apt-get", "install", "-y", "-qq",
-        "unattended-upgrades", "apt-listchanges")
-    if output, err := cmd.CombinedOutput(); err != nil {
+    if output, err := sys.Run("apt-get", "install", "-y", "-qq",
+        "unattended-upgrades", "apt-listchanges"); err != nil {
         return fmt.Errorf("install: %s: %s", err, output)
     }
     return nil
@@ -148,7 +144,7 @@ func configureUnattendedUpgrades() error {
 APT::Periodic::Unattended-Upgrade "1";
 APT::Periodic::AutocleanInterval "7";
 `
-    if err := os.WriteFile("/etc/apt/apt.conf.d/20auto-upgrades",
+    if err := sys.WriteFile(paths.Node.Path("/etc/apt/apt.conf.d/20auto-upgrades"),
         []byte(autoConf), 0644); err != nil {
         return err
     }
@@ -171,13 +167,12 @@ Unattended-Upgrade::Automatic-Reboot-Time "04:00";
 Unattended-Upgrade::Remove-Unused-Kernel-Packages "true";
 Unattended-Upgrade::Remove-Unused-Dependencies "true";
 `
-    return os.WriteFile("/etc/apt/apt.conf.d/50unattended-upgrades",
+    return sys.WriteFile(paths.Node.Path("/etc/apt/apt.conf.d/50unattended-upgrades"),
         []byte(upgradeConf), 0644)
 }
 
 func installFail2ban() error {
-    cmd := exec.Command("apt-get", "install", "-y", "-qq", "fail2ban")
-    if output, err := cmd.CombinedOutput(); err != nil {
+    if output, err := sys.Run("apt-get", "install", "-y", "-qq", "fail2ban"); err != nil {
         return fmt.Errorf("install fail2ban: %s: %s", err, output)
     }
     return nil
@@ -197,7 +192,7 @@ maxretry = 5
 findtime = 600
 bantime = 600
 `
-    if err := os.WriteFile("/etc/fail2ban/jail.local",
+    if err := sys.WriteFile(paths.Node.Path("/etc/fail2ban/jail.local"),
         []byte(content), 0644); err != nil {
         return err
     }
@@ -205,8 +200,7 @@ bantime = 600
         {"systemctl", "enable", "fail2ban"},
         {"systemctl", "restart", "fail2ban"},
     } {
-        cmd := exec.Command(args[0], args[1:]...)
-        if output, err := cmd.CombinedOutput(); err != nil {
+        if output, err := sys.Run(args[0], args[1:]...); err != nil {
             return fmt.Errorf("%v: %s: %s", args, err, output)
         }
     }
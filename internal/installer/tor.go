@@ -2,14 +2,13 @@ package installer
 
 import (
     "fmt"
-    "os"
-    "os/exec"
+
+    "github.com/ripsline/virtual-private-node/internal/paths"
 )
 
 // installTor installs the Tor package from Debian's repositories.
 func installTor() error {
-    cmd := exec.Command("apt-get", "install", "-y", "-qq", "tor")
-    if output, err := cmd.CombinedOutput(); err != nil {
+    if output, err := sys.Run("apt-get", "install", "-y", "-qq", "tor"); err != nil {
         return fmt.Errorf("%s: %s", err, output)
     }
     return nil
@@ -36,36 +35,35 @@ CookieAuthFileGroupReadable 1
     // Bitcoin hidden services — always created
     content += fmt.Sprintf(`
 # Bitcoin Core RPC (for wallet connections like Sparrow)
-HiddenServiceDir /var/lib/tor/bitcoin-rpc/
+HiddenServiceDir %s/
 HiddenServicePort %d 127.0.0.1:%d
 
 # Bitcoin Core P2P (static onion address for peers)
-HiddenServiceDir /var/lib/tor/bitcoin-p2p/
+HiddenServiceDir %s/
 HiddenServicePort %d 127.0.0.1:%d
-`, cfg.network.RPCPort, cfg.network.RPCPort,
-        cfg.network.P2PPort, cfg.network.P2PPort)
+`, paths.Live.OnionDir("bitcoin-rpc"), cfg.network.RPCPort, cfg.network.RPCPort,
+        paths.Live.OnionDir("bitcoin-p2p"), cfg.network.P2PPort, cfg.network.P2PPort)
 
     // LND hidden services — only if LND is installed
     if cfg.components == "bitcoin+lnd" {
-        content += `
+        content += fmt.Sprintf(`
 # LND gRPC (wallet connections over Tor)
-HiddenServiceDir /var/lib/tor/lnd-grpc/
+HiddenServiceDir %s/
 HiddenServicePort 10009 127.0.0.1:10009
 
 # LND REST (wallet connections over Tor)
-HiddenServiceDir /var/lib/tor/lnd-rest/
+HiddenServiceDir %s/
 HiddenServicePort 8080 127.0.0.1:8080
-`
+`, paths.Live.OnionDir("lnd-grpc"), paths.Live.OnionDir("lnd-rest"))
     }
 
-    return os.WriteFile("/etc/tor/torrc", []byte(content), 0644)
+    return sys.WriteFile(paths.Node.TorConfig(), []byte(content), 0644)
 }
 
 // addUserToTorGroup allows the system user to read the Tor
 // control auth cookie for LND's onion service management.
 func addUserToTorGroup(username string) error {
-    cmd := exec.Command("usermod", "-aG", "debian-tor", username)
-    if output, err := cmd.CombinedOutput(); err != nil {
+    if output, err := sys.Run("usermod", "-aG", "debian-tor", username); err != nil {
         return fmt.Errorf("%s: %s", err, output)
     }
     return nil
@@ -81,8 +79,7 @@ func restartTor() error {
     }
 
     for _, args := range commands {
-        cmd := exec.Command(args[0], args[1:]...)
-        if output, err := cmd.CombinedOutput(); err != nil {
+        if output, err := sys.Run(args[0], args[1:]...); err != nil {
             return fmt.Errorf("%v: %s: %s", args, err, output)
         }
     }
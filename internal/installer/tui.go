@@ -41,16 +41,26 @@ func buildQuestions() []question {
         {title: "Network", options: []option{
             {label: "Mainnet", desc: "Real bitcoin — use with caution", value: "mainnet"},
             {label: "Testnet4", desc: "Test bitcoin — safe for experimenting", value: "testnet4"},
+            {label: "Signet", desc: "Test bitcoin with reliable blocks — good for Lightning", value: "signet"},
+            {label: "Regtest", desc: "Private chain for development — mine blocks on demand", value: "regtest",
+                warn: "No real network — blocks are mined from the dashboard"},
         }},
         {title: "Components", options: []option{
-            {label: "Bitcoin Core only", desc: "Pruned node routed through Tor", value: "bitcoin"},
+            {label: "Bitcoin Core only", desc: "Bitcoin node routed through Tor", value: "bitcoin"},
             {label: "Bitcoin Core + LND", desc: "Full Lightning node with Tor hidden services", value: "bitcoin+lnd"},
         }},
-        {title: "Blockchain Storage (Pruned)", options: []option{
-            {label: "10 GB", desc: "Minimum — works but tight", value: "10"},
-            {label: "25 GB", desc: "Recommended", value: "25"},
-            {label: "50 GB", desc: "More block history", value: "50",
+        {title: "Blockchain Storage", options: []option{
+            {label: "Pruned 10 GB", desc: "Minimum — works but tight", value: "10"},
+            {label: "Pruned 25 GB", desc: "Recommended", value: "25"},
+            {label: "Pruned 50 GB", desc: "More block history", value: "50",
                 warn: "Make sure your VPS has at least 60 GB of disk space"},
+            {label: "Full node", desc: "Unpruned with txindex and block filters", value: "full",
+                warn: fmt.Sprintf("Mainnet needs about %d GB of disk; the install checks before downloading",
+                    Mainnet().ChainSizeGB+diskHeadroomGB)},
+        }},
+        {title: "Release Downloads", options: []option{
+            {label: "Clearnet", desc: "Fastest — download hosts see the server's IP", value: "clearnet"},
+            {label: "Tor", desc: "Fetched through Tor once it is running — slower", value: "tor"},
         }},
     }
 }
@@ -82,7 +92,11 @@ type tuiModel struct {
 }
 
 type tuiResult struct {
-    network, components, pruneSize, p2pMode string
+    network, components, pruneSize, p2pMode, downloads string
+
+    // Set only from an answers file; the TUI installs the
+    // default signet.
+    signetChallenge, signetSeedNode string
 }
 
 func newTuiModel(version string) tuiModel {
@@ -240,7 +254,8 @@ func (m tuiModel) renderSummary() string {
     r := m.getResult()
     rows := []struct{ k, v string }{
         {"Network", r.network}, {"Components", r.components},
-        {"Prune", r.pruneSize + " GB"},
+        {"Storage", storageLabel(r.pruneSize)},
+        {"Downloads", downloadsLabel(r.downloads)},
     }
     if r.components == "bitcoin+lnd" {
         mode := "Tor only"
@@ -259,9 +274,27 @@ func (m tuiModel) renderSummary() string {
     return b.String()
 }
 
+func defaultResult() tuiResult {
+    return tuiResult{network: "testnet4", components: "bitcoin+lnd",
+        pruneSize: "25", p2pMode: "tor", downloads: "clearnet"}
+}
+
+func storageLabel(v string) string {
+    if v == "full" {
+        return "Unpruned (txindex, block filters)"
+    }
+    return "Pruned " + v + " GB"
+}
+
+func downloadsLabel(v string) string {
+    if v == "tor" {
+        return "Through Tor"
+    }
+    return "Clearnet"
+}
+
 func (m tuiModel) getResult() tuiResult {
-    r := tuiResult{network: "testnet4", components: "bitcoin+lnd",
-        pruneSize: "25", p2pMode: "tor"}
+    r := defaultResult()
     for i, q := range m.questions {
         if i >= len(m.answers) || m.answers[i] == "" {
             continue
@@ -271,10 +304,12 @@ func (m tuiModel) getResult() tuiResult {
             r.network = m.answers[i]
         case "Components":
             r.components = m.answers[i]
-        case "Blockchain Storage (Pruned)":
+        case "Blockchain Storage":
             r.pruneSize = m.answers[i]
         case "LND P2P Mode":
             r.p2pMode = m.answers[i]
+        case "Release Downloads":
+            r.downloads = m.answers[i]
         }
     }
     return r
@@ -291,17 +326,21 @@ func RunTUI(version string) (*installConfig, error) {
     if final.phase == phaseCancelled {
         return nil, nil
     }
-    r := final.getResult()
+    return newInstallConfig(final.getResult())
+}
+
+// newInstallConfig converts questionnaire answers into the
+// installer's config. Shared by the TUI and answers files.
+func newInstallConfig(r tuiResult) (*installConfig, error) {
+    network, err := networkConfig(r.network, r.signetChallenge, r.signetSeedNode)
+    if err != nil {
+        return nil, err
+    }
     cfg := &installConfig{
-        network: NetworkConfigFromName(r.network), components: r.components,
-        p2pMode: r.p2pMode,
+        network:    network,
+        components: r.components, p2pMode: r.p2pMode, downloads: r.downloads,
     }
+    // "full" does not scan, leaving pruneSize 0: unpruned
     fmt.Sscanf(r.pruneSize, "%d", &cfg.pruneSize)
-    if cfg.p2pMode == "hybrid" {
-        cfg.publicIPv4 = detectPublicIP()
-        if cfg.publicIPv4 == "" {
-            cfg.p2pMode = "tor"
-        }
-    }
     return cfg, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,233 @@
+package installer
+
+import (
+    "context"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/ripsline/virtual-private-node/internal/config"
+    "github.com/ripsline/virtual-private-node/internal/paths"
+)
+
+// hardware is what bitcoind's resource settings are derived from.
+type hardware struct {
+    memMB int
+    cpus  int
+    ssd   bool
+}
+
+// defaultHardware is the smallest VPS the README asks for. It is
+// assumed for anything that cannot be detected.
+var defaultHardware = hardware{memMB: 4096, cpus: 2, ssd: true}
+
+// detectHardware reads total RAM, the CPU count and whether the
+// disks are solid state from /proc and /sys. Disks are assumed
+// solid state unless one reliably reports otherwise.
+func detectHardware() hardware {
+    hw := defaultHardware
+    if data, err := sys.ReadFile("/proc/meminfo"); err == nil {
+        for _, line := range strings.Split(string(data), "\n") {
+            if v, ok := strings.CutPrefix(line, "MemTotal:"); ok {
+                if kb, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(v), " kB")); err == nil {
+                    hw.memMB = kb / 1024
+                }
+            }
+        }
+    }
+    if data, err := sys.ReadFile("/proc/cpuinfo"); err == nil {
+        n := 0
+        for _, line := range strings.Split(string(data), "\n") {
+            if strings.HasPrefix(line, "processor") {
+                n++
+            }
+        }
+        if n > 0 {
+            hw.cpus = n
+        }
+    }
+    // One spinning disk is enough to treat storage as slow.
+    // virtio and Xen disks report whatever the hypervisor was
+    // configured with, usually rotational whatever backs them, so
+    // they count as undetected and keep the default.
+    if entries, err := sys.ReadDir("/sys/block"); err == nil {
+        for _, e := range entries {
+            name := e.Name()
+            if strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram") ||
+                strings.HasPrefix(name, "zram") || strings.HasPrefix(name, "sr") ||
+                strings.HasPrefix(name, "vd") || strings.HasPrefix(name, "xvd") {
+                continue
+            }
+            rot, err := sys.ReadFile("/sys/block/" + name + "/queue/rotational")
+            if err == nil && strings.TrimSpace(string(rot)) == "1" {
+                hw.ssd = false
+            }
+        }
+    }
+    return hw
+}
+
+// bitcoinTuning holds the bitcoin.conf resource settings. dbcache
+// is the steady-state cache; ibdDBCache is used until the initial
+// block download finishes.
+type bitcoinTuning struct {
+    dbcache, ibdDBCache        int
+    par                        int
+    maxConnections, maxMempool int
+}
+
+func clamp(v, lo, hi int) int {
+    return max(lo, min(v, hi))
+}
+
+// tuneBitcoin derives bitcoind's settings from hw. The steady
+// cache leaves room for LND, Tor and the mempool; during the
+// initial download a larger share of RAM goes to the cache, more
+// so on spinning disks where every flush is slow. One core is
+// left for LND and Tor.
+func tuneBitcoin(hw hardware) bitcoinTuning {
+    t := bitcoinTuning{
+        dbcache: clamp(hw.memMB/8, 300, 2048),
+        par:     clamp(hw.cpus-1, 1, 15),
+    }
+    share := hw.memMB * 3 / 8
+    if !hw.ssd {
+        share = hw.memMB / 2
+    }
+    t.ibdDBCache = clamp(share, t.dbcache, 16384)
+    switch {
+    case hw.memMB < 2048:
+        t.maxConnections, t.maxMempool = 40, 100
+    case hw.memMB < 4096:
+        t.maxConnections, t.maxMempool = 64, 300
+    case hw.memMB < 8192:
+        t.maxConnections, t.maxMempool = 125, 300
+    default:
+        t.maxConnections, t.maxMempool = 125, 500
+    }
+    return t
+}
+
+// IBDMarker starts the bitcoin.conf comment recording the cache
+// to drop to once the initial block download is done.
+const IBDMarker = "# rlvpn: after initial block download dbcache="
+
+// confLines renders t for bitcoin.conf, starting with the large
+// initial-download cache.
+func (t bitcoinTuning) confLines() string {
+    return fmt.Sprintf("dbcache=%d\n%s%d\npar=%d\nmaxconnections=%d\nmaxmempool=%d\n",
+        t.ibdDBCache, IBDMarker, t.dbcache, t.par, t.maxConnections, t.maxMempool)
+}
+
+// scheduleIBDCheck installs a timer that runs `rlvpn tune
+// ibd-check` until bitcoind has finished the initial block
+// download and the cache has been dropped back.
+func scheduleIBDCheck() error {
+    service := fmt.Sprintf(`[Unit]
+Description=Drop the bitcoind cache after initial block download
+After=bitcoind.service
+
+[Service]
+Type=oneshot
+ExecStart=%s tune ibd-check
+`, paths.Live.Bin("rlvpn"))
+    if err := sys.WriteFile(paths.Node.Unit("bitcoind-ibd.service"), []byte(service), 0644); err != nil {
+        return err
+    }
+    timer := `[Unit]
+Description=Check whether bitcoind has finished initial block download
+
+[Timer]
+OnBootSec=15min
+OnUnitActiveSec=30min
+
+[Install]
+WantedBy=timers.target
+`
+    if err := sys.WriteFile(paths.Node.Unit("bitcoind-ibd.timer"), []byte(timer), 0644); err != nil {
+        return err
+    }
+    for _, args := range [][]string{
+        {"systemctl", "daemon-reload"},
+        {"systemctl", "enable", "--now", "bitcoind-ibd.timer"},
+    } {
+        if output, err := sys.Run(args[0], args[1:]...); err != nil {
+            return fmt.Errorf("%v: %s: %s", args, err, output)
+        }
+    }
+    return nil
+}
+
+// FinishIBDCache is run by bitcoind-ibd.timer. Once bitcoind on
+// cfg's network reports the initial block download is over, the
+// cache in bitcoin.conf is set back to the steady value, bitcoind is
+// restarted and the timer turns itself off. Until then it does
+// nothing.
+func FinishIBDCache(cfg *config.AppConfig) error {
+    data, err := sys.ReadFile(paths.Node.BitcoinConf())
+    if err != nil {
+        return err
+    }
+    lines := strings.Split(string(data), "\n")
+    steady := ""
+    for _, line := range lines {
+        if v, ok := strings.CutPrefix(line, IBDMarker); ok {
+            steady = v
+        }
+    }
+    if steady != "" {
+        ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+        defer cancel()
+        rpc, err := newBitcoinRPC(cfg.Network)
+        if err != nil {
+            return err
+        }
+        info, err := rpc.GetBlockchainInfo(ctx)
+        if err != nil {
+            return err
+        }
+        if info.InitialBlockDownload {
+            return nil
+        }
+        var kept []string
+        for _, line := range lines {
+            switch {
+            case strings.HasPrefix(line, IBDMarker):
+                continue
+            case strings.HasPrefix(line, "dbcache="):
+                line = "dbcache=" + steady
+            }
+            kept = append(kept, line)
+        }
+        if err := sys.WriteFile(paths.Node.BitcoinConf(), []byte(strings.Join(kept, "\n")), 0640); err != nil {
+            return err
+        }
+        if output, err := sys.Run("chown", "root:"+systemUser, paths.Live.BitcoinConf()); err != nil {
+            return fmt.Errorf("chown bitcoin.conf: %s: %s", err, output)
+        }
+        if output, err := sys.Run("systemctl", "restart", "bitcoind"); err != nil {
+            return fmt.Errorf("restart bitcoind: %s: %s", err, output)
+        }
+    }
+    if output, err := sys.Run("systemctl", "disable", "--now", "bitcoind-ibd.timer"); err != nil {
+        return fmt.Errorf("disable bitcoind-ibd.timer: %s: %s", err, output)
+    }
+    return nil
+}
+
+// PrintTuning writes the detected hardware and the settings
+// derived from it.
+func PrintTuning() {
+    hw := detectHardware()
+    t := tuneBitcoin(hw)
+    disk := "SSD"
+    if !hw.ssd {
+        disk = "spinning disk"
+    }
+    fmt.Printf("Hardware:       %d MB RAM, %d CPUs, %s\n", hw.memMB, hw.cpus, disk)
+    fmt.Printf("dbcache:        %d MB (%d MB during initial block download)\n", t.dbcache, t.ibdDBCache)
+    fmt.Printf("par:            %d\n", t.par)
+    fmt.Printf("maxconnections: %d\n", t.maxConnections)
+    fmt.Printf("maxmempool:     %d MB\n", t.maxMempool)
+}
@@ -0,0 +1,537 @@
+package installer
+
+import (
+    "fmt"
+    "os"
+    "strings"
+
+    "github.com/ripsline/virtual-private-node/internal/config"
+    "github.com/ripsline/virtual-private-node/internal/paths"
+)
+
+// uninstallPlan lists everything `rlvpn uninstall` touches for
+// one component choice. The confirmation screen and the steps
+// are both built from it so they cannot disagree.
+type uninstallPlan struct {
+    component string
+    keepData  bool
+    lit       bool
+    syncthing bool
+    lnd       bool
+    bitcoin   bool
+    units     []string // stopped, disabled and unit file removed
+    onions    []string // HiddenServiceDir blocks removed from torrc
+    paths     []string // files and directories removed
+    packages  []string // apt packages purged
+    kept      []string // data directories preserved by --keep-data
+}
+
+// UninstallComponents are the values accepted by --component.
+var UninstallComponents = []string{"lnd", "lit", "syncthing", "all"}
+
+func buildUninstallPlan(cfg *config.AppConfig, component string, keepData bool) (*uninstallPlan, error) {
+    p := &uninstallPlan{component: component, keepData: keepData}
+    all := component == "all"
+    switch component {
+    case "lit":
+        if !cfg.LITInstalled {
+            return nil, fmt.Errorf("Lightning Terminal is not installed")
+        }
+    case "syncthing":
+        if !cfg.SyncthingInstalled {
+            return nil, fmt.Errorf("Syncthing is not installed")
+        }
+    case "lnd":
+        if !cfg.HasLND() {
+            return nil, fmt.Errorf("LND is not installed")
+        }
+    case "all":
+    default:
+        return nil, fmt.Errorf("unknown component %q (want one of %s)",
+            component, strings.Join(UninstallComponents, ", "))
+    }
+
+    // LIT runs on top of LND, so removing LND removes LIT too
+    p.lnd = component == "lnd" || (all && cfg.HasLND())
+    p.lit = component == "lit" || (p.lnd && cfg.LITInstalled)
+    p.syncthing = component == "syncthing" || (all && cfg.SyncthingInstalled)
+    p.bitcoin = all
+
+    if p.lit {
+        p.units = append(p.units, "litd.service")
+        p.onions = append(p.onions, "lnd-lit")
+        p.paths = append(p.paths, paths.Live.Bin("litd"), paths.Live.LITConfDir(), paths.Live.LITData())
+    }
+    if p.syncthing {
+        p.units = append(p.units, "syncthing.service")
+        p.onions = append(p.onions, "syncthing", "syncthing-sync")
+        p.paths = append(p.paths, paths.Live.SyncthingConfDir(), paths.Live.SyncthingData(),
+            "/etc/apt/sources.list.d/syncthing.list",
+            "/etc/apt/keyrings/syncthing-archive-keyring.gpg")
+        p.packages = append(p.packages, "syncthing")
+    }
+    // The backup watcher copies LND's channel.backup into the
+    // Syncthing folder and is useless without either side
+    if cfg.SyncthingInstalled && (p.syncthing || p.lnd) {
+        p.units = append(p.units, "lnd-backup-watch.path", "lnd-backup-copy.service")
+    }
+    if p.lnd {
+        p.units = append(p.units, "lnd.service")
+        p.onions = append(p.onions, "lnd-grpc", "lnd-rest")
+        p.paths = append(p.paths, paths.Live.Bin("lnd"), paths.Live.Bin("lncli"), paths.Live.LNDConfDir())
+        for _, r := range dirNames(paths.Node.Release(""), releaseName("lnd", "")) {
+            p.paths = append(p.paths, paths.Live.Release(r))
+        }
+        p.dataDir(paths.Live.LNDData())
+    }
+    if p.bitcoin {
+        p.units = append(p.units, "bitcoind.service", "bitcoind-ibd.timer", "bitcoind-ibd.service")
+        p.onions = append(p.onions, "bitcoin-rpc", "bitcoin-p2p")
+        for _, b := range dirNames(paths.Node.Bin(""), "bitcoin") {
+            p.paths = append(p.paths, paths.Live.Bin(b))
+        }
+        p.paths = append(p.paths, paths.Live.Bin("test_bitcoin"), paths.Live.BitcoinConfDir())
+        p.dataDir(paths.Live.BitcoinData())
+        p.paths = append(p.paths, "/etc/fail2ban/jail.local",
+            "/etc/sysctl.d/99-disable-ipv6.conf", paths.Live.OnionDir(""), paths.Live.RLVPNDir(),
+            paths.Live.Release(""))
+        p.packages = append(p.packages, "tor", "fail2ban", "ufw")
+    }
+    return p, nil
+}
+
+// dirNames lists the entries of dir whose names start with
+// prefix. A missing dir has none.
+func dirNames(dir, prefix string) []string {
+    entries, err := sys.ReadDir(dir)
+    if err != nil {
+        return nil
+    }
+    var names []string
+    for _, e := range entries {
+        if strings.HasPrefix(e.Name(), prefix) {
+            names = append(names, e.Name())
+        }
+    }
+    return names
+}
+
+func (p *uninstallPlan) dataDir(path string) {
+    if p.keepData {
+        p.kept = append(p.kept, path)
+        return
+    }
+    p.paths = append(p.paths, path)
+}
+
+// reverts describes the system changes undone when removing
+// everything. These have no single path to list.
+func (p *uninstallPlan) reverts() []string {
+    var r []string
+    if p.lit && !p.lnd {
+        r = append(r, "rpcmiddleware.enable in /etc/lnd/lnd.conf")
+    }
+    if p.lnd && !p.bitcoin {
+        r = append(r, "Tor control port in /etc/tor/torrc",
+            "lncli helper in /home/ripsline/.bashrc")
+    }
+    if p.bitcoin {
+        r = append(r, "UFW rules (reset and disabled)",
+            "IPv6 re-enabled",
+            "Shell helpers in /home/ripsline/.bashrc")
+        if !p.keepData {
+            r = append(r, "System user "+systemUser)
+        }
+    }
+    return r
+}
+
+func (p *uninstallPlan) title() string {
+    switch p.component {
+    case "lit":
+        return "Lightning Terminal"
+    case "syncthing":
+        return "Syncthing"
+    case "lnd":
+        if p.lit {
+            return "LND and Lightning Terminal"
+        }
+        return "LND"
+    }
+    return "Virtual Private Node"
+}
+
+func (p *uninstallPlan) confirmMessage() string {
+    var b strings.Builder
+    b.WriteString(setupTitleStyle.Render("Uninstall "+p.title()) + "\n\n")
+    section := func(heading string, items []string) {
+        if len(items) == 0 {
+            return
+        }
+        b.WriteString(setupTextStyle.Render(heading) + "\n")
+        for _, line := range wrapList(items, 60) {
+            b.WriteString(setupDimStyle.Render("  "+line) + "\n")
+        }
+        b.WriteString("\n")
+    }
+    section("Services stopped and disabled:", p.units)
+    section("Tor hidden services removed:", p.onions)
+    section("Files and directories removed:", p.paths)
+    section("Packages purged:", p.packages)
+    section("Reverted:", p.reverts())
+    section("Kept (--keep-data):", p.kept)
+    if p.bitcoin {
+        b.WriteString(setupDimStyle.Render("Unattended security upgrades stay enabled.") + "\n\n")
+    }
+    if p.lnd && !p.keepData {
+        b.WriteString(setupWarnStyle.Render("WARNING: /var/lib/lnd holds your wallet and channels.") + "\n" +
+            setupWarnStyle.Render("WARNING: Without your seed and channel.backup, funds are lost.") + "\n\n")
+    }
+    b.WriteString(setupDimStyle.Render("Enter to uninstall • backspace to cancel"))
+    return b.String()
+}
+
+// wrapList joins items with commas, breaking lines before width.
+func wrapList(items []string, width int) []string {
+    var lines []string
+    line := ""
+    for i, item := range items {
+        if i < len(items)-1 {
+            item += ","
+        }
+        if line != "" && len(line)+1+len(item) > width {
+            lines = append(lines, line)
+            line = ""
+        }
+        if line != "" {
+            line += " "
+        }
+        line += item
+    }
+    if line != "" {
+        lines = append(lines, line)
+    }
+    return lines
+}
+
+func (p *uninstallPlan) steps() []installStep {
+    steps := []installStep{
+        {name: "Stopping services", fn: func() error { return stopUnits(p.units) }},
+    }
+    if p.lit && !p.lnd {
+        steps = append(steps,
+            installStep{name: "Disabling RPC middleware in LND", fn: disableRPCMiddleware})
+    }
+    if !p.bitcoin {
+        steps = append(steps,
+            installStep{name: "Removing Tor hidden services", fn: func() error { return removeTorServices(p.onions, p.lnd) }},
+            installStep{name: "Restarting Tor", fn: restartTor})
+    }
+    if p.lnd && !p.bitcoin {
+        steps = append(steps,
+            installStep{name: "Closing LND P2P port", fn: func() error {
+                sys.Run("ufw", "delete", "allow", "9735/tcp")
+                return nil
+            }},
+            installStep{name: "Removing lncli helper", fn: removeLNCLIHelper})
+    }
+    if p.bitcoin {
+        steps = append(steps,
+            installStep{name: "Resetting firewall", fn: resetFirewall},
+            installStep{name: "Re-enabling IPv6", fn: enableIPv6},
+            installStep{name: "Removing shell helpers", fn: removeShellEnvironment})
+    }
+    if len(p.packages) > 0 {
+        steps = append(steps,
+            installStep{name: "Purging " + strings.Join(p.packages, ", "), fn: func() error {
+                return purgePackages(p.packages)
+            }})
+    }
+    steps = append(steps,
+        installStep{name: "Removing files", fn: func() error { return removePaths(p.paths) }})
+    if p.bitcoin && !p.keepData {
+        steps = append(steps,
+            installStep{name: "Removing system user", fn: func() error {
+                sys.Run("deluser", "--system", systemUser)
+                return nil
+            }})
+    }
+    return steps
+}
+
+// RunUninstall shows what will be removed for component and,
+// once confirmed, removes it. With keepData the bitcoin and lnd
+// data directories are left in place for a later reinstall.
+func RunUninstall(cfg *config.AppConfig, component string, keepData bool) error {
+    p, err := buildUninstallPlan(cfg, component, keepData)
+    if err != nil {
+        return err
+    }
+    if !showConfirmBox(p.confirmMessage()) {
+        return nil
+    }
+    if err := runInstallTUI(p.steps(), appVersion, nil); err != nil {
+        return err
+    }
+    if p.bitcoin {
+        return nil
+    }
+    if p.lit {
+        cfg.LITInstalled = false
+        cfg.LITPassword = ""
+    }
+    if p.syncthing {
+        cfg.SyncthingInstalled = false
+        cfg.SyncthingPassword = ""
+    }
+    if p.lnd {
+        cfg.Components = "bitcoin"
+        cfg.AutoUnlock = false
+    }
+    return config.Save(cfg)
+}
+
+// ── Removal steps ────────────────────────────────────────
+
+// stopUnits stops and disables each unit and deletes its unit
+// file. Units that are already gone are not an error.
+func stopUnits(units []string) error {
+    for _, u := range units {
+        sys.Run("systemctl", "stop", u)
+        sys.Run("systemctl", "disable", u)
+        if err := sys.Remove(paths.Node.Unit(u)); err != nil && !os.IsNotExist(err) {
+            return err
+        }
+    }
+    if output, err := sys.Run("systemctl", "daemon-reload"); err != nil {
+        return fmt.Errorf("daemon-reload: %s: %s", err, output)
+    }
+    return nil
+}
+
+func disableRPCMiddleware() error {
+    data, err := sys.ReadFile(paths.Node.LNDConf())
+    if err != nil {
+        return err
+    }
+    content := strings.Replace(string(data),
+        "\n# Required for Lightning Terminal\nrpcmiddleware.enable=true\n", "", 1)
+    if err := sys.WriteFile(paths.Node.LNDConf(), []byte(content), 0640); err != nil {
+        return err
+    }
+    if output, err := sys.Run("chown", "root:"+systemUser, paths.Live.LNDConf()); err != nil {
+        return fmt.Errorf("chown lnd.conf: %s: %s", err, output)
+    }
+    if output, err := sys.Run("systemctl", "restart", "lnd"); err != nil {
+        return fmt.Errorf("restart lnd: %s: %s", err, output)
+    }
+    return nil
+}
+
+// removeTorServices drops the named hidden services from torrc
+// along with their key directories. With controlPort the control
+// port LND used for its P2P onion goes too.
+func removeTorServices(names []string, controlPort bool) error {
+    data, err := sys.ReadFile(paths.Node.TorConfig())
+    if err != nil {
+        return err
+    }
+    content := removeTorBlocks(string(data), names)
+    if controlPort {
+        content = removeTorControlPort(content)
+    }
+    if err := sys.WriteFile(paths.Node.TorConfig(), []byte(content), 0644); err != nil {
+        return err
+    }
+    for _, name := range names {
+        sys.RemoveAll(paths.Node.OnionDir(name))
+    }
+    return nil
+}
+
+// removeTorBlocks removes each HiddenServiceDir for names, the
+// HiddenServicePort lines after it and the comment lines
+// directly above it.
+func removeTorBlocks(torrc string, names []string) string {
+    drop := make(map[string]bool)
+    for _, n := range names {
+        drop["HiddenServiceDir "+paths.Live.OnionDir(n)+"/"] = true
+    }
+    lines := strings.Split(torrc, "\n")
+    var out []string
+    for i := 0; i < len(lines); i++ {
+        if !drop[strings.TrimSpace(lines[i])] {
+            out = append(out, lines[i])
+            continue
+        }
+        for len(out) > 0 && strings.HasPrefix(out[len(out)-1], "#") {
+            out = out[:len(out)-1]
+        }
+        for i+1 < len(lines) && strings.HasPrefix(lines[i+1], "HiddenServicePort") {
+            i++
+        }
+    }
+    return joinTorLines(out)
+}
+
+// torControlLines are what writeTorConfig adds for LND's control
+// port.
+var torControlLines = map[string]bool{
+    "# Control port for LND P2P onion management": true,
+    "ControlPort 9051":                            true,
+    "CookieAuthentication 1":                      true,
+    "CookieAuthFileGroupReadable 1":               true,
+}
+
+// removeTorControlPort removes the control port block
+// writeTorConfig adds when LND is installed.
+func removeTorControlPort(torrc string) string {
+    var out []string
+    for _, line := range strings.Split(torrc, "\n") {
+        if !torControlLines[strings.TrimSpace(line)] {
+            out = append(out, line)
+        }
+    }
+    return joinTorLines(out)
+}
+
+// joinTorLines joins torrc lines, collapsing the blank lines left
+// where blocks were removed.
+func joinTorLines(out []string) string {
+    var result []string
+    for _, line := range out {
+        if line == "" && len(result) > 0 && result[len(result)-1] == "" {
+            continue
+        }
+        result = append(result, line)
+    }
+    return strings.TrimRight(strings.Join(result, "\n"), "\n") + "\n"
+}
+
+func resetFirewall() error {
+    for _, args := range [][]string{
+        {"ufw", "--force", "reset"},
+        {"ufw", "disable"},
+    } {
+        if output, err := sys.Run(args[0], args[1:]...); err != nil {
+            return fmt.Errorf("%v: %s: %s", args, err, output)
+        }
+    }
+    return nil
+}
+
+// enableIPv6 undoes disableIPv6 for the running kernel. The
+// sysctl.d file itself is removed with the other files.
+func enableIPv6() error {
+    for _, key := range []string{"all", "default", "lo"} {
+        sys.Run("sysctl", "-w", "net.ipv6.conf."+key+".disable_ipv6=0")
+    }
+    return nil
+}
+
+// removeShellEnvironment strips the block setupShellEnvironment
+// appended to .bashrc, leaving anything the admin added after it.
+func removeShellEnvironment() error {
+    path := paths.Node.AdminBashrc()
+    data, err := sys.ReadFile(path)
+    if err != nil {
+        return nil
+    }
+    content := removeShellBlock(string(data))
+    if content == string(data) {
+        return nil
+    }
+    return sys.WriteFile(path, []byte(content), 0644)
+}
+
+// removeShellBlock removes the lines from shellBlockStart through
+// shellBlockEnd, and the blank line setupShellEnvironment puts
+// before them. Blocks written before the end marker existed end
+// at the last of their export -f lines.
+func removeShellBlock(bashrc string) string {
+    lines := strings.Split(bashrc, "\n")
+    start := indexLine(lines, 0, shellBlockStart)
+    if start == -1 {
+        return bashrc
+    }
+    end := indexLine(lines, start+1, shellBlockEnd)
+    if end == -1 {
+        end = indexLine(lines, start+1, "export -f bitcoin-cli")
+        if end != -1 && end+2 < len(lines) && lines[end+1] == "" && lines[end+2] == "lncli() {" {
+            end = indexLine(lines, end+2, "export -f lncli")
+        }
+    }
+    if end == -1 {
+        return bashrc
+    }
+    if start > 0 && lines[start-1] == "" {
+        start--
+    }
+    return strings.Join(append(lines[:start:start], lines[end+1:]...), "\n")
+}
+
+// removeLNCLIHelper takes the lncli function out of the shell
+// block, leaving bitcoin-cli for the node that stays.
+func removeLNCLIHelper() error {
+    path := paths.Node.AdminBashrc()
+    data, err := sys.ReadFile(path)
+    if err != nil {
+        return nil
+    }
+    content := removeLNCLIFunc(string(data))
+    if content == string(data) {
+        return nil
+    }
+    return sys.WriteFile(path, []byte(content), 0644)
+}
+
+// removeLNCLIFunc removes the lines from "lncli() {" through
+// "export -f lncli" inside the shell block, with the blank line
+// before them. An lncli the admin defined elsewhere is left alone.
+func removeLNCLIFunc(bashrc string) string {
+    lines := strings.Split(bashrc, "\n")
+    start := indexLine(lines, 0, shellBlockStart)
+    if start == -1 {
+        return bashrc
+    }
+    from := indexLine(lines, start+1, "lncli() {")
+    if end := indexLine(lines, start+1, shellBlockEnd); from == -1 || (end != -1 && from > end) {
+        return bashrc
+    }
+    to := indexLine(lines, from+1, "export -f lncli")
+    if to == -1 {
+        return bashrc
+    }
+    if lines[from-1] == "" {
+        from--
+    }
+    return strings.Join(append(lines[:from:from], lines[to+1:]...), "\n")
+}
+
+// indexLine returns the index of the first line from from on
+// that is want, or -1.
+func indexLine(lines []string, from int, want string) int {
+    for i := from; i < len(lines); i++ {
+        if lines[i] == want {
+            return i
+        }
+    }
+    return -1
+}
+
+func purgePackages(packages []string) error {
+    args := append([]string{"purge", "-y", "-qq"}, packages...)
+    if output, err := sys.Run("apt-get", args...); err != nil {
+        return fmt.Errorf("apt-get purge: %s: %s", err, output)
+    }
+    return nil
+}
+
+func removePaths(list []string) error {
+    for _, path := range list {
+        if err := sys.RemoveAll(paths.Node.Path(path)); err != nil {
+            return fmt.Errorf("remove %s: %w", path, err)
+        }
+    }
+    return nil
+}
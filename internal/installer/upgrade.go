@@ -0,0 +1,569 @@
+package installer
+
+import (
+    "context"
+    "fmt"
+    "path/filepath"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/ripsline/virtual-private-node/internal/config"
+    "github.com/ripsline/virtual-private-node/internal/paths"
+)
+
+// Upgrades install a release next to the running one under
+// paths.Release and repoint the links in /usr/local/bin, so the
+// previous binaries are still on disk to roll back to.
+
+// healthPoll and healthTimeout bound how long an upgraded daemon
+// gets to answer RPC. Variables so tests need not wait.
+var (
+    healthPoll    = 5 * time.Second
+    healthTimeout = 10 * time.Minute
+)
+
+// releaseName is the versioned directory name for component.
+func releaseName(component, version string) string {
+    return component + "-" + version
+}
+
+// installRelease copies bins from srcDir into the versioned
+// directory for component and version.
+func installRelease(component, version, srcDir string, bins []string) error {
+    dir := paths.Live.Release(releaseName(component, version))
+    if output, err := sys.Run("install", "-d", "-m", "0755", dir); err != nil {
+        return fmt.Errorf("create %s: %s: %s", dir, err, output)
+    }
+    for _, bin := range bins {
+        if output, err := sys.Run("install", "-m", "0755", "-o", "root", "-g", "root",
+            srcDir+"/"+bin, dir+"/"); err != nil {
+            return fmt.Errorf("install %s: %s: %s", bin, err, output)
+        }
+    }
+    return nil
+}
+
+// preserveRelease copies the binaries currently in /usr/local/bin
+// into the versioned directory for version, unless an earlier
+// upgrade already put them there. Nodes installed before upgrades
+// existed have plain files rather than links.
+func preserveRelease(component, version string, bins []string) error {
+    if _, err := sys.Stat(paths.Node.Release(releaseName(component, version))); err == nil {
+        return nil
+    }
+    var present []string
+    for _, bin := range bins {
+        if _, err := sys.Stat(paths.Node.Bin(bin)); err == nil {
+            present = append(present, bin)
+        }
+    }
+    if len(present) == 0 {
+        return fmt.Errorf("no %s binaries in %s to keep for rollback", component, paths.Live.Bin(""))
+    }
+    return installRelease(component, version, paths.Live.Bin(""), present)
+}
+
+// activateRelease points /usr/local/bin at every binary in the
+// versioned directory for component and version.
+func activateRelease(component, version string) error {
+    name := releaseName(component, version)
+    entries, err := sys.ReadDir(paths.Node.Release(name))
+    if err != nil {
+        return fmt.Errorf("read %s: %w", paths.Live.Release(name), err)
+    }
+    for _, e := range entries {
+        target := paths.Live.Release(name) + "/" + e.Name()
+        if output, err := sys.Run("ln", "-sfn", target, paths.Live.Bin(e.Name())); err != nil {
+            return fmt.Errorf("link %s: %s: %s", e.Name(), err, output)
+        }
+    }
+    return nil
+}
+
+// ── Bitcoin Core ─────────────────────────────────────────
+
+var bitcoinVersionRe = regexp.MustCompile(`^(\d+)\.(\d+)(?:\.(\d+))?$`)
+
+// bitcoinVersionNumber converts a release version such as "29.3"
+// to the integer getnetworkinfo reports (290300).
+func bitcoinVersionNumber(version string) (int, error) {
+    m := bitcoinVersionRe.FindStringSubmatch(version)
+    if m == nil {
+        return 0, fmt.Errorf("%q is not a Bitcoin Core release version (like %s)", version, bitcoinVersion)
+    }
+    n := 0
+    for _, part := range m[1:] {
+        v, _ := strconv.Atoi(part)
+        n = n*100 + v
+    }
+    return n, nil
+}
+
+// checkBitcoinUpgrade refuses a version that is not newer than
+// previous. An older release may not open the block index or
+// wallets a newer one has written.
+func checkBitcoinUpgrade(version, previous string) error {
+    n, err := bitcoinVersionNumber(version)
+    if err != nil {
+        return err
+    }
+    prev, err := bitcoinVersionNumber(previous)
+    if err != nil {
+        return fmt.Errorf("installed version: %w", err)
+    }
+    switch {
+    case n == prev:
+        return fmt.Errorf("Bitcoin Core %s is already installed", previous)
+    case n < prev:
+        return fmt.Errorf("Bitcoin Core %s is older than the installed %s; downgrades are not supported", version, previous)
+    }
+    return nil
+}
+
+// InstalledBitcoinVersion is the Bitcoin Core release the node
+// runs. Nodes installed before it was recorded run the version
+// this build installs.
+func InstalledBitcoinVersion(cfg *config.AppConfig) string {
+    if cfg.BitcoinVersion != "" {
+        return cfg.BitcoinVersion
+    }
+    return bitcoinVersion
+}
+
+// installBitcoinRelease extracts the verified tarball into its
+// versioned directory and cleans up /tmp.
+func installBitcoinRelease(version string) error {
+    filename := fmt.Sprintf("bitcoin-%s-x86_64-linux-gnu.tar.gz", version)
+    if output, err := sys.Run("tar", "-xzf", paths.Live.Tmp(filename), "-C", paths.Live.Tmp("")); err != nil {
+        return fmt.Errorf("extract: %s: %s", err, output)
+    }
+    binDir := fmt.Sprintf("bitcoin-%s/bin", version)
+    bins, err := bitcoinBinaries(paths.Node.Tmp(binDir))
+    if err != nil {
+        return err
+    }
+    if err := installRelease("bitcoin", version, paths.Live.Tmp(binDir), bins); err != nil {
+        return err
+    }
+    sys.Remove(paths.Node.Tmp(filename))
+    sys.Remove(paths.Node.Tmp("SHA256SUMS"))
+    sys.Remove(paths.Node.Tmp("SHA256SUMS.asc"))
+    sys.RemoveAll(paths.Node.Tmp("bitcoin-" + version))
+    return nil
+}
+
+func bitcoinBinaries(dir string) ([]string, error) {
+    entries, err := sys.ReadDir(dir)
+    if err != nil {
+        return nil, fmt.Errorf("read dir: %w", err)
+    }
+    var bins []string
+    for _, e := range entries {
+        bins = append(bins, e.Name())
+    }
+    return bins, nil
+}
+
+// preserveBitcoinRelease keeps the running binaries under their
+// version, using the new release's file list as the names to look
+// for.
+func preserveBitcoinRelease(version, previous string) error {
+    bins, err := bitcoinBinaries(paths.Node.Release(releaseName("bitcoin", version)))
+    if err != nil {
+        return err
+    }
+    return preserveRelease("bitcoin", previous, bins)
+}
+
+// waitBitcoinRPC waits for bitcoind on network to answer
+// getnetworkinfo as version. It gives up early if systemd reports
+// the unit is no longer running.
+func waitBitcoinRPC(network, version string) error {
+    want, err := bitcoinVersionNumber(version)
+    if err != nil {
+        return err
+    }
+    rpc, err := newBitcoinRPC(network)
+    if err != nil {
+        return err
+    }
+    deadline := time.Now().Add(healthTimeout)
+    for {
+        ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+        info, err := rpc.GetNetworkInfo(ctx)
+        cancel()
+        if err == nil {
+            if info.Version != want {
+                return fmt.Errorf("bitcoind reports version %d, want %d", info.Version, want)
+            }
+            return nil
+        }
+        if _, err := sys.Run("systemctl", "is-active", "--quiet", "bitcoind"); err != nil {
+            return fmt.Errorf("bitcoind stopped running")
+        }
+        if time.Now().After(deadline) {
+            return fmt.Errorf("bitcoind RPC did not respond within %s", healthTimeout)
+        }
+        time.Sleep(healthPoll)
+    }
+}
+
+// startUpgradedBitcoind starts bitcoind on version and checks RPC.
+// If it does not come up, the links go back to previous and that
+// is started instead; the step still fails so the upgrade is
+// reported as not done.
+func startUpgradedBitcoind(network, version, previous string) error {
+    err := func() error {
+        if output, err := sys.Run("systemctl", "start", "bitcoind"); err != nil {
+            return fmt.Errorf("start bitcoind: %s: %s", err, output)
+        }
+        return waitBitcoinRPC(network, version)
+    }()
+    if err == nil {
+        return nil
+    }
+    sys.Run("systemctl", "stop", "bitcoind")
+    if rerr := activateRelease("bitcoin", previous); rerr != nil {
+        return fmt.Errorf("%v; rollback failed: %v", err, rerr)
+    }
+    sys.Run("systemctl", "start", "bitcoind")
+    if rerr := waitBitcoinRPC(network, previous); rerr != nil {
+        return fmt.Errorf("%v; rolled back to %s, which did not come up either: %v", err, previous, rerr)
+    }
+    return fmt.Errorf("%v; rolled back to %s", err, previous)
+}
+
+func bitcoinUpgradeSteps(network, version, previous string) []installStep {
+    return []installStep{
+        {name: "Downloading Bitcoin Core " + version, fn: func() error { return downloadBitcoin(version) }},
+        {name: "Downloading Bitcoin Core signatures", fn: func() error { return downloadBitcoinSigFile(version) }},
+        {name: "Verifying Bitcoin Core signatures (2/5)", fn: func() error { return verifyBitcoinCoreSigs(2) }},
+        {name: "Verifying Bitcoin Core checksum", fn: func() error { return verifyBitcoin(version) }},
+        {name: "Installing Bitcoin Core " + version, fn: func() error { return installBitcoinRelease(version) }},
+        {name: "Keeping Bitcoin Core " + previous + " for rollback",
+            fn: func() error { return preserveBitcoinRelease(version, previous) }},
+        {name: "Stopping Bitcoin Core", fn: func() error {
+            if output, err := sys.Run("systemctl", "stop", "bitcoind"); err != nil {
+                return fmt.Errorf("stop bitcoind: %s: %s", err, output)
+            }
+            return nil
+        }},
+        {name: "Switching to Bitcoin Core " + version, fn: func() error { return activateRelease("bitcoin", version) }},
+        {name: "Starting Bitcoin Core " + version,
+            fn: func() error { return startUpgradedBitcoind(network, version, previous) }},
+    }
+}
+
+// RunBitcoinUpgrade installs Bitcoin Core version alongside the
+// running release and switches to it, rolling back if bitcoind
+// does not answer RPC afterwards.
+func RunBitcoinUpgrade(cfg *config.AppConfig, version string) error {
+    previous := InstalledBitcoinVersion(cfg)
+    if err := checkBitcoinUpgrade(version, previous); err != nil {
+        return err
+    }
+    downloads.viaTor = cfg.DownloadsViaTor()
+    confirmMsg := setupTitleStyle.Render("Upgrade Bitcoin Core") + "\n\n" +
+        setupTextStyle.Render("This will:") + "\n\n" +
+        setupTextStyle.Render("  • Download and verify Bitcoin Core "+version) + "\n" +
+        setupTextStyle.Render("  • Install it to "+paths.Live.Release(releaseName("bitcoin", version))) + "\n" +
+        setupTextStyle.Render("  • Keep "+previous+" in "+paths.Live.Release(releaseName("bitcoin", previous))) + "\n" +
+        setupTextStyle.Render("  • Restart bitcoind on "+version) + "\n\n" +
+        setupDimStyle.Render("If bitcoind does not answer RPC, "+previous+" is restored.") + "\n\n" +
+        setupDimStyle.Render("Enter to proceed • backspace to cancel")
+    if !showConfirmBox(confirmMsg) {
+        return nil
+    }
+    if err := runInstallTUI(bitcoinUpgradeSteps(cfg.Network, version, previous), appVersion, nil); err != nil {
+        return err
+    }
+    cfg.BitcoinVersion = version
+    return config.Save(cfg)
+}
+
+// RunBitcoinUpgradePrompt asks for the version to install, with
+// the release this build ships as the default. Used by the
+// dashboard, which has no text input of its own.
+func RunBitcoinUpgradePrompt(cfg *config.AppConfig) error {
+    version := promptVersion("Bitcoin Core", InstalledBitcoinVersion(cfg), bitcoinVersion)
+    return reportFailure("Upgrade", RunBitcoinUpgrade(cfg, version))
+}
+
+// promptVersion reads a release version on the plain terminal,
+// falling back to def on an empty line.
+func promptVersion(title, installed, def string) string {
+    fmt.Print("\033[2J\033[H")
+    fmt.Println("\n  ═══════════════════════════════════════════")
+    fmt.Println("    Upgrade " + title)
+    fmt.Print("  ═══════════════════════════════════════════\n\n")
+    fmt.Printf("  Installed: %s\n\n", installed)
+    fmt.Printf("  Version to install [%s]: ", def)
+    if version := readLine(stdin); version != "" {
+        return version
+    }
+    return def
+}
+
+// ── LND ──────────────────────────────────────────────────
+
+var lndVersionRe = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)-beta$`)
+
+// lndVersionNumber converts a release version such as
+// "0.20.0-beta" to a number that orders releases (2000).
+func lndVersionNumber(version string) (int, error) {
+    m := lndVersionRe.FindStringSubmatch(version)
+    if m == nil {
+        return 0, fmt.Errorf("%q is not an LND release version (like %s)", version, lndVersion)
+    }
+    n := 0
+    for _, part := range m[1:] {
+        v, _ := strconv.Atoi(part)
+        n = n*100 + v
+    }
+    return n, nil
+}
+
+// checkLNDUpgrade refuses a version that is not newer than
+// previous. LND's database migrations only run forwards, so an
+// older release cannot open channel.db after a newer one has.
+func checkLNDUpgrade(version, previous string) error {
+    n, err := lndVersionNumber(version)
+    if err != nil {
+        return err
+    }
+    prev, err := lndVersionNumber(previous)
+    if err != nil {
+        return fmt.Errorf("installed version: %w", err)
+    }
+    switch {
+    case n == prev:
+        return fmt.Errorf("LND %s is already installed", previous)
+    case n < prev:
+        return fmt.Errorf("LND %s is older than the installed %s; downgrades are not supported", version, previous)
+    }
+    return nil
+}
+
+// lndBinaries are the files an LND release installs.
+var lndBinaries = []string{"lnd", "lncli"}
+
+// InstalledLNDVersion is the LND release the node runs, like
+// InstalledBitcoinVersion.
+func InstalledLNDVersion(cfg *config.AppConfig) string {
+    if cfg.LNDVersion != "" {
+        return cfg.LNDVersion
+    }
+    return lndVersion
+}
+
+func installLNDRelease(version string) error {
+    filename := fmt.Sprintf("lnd-linux-amd64-v%s.tar.gz", version)
+    if output, err := sys.Run("tar", "-xzf", paths.Live.Tmp(filename), "-C", paths.Live.Tmp("")); err != nil {
+        return fmt.Errorf("extract: %s: %s", err, output)
+    }
+    extractDir := fmt.Sprintf("lnd-linux-amd64-v%s", version)
+    if err := installRelease("lnd", version, paths.Live.Tmp(extractDir), lndBinaries); err != nil {
+        return err
+    }
+    sys.Remove(paths.Node.Tmp(filename))
+    sys.Remove(paths.Node.Tmp("manifest.txt"))
+    sys.RemoveAll(paths.Node.Tmp(extractDir))
+    return nil
+}
+
+// lndDatabases are the files snapshotted before an upgrade: the
+// channel state and the on-chain wallet. A node without a wallet
+// yet has neither.
+func lndDatabases(network string) []string {
+    var dbs []string
+    for _, db := range []string{paths.Live.LNDChannelDB(network), paths.Live.LNDWalletDB(network)} {
+        if _, err := sys.Stat(paths.Node.Path(db)); err == nil {
+            dbs = append(dbs, db)
+        }
+    }
+    return dbs
+}
+
+// snapshotLNDDatabases copies the databases into the backup
+// directory for previous. LND must be stopped so the copies are
+// consistent.
+func snapshotLNDDatabases(network, previous string) error {
+    dir := paths.Live.LNDUpgradeBackup(previous)
+    if output, err := sys.Run("install", "-d", "-m", "0700", "-o", systemUser, "-g", systemUser, dir); err != nil {
+        return fmt.Errorf("create %s: %s: %s", dir, err, output)
+    }
+    for _, db := range lndDatabases(network) {
+        name := filepath.Base(db)
+        if output, err := sys.Run("cp", "-a", db, dir+"/"+name); err != nil {
+            return fmt.Errorf("back up %s: %s: %s", name, err, output)
+        }
+    }
+    return nil
+}
+
+// restoreLNDDatabases copies the snapshot taken by
+// snapshotLNDDatabases back over the live databases.
+func restoreLNDDatabases(network, previous string) error {
+    dir := paths.Live.LNDUpgradeBackup(previous)
+    for _, db := range []string{paths.Live.LNDChannelDB(network), paths.Live.LNDWalletDB(network)} {
+        backup := dir + "/" + filepath.Base(db)
+        if _, err := sys.Stat(paths.Node.Path(backup)); err != nil {
+            continue
+        }
+        if output, err := sys.Run("cp", "-a", backup, db); err != nil {
+            return fmt.Errorf("restore %s: %s: %s", filepath.Base(db), err, output)
+        }
+    }
+    return nil
+}
+
+// waitLNDState waits for LND to reach want on /v1/state.
+func waitLNDState(want string) error {
+    deadline := time.Now().Add(healthTimeout)
+    last := ""
+    for {
+        if state, err := lndState(); err == nil {
+            if lndStateReached(state, want) {
+                return nil
+            }
+            last = state
+        }
+        if _, err := sys.Run("systemctl", "is-active", "--quiet", "lnd"); err != nil {
+            return fmt.Errorf("lnd stopped running")
+        }
+        if time.Now().After(deadline) {
+            if last == "" {
+                return fmt.Errorf("LND did not respond within %s", healthTimeout)
+            }
+            return fmt.Errorf("LND stayed %s instead of %s for %s", last, want, healthTimeout)
+        }
+        time.Sleep(healthPoll)
+    }
+}
+
+// lndHealthyState is what an upgraded LND must reach. Without
+// auto-unlock nobody is there to unlock the wallet, so answering
+// as LOCKED is as far as the check can go.
+func lndHealthyState(cfg *config.AppConfig) string {
+    switch {
+    case !cfg.WalletExists():
+        return "NON_EXISTING"
+    case cfg.AutoUnlock:
+        return "RPC_ACTIVE"
+    }
+    return "LOCKED"
+}
+
+// lndStateReached reports whether state satisfies want. LND moves
+// on from RPC_ACTIVE to SERVER_ACTIVE once synced, which counts.
+func lndStateReached(state, want string) bool {
+    if want == "RPC_ACTIVE" && state == "SERVER_ACTIVE" {
+        return true
+    }
+    return state == want
+}
+
+// startUpgradedLND starts LND on the new binaries and waits for
+// want. On failure the old binaries and the database snapshot go
+// back and the previous release is started.
+func startUpgradedLND(network, previous, want string) error {
+    err := func() error {
+        if output, err := sys.Run("systemctl", "start", "lnd"); err != nil {
+            return fmt.Errorf("start lnd: %s: %s", err, output)
+        }
+        return waitLNDState(want)
+    }()
+    if err == nil {
+        return nil
+    }
+    sys.Run("systemctl", "stop", "lnd")
+    if rerr := restoreLNDDatabases(network, previous); rerr != nil {
+        return fmt.Errorf("%v; rollback failed: %v", err, rerr)
+    }
+    if rerr := activateRelease("lnd", previous); rerr != nil {
+        return fmt.Errorf("%v; rollback failed: %v", err, rerr)
+    }
+    sys.Run("systemctl", "start", "lnd")
+    if rerr := waitLNDState(want); rerr != nil {
+        return fmt.Errorf("%v; rolled back to %s, which did not come up either: %v", err, previous, rerr)
+    }
+    return fmt.Errorf("%v; rolled back to %s", err, previous)
+}
+
+func lndUpgradeSteps(cfg *config.AppConfig, version, previous string) []installStep {
+    network := cfg.Network
+    want := lndHealthyState(cfg)
+    steps := []installStep{
+        {name: "Downloading LND " + version, fn: func() error { return downloadLND(version) }},
+        {name: "Verifying LND signature", fn: func() error { return verifyLNDSig(version) }},
+        {name: "Verifying LND checksum", fn: func() error { return verifyLND(version) }},
+        {name: "Installing LND " + version, fn: func() error { return installLNDRelease(version) }},
+        {name: "Keeping LND " + previous + " for rollback",
+            fn: func() error { return preserveRelease("lnd", previous, lndBinaries) }},
+        {name: "Stopping LND", fn: func() error {
+            if output, err := sys.Run("systemctl", "stop", "lnd"); err != nil {
+                return fmt.Errorf("stop lnd: %s: %s", err, output)
+            }
+            return nil
+        }},
+        {name: "Backing up LND databases",
+            fn: func() error { return snapshotLNDDatabases(network, previous) }},
+        {name: "Switching to LND " + version, fn: func() error { return activateRelease("lnd", version) }},
+        {name: "Starting LND " + version,
+            fn: func() error { return startUpgradedLND(network, previous, want) }},
+    }
+    if cfg.LITInstalled {
+        steps = append(steps, installStep{name: "Restarting Lightning Terminal", fn: func() error {
+            if output, err := sys.Run("systemctl", "restart", "litd"); err != nil {
+                return fmt.Errorf("restart litd: %s: %s", err, output)
+            }
+            return nil
+        }})
+    }
+    return steps
+}
+
+// RunLNDUpgrade installs LND version alongside the running release
+// and switches to it. The channel and wallet databases are
+// snapshotted first and restored with the old binaries if LND
+// does not come back up.
+func RunLNDUpgrade(cfg *config.AppConfig, version string) error {
+    version = strings.TrimPrefix(version, "v")
+    if !cfg.HasLND() {
+        return fmt.Errorf("LND is not installed")
+    }
+    previous := InstalledLNDVersion(cfg)
+    if err := checkLNDUpgrade(version, previous); err != nil {
+        return err
+    }
+    downloads.viaTor = cfg.DownloadsViaTor()
+    want := lndHealthyState(cfg)
+    confirmMsg := setupTitleStyle.Render("Upgrade LND") + "\n\n" +
+        setupTextStyle.Render("This will:") + "\n\n" +
+        setupTextStyle.Render("  • Download and verify LND "+version) + "\n" +
+        setupTextStyle.Render("  • Install it to "+paths.Live.Release(releaseName("lnd", version))) + "\n" +
+        setupTextStyle.Render("  • Stop LND and back up channel.db and wallet.db") + "\n" +
+        setupTextStyle.Render("    to "+paths.Live.LNDUpgradeBackup(previous)) + "\n" +
+        setupTextStyle.Render("  • Restart LND on "+version) + "\n\n" +
+        setupDimStyle.Render("If LND does not reach "+want+", the backup and "+previous+" are restored.") + "\n\n"
+    if want == "LOCKED" {
+        confirmMsg += setupWarnStyle.Render("Auto-unlock is off: unlock the wallet afterwards.") + "\n\n"
+    }
+    confirmMsg += setupDimStyle.Render("Enter to proceed • backspace to cancel")
+    if !showConfirmBox(confirmMsg) {
+        return nil
+    }
+    if err := runInstallTUI(lndUpgradeSteps(cfg, version, previous), appVersion, nil); err != nil {
+        return err
+    }
+    cfg.LNDVersion = version
+    return config.Save(cfg)
+}
+
+// RunLNDUpgradePrompt is RunBitcoinUpgradePrompt for LND.
+func RunLNDUpgradePrompt(cfg *config.AppConfig) error {
+    version := promptVersion("LND", InstalledLNDVersion(cfg), lndVersion)
+    return reportFailure("Upgrade", RunLNDUpgrade(cfg, version))
+}
@@ -1,238 +1,389 @@
 package installer
 
 import (
+    "bytes"
+    "embed"
+    "encoding/hex"
     "fmt"
-    "os"
-    "os/exec"
+    "io"
+    "io/fs"
     "strings"
+    "time"
+
+    "github.com/ProtonMail/go-crypto/openpgp"
+    "github.com/ProtonMail/go-crypto/openpgp/armor"
+    pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+    "github.com/ProtonMail/go-crypto/openpgp/packet"
+
+    "github.com/ripsline/virtual-private-node/internal/paths"
 )
 
 // ── Trusted signing keys ─────────────────────────────────
 //
-// PRIMARY key fingerprints (from gpg --list-keys after import).
-// GPG signs with subkeys but we verify the primary key exists.
+// Public keys are embedded from keys/<name>.asc and must have the
+// PRIMARY key fingerprint pinned here. Signatures may come from
+// any of the key's signing subkeys. Nothing is imported into a
+// GPG keyring.
+
+//go:embed keys
+var embeddedKeys embed.FS
+
+// signingKeys holds keys/<name>.asc for every signer. Tests
+// replace it with generated keys.
+var signingKeys fs.FS = embeddedKeys
 
-var bitcoinCoreSigners = []struct {
+// pgpNow is the time keys and signatures are checked against.
+var pgpNow = time.Now
+
+type signer struct {
     name        string
     fingerprint string
-    keyURL      string
-}{
+    // source is where keys/<name>.asc was exported from.
+    source string
+}
+
+var bitcoinCoreSigners = []signer{
     {
         name:        "fanquake",
         fingerprint: "E777299FC265DD04793070EB944D35F9AC3DB76A",
-        keyURL:      "https://raw.githubusercontent.com/bitcoin-core/guix.sigs/main/builder-keys/fanquake.gpg",
+        source:      "https://raw.githubusercontent.com/bitcoin-core/guix.sigs/main/builder-keys/fanquake.gpg",
     },
     {
         name:        "guggero",
         fingerprint: "FDE04B7075113BFB085020B57BBD8D4D95DB9F03",
-        keyURL:      "https://raw.githubusercontent.com/bitcoin-core/guix.sigs/main/builder-keys/guggero.gpg",
+        source:      "https://raw.githubusercontent.com/bitcoin-core/guix.sigs/main/builder-keys/guggero.gpg",
     },
     {
         name:        "hebasto",
         fingerprint: "CBE89ED88EE8525FD8D79F1EDB56ADFD8B5EF498",
-        keyURL:      "https://raw.githubusercontent.com/bitcoin-core/guix.sigs/main/builder-keys/hebasto.gpg",
+        source:      "https://raw.githubusercontent.com/bitcoin-core/guix.sigs/main/builder-keys/hebasto.gpg",
     },
     {
         name:        "theStack",
         fingerprint: "9343A22960A50972CC1EFD7DB3B5CB8DB648B27F",
-        keyURL:      "https://raw.githubusercontent.com/bitcoin-core/guix.sigs/main/builder-keys/theStack.gpg",
+        source:      "https://raw.githubusercontent.com/bitcoin-core/guix.sigs/main/builder-keys/theStack.gpg",
     },
     {
         name:        "willcl-ark",
         fingerprint: "A0083660F235A27000CD3C81CE6EC49945C17EA6",
-        keyURL:      "https://raw.githubusercontent.com/bitcoin-core/guix.sigs/main/builder-keys/willcl-ark.gpg",
+        source:      "https://raw.githubusercontent.com/bitcoin-core/guix.sigs/main/builder-keys/willcl-ark.gpg",
     },
 }
 
-var lndSigner = struct {
-    name        string
-    fingerprint string
-    keyURL      string
-}{
+var lndSigner = signer{
     name:        "roasbeef",
     fingerprint: "296212681AADF05656A2CDEE90525F7DEEE0AD86",
-    keyURL:      "https://raw.githubusercontent.com/lightningnetwork/lnd/master/scripts/keys/roasbeef.asc",
+    source:      "https://raw.githubusercontent.com/lightningnetwork/lnd/master/scripts/keys/roasbeef.asc",
 }
 
-var litSigner = struct {
-    name        string
-    fingerprint string
-    keyID       string
-}{
+var litSigner = signer{
     name:        "ViktorT-11",
     fingerprint: "C20A78516A0944900EBFCA29961CC8259AE675D4",
-    keyID:       "C20A78516A0944900EBFCA29961CC8259AE675D4",
+    source:      "hkps://keyserver.ubuntu.com",
 }
 
-// ── GPG setup ────────────────────────────────────────────
+// ── Per-signer results ───────────────────────────────────
 
-func ensureGPG() error {
-    if _, err := exec.LookPath("gpg"); err == nil {
-        return nil
-    }
-    cmd := exec.Command("apt-get", "install", "-y", "-qq", "gnupg")
-    if output, err := cmd.CombinedOutput(); err != nil {
-        return fmt.Errorf("install gpg: %s: %s", err, output)
-    }
-    return nil
-}
+type sigStatus int
 
-// ── Key import ───────────────────────────────────────────
+const (
+    sigMissing sigStatus = iota
+    sigGood
+    sigBad
+    sigExpired
+    sigRevoked
+    sigNoKey
+)
 
-// importBitcoinCoreKeys downloads and imports trusted builder keys.
-// Continues on individual failures — we only need 2/5 later.
-func importBitcoinCoreKeys() error {
-    imported := 0
-    for _, signer := range bitcoinCoreSigners {
-        keyFile := fmt.Sprintf("/tmp/btc-key-%s.gpg", signer.name)
-        if err := download(signer.keyURL, keyFile); err != nil {
-            continue
-        }
-        cmd := exec.Command("gpg", "--batch", "--import", keyFile)
-        cmd.CombinedOutput()
-        os.Remove(keyFile)
+var sigStatusText = map[sigStatus]string{
+    sigMissing: "no signature",
+    sigGood:    "good",
+    sigBad:     "bad signature",
+    sigExpired: "expired",
+    sigRevoked: "revoked",
+    sigNoKey:   "key unavailable",
+}
 
-        if gpgHasFingerprint(signer.fingerprint) {
-            imported++
-        }
-    }
-    if imported == 0 {
-        return fmt.Errorf("could not import any Bitcoin Core signing keys")
-    }
-    return nil
+type signerResult struct {
+    signer signer
+    status sigStatus
+    detail string
 }
 
-func importLNDKey() error {
-    keyFile := "/tmp/lnd-key-roasbeef.asc"
-    if err := download(lndSigner.keyURL, keyFile); err != nil {
-        return fmt.Errorf("download LND signing key: %w", err)
+func (r signerResult) String() string {
+    s := r.signer.name + ": " + sigStatusText[r.status]
+    if r.detail != "" {
+        s += " (" + r.detail + ")"
     }
-    defer os.Remove(keyFile)
+    return s
+}
 
-    cmd := exec.Command("gpg", "--batch", "--import", keyFile)
-    if output, err := cmd.CombinedOutput(); err != nil {
-        return fmt.Errorf("import LND key: %s: %s", err, output)
-    }
-    if !gpgHasFingerprint(lndSigner.fingerprint) {
-        return fmt.Errorf("LND key fingerprint mismatch")
-    }
-    return nil
+// signatureError reports a manifest without enough good
+// signatures, with the outcome for every trusted signer.
+type signatureError struct {
+    manifest string
+    need     int
+    results  []signerResult
 }
 
-func importLITKey() error {
-    cmd := exec.Command("gpg", "--batch", "--keyserver",
-        "hkps://keyserver.ubuntu.com", "--recv-keys", litSigner.keyID)
-    if output, err := cmd.CombinedOutput(); err != nil {
-        return fmt.Errorf("import LIT key: %s: %s", err, output)
-    }
-    if !gpgHasFingerprint(litSigner.fingerprint) {
-        return fmt.Errorf("LIT key fingerprint mismatch")
+func (e *signatureError) Error() string {
+    var parts []string
+    for _, r := range e.results {
+        parts = append(parts, r.String())
     }
-    return nil
+    return fmt.Sprintf("%s: %d of %d trusted signatures valid, need %d: %s",
+        e.manifest, countGood(e.results), len(e.results), e.need,
+        strings.Join(parts, "; "))
 }
 
-// ── Signature verification ───────────────────────────────
-
-// verifyBitcoinCoreSigs counts GOODSIG lines in GPG output.
-// Since we only import trusted keys, every GOODSIG is from
-// a trusted builder. Requires minValid valid signatures.
-func verifyBitcoinCoreSigs(minValid int) error {
-    sumsFile := "/tmp/SHA256SUMS"
-    sigFile := "/tmp/SHA256SUMS.asc"
-
-    if _, err := os.Stat(sumsFile); err != nil {
-        return fmt.Errorf("SHA256SUMS not found")
-    }
-    if _, err := os.Stat(sigFile); err != nil {
-        return fmt.Errorf("SHA256SUMS.asc not found")
+func countGood(results []signerResult) int {
+    n := 0
+    for _, r := range results {
+        if r.status == sigGood {
+            n++
+        }
     }
+    return n
+}
 
-    cmd := exec.Command("gpg", "--batch", "--verify",
-        "--status-fd", "1", sigFile, sumsFile)
-    output, _ := cmd.CombinedOutput()
+// manifestSigners remembers who signed each verified manifest so
+// recordVerifiedHash can store it next to the checksum.
+var manifestSigners = make(map[string][]string)
 
-    validCount := strings.Count(string(output), "GOODSIG")
+// ── Signature verification ───────────────────────────────
 
-    if validCount < minValid {
-        return fmt.Errorf(
-            "insufficient valid signatures: got %d, need %d",
-            validCount, minValid)
-    }
-    return nil
+// verifyBitcoinCoreSigs requires minValid of the trusted builders
+// to have signed SHA256SUMS.
+func verifyBitcoinCoreSigs(minValid int) error {
+    return verifyManifest("SHA256SUMS", "SHA256SUMS.asc", bitcoinCoreSigners, minValid)
 }
 
-// verifyLNDSig verifies the LND manifest GPG signature.
+// verifyLNDSig verifies the LND manifest signature.
 func verifyLNDSig(version string) error {
-    manifestFile := "/tmp/manifest.txt"
-    sigFile := fmt.Sprintf("/tmp/manifest-roasbeef-v%s.sig", version)
-
-    if _, err := os.Stat(manifestFile); err != nil {
-        return fmt.Errorf("LND manifest not found at %s", manifestFile)
-    }
-
+    sigFile := fmt.Sprintf("manifest-roasbeef-v%s.sig", version)
     sigURL := fmt.Sprintf(
         "https://github.com/lightningnetwork/lnd/releases/download/v%s/manifest-roasbeef-v%s.sig",
         version, version)
-    if err := download(sigURL, sigFile); err != nil {
+    if err := download(sigURL, paths.Node.Tmp(sigFile)); err != nil {
         return fmt.Errorf("download LND signature: %w", err)
     }
-    defer os.Remove(sigFile)
+    defer sys.Remove(paths.Node.Tmp(sigFile))
+    return verifyManifest("manifest.txt", sigFile, []signer{lndSigner}, 1)
+}
+
+// verifyLITSig verifies the LIT manifest signature.
+func verifyLITSig(version string) error {
+    sigFile := fmt.Sprintf("manifest-ViktorT-11-v%s.sig", version)
+    sigURL := fmt.Sprintf(
+        "https://github.com/lightninglabs/lightning-terminal/releases/download/v%s/manifest-ViktorT-11-v%s.sig",
+        version, version)
+    if err := download(sigURL, paths.Node.Tmp(sigFile)); err != nil {
+        return fmt.Errorf("download LIT signature: %w", err)
+    }
+    defer sys.Remove(paths.Node.Tmp(sigFile))
+    return verifyManifest("lit-manifest.txt", sigFile, []signer{litSigner}, 1)
+}
 
-    cmd := exec.Command("gpg", "--batch", "--verify",
-        "--status-fd", "1", sigFile, manifestFile)
-    output, err := cmd.CombinedOutput()
+// verifyManifest checks the detached signatures in /tmp/sigFile
+// over /tmp/manifest and requires need of signers to be good.
+func verifyManifest(manifest, sigFile string, signers []signer, need int) error {
+    data, err := sys.ReadFile(paths.Node.Tmp(manifest))
     if err != nil {
-        return fmt.Errorf("LND signature verification failed: %s", output)
+        return fmt.Errorf("%s not found", manifest)
     }
-    if !strings.Contains(string(output), "GOODSIG") {
-        return fmt.Errorf("LND signature invalid")
+    sigData, err := sys.ReadFile(paths.Node.Tmp(sigFile))
+    if err != nil {
+        return fmt.Errorf("%s not found", sigFile)
+    }
+    sigs, err := readSignatures(sigData)
+    if err != nil {
+        return fmt.Errorf("%s: %w", sigFile, err)
+    }
+    results := checkSigners(data, sigs, signers)
+    if countGood(results) < need {
+        return &signatureError{manifest: manifest, need: need, results: results}
     }
+    var good []string
+    for _, r := range results {
+        if r.status == sigGood {
+            good = append(good, r.signer.name)
+        }
+    }
+    manifestSigners[manifest] = good
     return nil
 }
 
-// verifyLITSig verifies the LIT manifest GPG signature.
-func verifyLITSig(version string) error {
-    manifestFile := "/tmp/lit-manifest.txt"
-    sigFile := fmt.Sprintf("/tmp/manifest-ViktorT-11-v%s.sig", version)
+// checkSigners finds each signer's signature among sigs and
+// verifies it. A signer with several signatures is reported by
+// the best outcome.
+func checkSigners(data []byte, sigs []*packet.Signature, signers []signer) []signerResult {
+    var results []signerResult
+    for _, s := range signers {
+        res := signerResult{signer: s}
+        entity, err := loadSignerKey(s)
+        if err != nil {
+            res.status, res.detail = sigNoKey, err.Error()
+            results = append(results, res)
+            continue
+        }
+        keyring := openpgp.EntityList{entity}
+        for _, sig := range sigs {
+            if sig.IssuerKeyId == nil {
+                continue
+            }
+            for _, key := range keyring.KeysById(*sig.IssuerKeyId) {
+                status, detail := checkSignature(data, sig, key)
+                if res.status == sigMissing || status == sigGood {
+                    res.status, res.detail = status, detail
+                }
+            }
+            if res.status == sigGood {
+                break
+            }
+        }
+        results = append(results, res)
+    }
+    return results
+}
 
-    if _, err := os.Stat(manifestFile); err != nil {
-        return fmt.Errorf("LIT manifest not found at %s", manifestFile)
+// checkSignature verifies one signature by key and checks the key
+// was neither revoked nor expired.
+func checkSignature(data []byte, sig *packet.Signature, key openpgp.Key) (sigStatus, string) {
+    if len(key.Entity.Revocations) > 0 {
+        return sigRevoked, "primary key"
+    }
+    subkey := key.PublicKey != key.Entity.PrimaryKey
+    // a subkey's revocations are kept apart from its binding
+    // signature, in key.Revocations
+    if subkey && len(key.Revocations) > 0 {
+        return sigRevoked, "signing subkey"
+    }
+    self := key.SelfSignature
+    if self == nil {
+        return sigBad, "key has no self-signature"
+    }
+    if self.FlagsValid && !self.FlagSign {
+        return sigBad, "key is not a signing key"
     }
 
-    sigURL := fmt.Sprintf(
-        "https://github.com/lightninglabs/lightning-terminal/releases/download/v%s/manifest-ViktorT-11-v%s.sig",
-        version, version)
-    if err := download(sigURL, sigFile); err != nil {
-        return fmt.Errorf("download LIT signature: %w", err)
+    if sig.SigType == packet.SigTypeText {
+        data = canonicalText(data)
+    }
+    if !sig.Hash.Available() {
+        return sigBad, fmt.Sprintf("unsupported hash %s", sig.Hash)
+    }
+    h := sig.Hash.New()
+    h.Write(data)
+    if err := key.PublicKey.VerifySignature(h, sig); err != nil {
+        return sigBad, err.Error()
     }
-    defer os.Remove(sigFile)
 
-    cmd := exec.Command("gpg", "--batch", "--verify",
-        "--status-fd", "1", sigFile, manifestFile)
-    output, err := cmd.CombinedOutput()
-    if err != nil {
-        return fmt.Errorf("LIT signature verification failed: %s", output)
+    now := pgpNow()
+    // a subkey cannot outlive its primary key, whose expiry is in
+    // the primary self-signature rather than the subkey binding
+    if subkey {
+        if primary, _ := key.Entity.PrimarySelfSignature(); primary != nil {
+            if expiry, ok := keyExpiry(key.Entity.PrimaryKey, primary); ok && now.After(expiry) {
+                return sigExpired, "primary key expired " + expiry.Format("2006-01-02")
+            }
+        }
     }
-    if !strings.Contains(string(output), "GOODSIG") {
-        return fmt.Errorf("LIT signature invalid")
+    if expiry, ok := keyExpiry(key.PublicKey, self); ok && now.After(expiry) {
+        return sigExpired, "key expired " + expiry.Format("2006-01-02")
     }
-    return nil
+    if sig.SigLifetimeSecs != nil && *sig.SigLifetimeSecs != 0 {
+        expiry := sig.CreationTime.Add(time.Duration(*sig.SigLifetimeSecs) * time.Second)
+        if now.After(expiry) {
+            return sigExpired, "signature expired " + expiry.Format("2006-01-02")
+        }
+    }
+    return sigGood, ""
 }
 
-// ── Helpers ──────────────────────────────────────────────
+// keyExpiry returns when pk expires by its self-signature, and
+// false if it does not.
+func keyExpiry(pk *packet.PublicKey, self *packet.Signature) (time.Time, bool) {
+    if self.KeyLifetimeSecs == nil || *self.KeyLifetimeSecs == 0 {
+        return time.Time{}, false
+    }
+    return pk.CreationTime.Add(time.Duration(*self.KeyLifetimeSecs) * time.Second), true
+}
 
-func gpgHasFingerprint(fingerprint string) bool {
-    cmd := exec.Command("gpg", "--batch", "--list-keys",
-        "--with-colons", fingerprint)
-    output, err := cmd.CombinedOutput()
+// canonicalText converts line endings to CRLF as text-mode
+// signatures are computed over.
+func canonicalText(data []byte) []byte {
+    data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+    return bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+}
+
+// loadSignerKey reads keys/<name>.asc and returns the entity
+// whose primary key has the pinned fingerprint.
+func loadSignerKey(s signer) (*openpgp.Entity, error) {
+    f, err := signingKeys.Open("keys/" + s.name + ".asc")
+    if err != nil {
+        return nil, fmt.Errorf("keys/%s.asc not embedded; export it from %s", s.name, s.source)
+    }
+    defer f.Close()
+    keyring, err := openpgp.ReadArmoredKeyRing(f)
     if err != nil {
-        return false
+        return nil, fmt.Errorf("keys/%s.asc: %w", s.name, err)
+    }
+    for _, e := range keyring {
+        if strings.EqualFold(hex.EncodeToString(e.PrimaryKey.Fingerprint[:]), s.fingerprint) {
+            return e, nil
+        }
+    }
+    return nil, fmt.Errorf("keys/%s.asc does not contain %s", s.name, s.fingerprint)
+}
+
+// readSignatures parses detached signatures, armored or binary.
+// SHA256SUMS.asc concatenates one armored block per builder.
+// Signatures this package cannot parse are skipped; their signer
+// is reported as having no signature.
+func readSignatures(data []byte) ([]*packet.Signature, error) {
+    const begin = "-----BEGIN PGP SIGNATURE-----"
+    if !bytes.Contains(data, []byte(begin)) {
+        return readSignaturePackets(bytes.NewReader(data))
+    }
+    var sigs []*packet.Signature
+    for _, block := range strings.Split(string(data), begin)[1:] {
+        b, err := armor.Decode(strings.NewReader(begin + block))
+        if err != nil {
+            return nil, err
+        }
+        s, err := readSignaturePackets(b.Body)
+        if err != nil {
+            return nil, err
+        }
+        sigs = append(sigs, s...)
     }
-    return strings.Contains(string(output), fingerprint)
+    return sigs, nil
 }
 
+func readSignaturePackets(r io.Reader) ([]*packet.Signature, error) {
+    var sigs []*packet.Signature
+    packets := packet.NewReader(r)
+    for {
+        p, err := packets.Next()
+        if err == io.EOF {
+            return sigs, nil
+        }
+        if _, ok := err.(pgperrors.UnsupportedError); ok {
+            continue
+        }
+        if err != nil {
+            return nil, err
+        }
+        if sig, ok := p.(*packet.Signature); ok {
+            sigs = append(sigs, sig)
+        }
+    }
+}
+
+// ── Helpers ──────────────────────────────────────────────
+
 func downloadBitcoinSigFile(version string) error {
     url := fmt.Sprintf(
         "https://bitcoincore.org/bin/bitcoin-core-%s/SHA256SUMS.asc", version)
-    return download(url, "/tmp/SHA256SUMS.asc")
-}
\ No newline at end of file
+    return download(url, paths.Node.Tmp("SHA256SUMS.asc"))
+}
@@ -0,0 +1,314 @@
+package installer
+
+import (
+    "bytes"
+    "crypto"
+    "encoding/hex"
+    "errors"
+    "io/fs"
+    "strings"
+    "sync"
+    "testing"
+    "testing/fstest"
+    "time"
+
+    "github.com/ProtonMail/go-crypto/openpgp"
+    "github.com/ProtonMail/go-crypto/openpgp/armor"
+    "github.com/ProtonMail/go-crypto/openpgp/packet"
+
+    "github.com/ripsline/virtual-private-node/internal/system"
+)
+
+var (
+    testKeysOnce sync.Once
+    testKeys     map[string]*openpgp.Entity
+)
+
+// testConfig keeps generated keys small so the suite stays fast.
+var testConfig = &packet.Config{RSABits: 1024}
+
+// useTestKeys replaces the pinned signers and embedded keys with
+// generated ones under the same names.
+func useTestKeys(t *testing.T) {
+    t.Helper()
+    testKeysOnce.Do(func() {
+        testKeys = make(map[string]*openpgp.Entity)
+        names := []string{lndSigner.name, litSigner.name}
+        for _, s := range bitcoinCoreSigners {
+            names = append(names, s.name)
+        }
+        for _, name := range names {
+            e, err := openpgp.NewEntity(name, "test", name+"@example.com", testConfig)
+            if err != nil {
+                panic(err)
+            }
+            testKeys[name] = e
+        }
+    })
+
+    prevBTC, prevLND, prevLIT, prevKeys := bitcoinCoreSigners, lndSigner, litSigner, signingKeys
+    t.Cleanup(func() {
+        bitcoinCoreSigners, lndSigner, litSigner, signingKeys = prevBTC, prevLND, prevLIT, prevKeys
+    })
+
+    keys := fstest.MapFS{}
+    pin := func(s signer) signer {
+        e := testKeys[s.name]
+        s.fingerprint = strings.ToUpper(hex.EncodeToString(e.PrimaryKey.Fingerprint[:]))
+        keys["keys/"+s.name+".asc"] = &fstest.MapFile{Data: armoredPublicKey(t, e)}
+        return s
+    }
+    btc := make([]signer, len(bitcoinCoreSigners))
+    for i, s := range bitcoinCoreSigners {
+        btc[i] = pin(s)
+    }
+    bitcoinCoreSigners = btc
+    lndSigner = pin(lndSigner)
+    litSigner = pin(litSigner)
+    signingKeys = keys
+}
+
+func armoredPublicKey(t *testing.T, e *openpgp.Entity) []byte {
+    var buf bytes.Buffer
+    w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := e.Serialize(w); err != nil {
+        t.Fatal(err)
+    }
+    w.Close()
+    return buf.Bytes()
+}
+
+// signManifest returns one armored detached signature per signer,
+// concatenated as in Bitcoin Core's SHA256SUMS.asc.
+func signManifest(t *testing.T, rec *system.Recorder, manifest string, signers ...string) string {
+    data, err := rec.ReadFile(manifest)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var out strings.Builder
+    for _, name := range signers {
+        var buf bytes.Buffer
+        if err := openpgp.ArmoredDetachSign(&buf, testKeys[name], bytes.NewReader(data), testConfig); err != nil {
+            t.Fatal(err)
+        }
+        out.WriteString(buf.String() + "\n")
+    }
+    return out.String()
+}
+
+func TestVerifyBitcoinCoreSigs(t *testing.T) {
+    names := func() []string {
+        var n []string
+        for _, s := range bitcoinCoreSigners {
+            n = append(n, s.name)
+        }
+        return n
+    }
+
+    t.Run("threshold met", func(t *testing.T) {
+        newTestRecorder(t)
+        if err := verifyBitcoinCoreSigs(2); err != nil {
+            t.Fatal(err)
+        }
+        got := strings.Join(manifestSigners["SHA256SUMS"], ",")
+        want := bitcoinCoreSigners[0].name + "," + bitcoinCoreSigners[2].name
+        if got != want {
+            t.Errorf("signers = %s, want %s", got, want)
+        }
+    })
+
+    t.Run("threshold not met", func(t *testing.T) {
+        rec := newTestRecorder(t)
+        rec.AddFile("/tmp/SHA256SUMS.asc", signManifest(t, rec, "/tmp/SHA256SUMS", names()[1]))
+        err := verifyBitcoinCoreSigs(2)
+        var se *signatureError
+        if !errors.As(err, &se) {
+            t.Fatalf("err = %v, want *signatureError", err)
+        }
+        want := []sigStatus{sigMissing, sigGood, sigMissing, sigMissing, sigMissing}
+        for i, r := range se.results {
+            if r.status != want[i] {
+                t.Errorf("%s: got %q, want %q", r.signer.name,
+                    sigStatusText[r.status], sigStatusText[want[i]])
+            }
+        }
+    })
+
+    t.Run("tampered manifest", func(t *testing.T) {
+        rec := newTestRecorder(t)
+        rec.AddFile("/tmp/SHA256SUMS", strings.Repeat("00", 32)+"  evil.tar.gz\n")
+        err := verifyBitcoinCoreSigs(1)
+        var se *signatureError
+        if !errors.As(err, &se) {
+            t.Fatalf("err = %v, want *signatureError", err)
+        }
+        if se.results[0].status != sigBad {
+            t.Errorf("fanquake: got %q, want bad signature", sigStatusText[se.results[0].status])
+        }
+    })
+
+    t.Run("key not embedded", func(t *testing.T) {
+        newTestRecorder(t)
+        keys := signingKeys.(fstest.MapFS)
+        delete(keys, "keys/"+bitcoinCoreSigners[0].name+".asc")
+        err := verifyBitcoinCoreSigs(2)
+        var se *signatureError
+        if !errors.As(err, &se) || se.results[0].status != sigNoKey {
+            t.Fatalf("err = %v, want fanquake key unavailable", err)
+        }
+    })
+
+    t.Run("fingerprint mismatch", func(t *testing.T) {
+        newTestRecorder(t)
+        bitcoinCoreSigners[0].fingerprint = strings.Repeat("A", 40)
+        err := verifyBitcoinCoreSigs(2)
+        var se *signatureError
+        if !errors.As(err, &se) || se.results[0].status != sigNoKey {
+            t.Fatalf("err = %v, want fanquake key unavailable", err)
+        }
+    })
+}
+
+func TestCheckSignatureExpiryAndRevocation(t *testing.T) {
+    useTestKeys(t)
+    data := []byte("manifest\n")
+    e := testKeys[lndSigner.name]
+    var buf bytes.Buffer
+    if err := openpgp.DetachSign(&buf, e, bytes.NewReader(data), testConfig); err != nil {
+        t.Fatal(err)
+    }
+    sigs, err := readSignatures(buf.Bytes())
+    if err != nil || len(sigs) != 1 {
+        t.Fatalf("readSignatures: %v, %d signatures", err, len(sigs))
+    }
+    key := openpgp.EntityList{e}.KeysById(*sigs[0].IssuerKeyId)[0]
+
+    if status, detail := checkSignature(data, sigs[0], key); status != sigGood {
+        t.Fatalf("fresh key: %s %s", sigStatusText[status], detail)
+    }
+
+    t.Run("expired", func(t *testing.T) {
+        lifetime := uint32(3600)
+        self := *key.SelfSignature
+        self.KeyLifetimeSecs = &lifetime
+        k := key
+        k.SelfSignature = &self
+        prev := pgpNow
+        pgpNow = func() time.Time { return e.PrimaryKey.CreationTime.Add(2 * time.Hour) }
+        defer func() { pgpNow = prev }()
+        if status, _ := checkSignature(data, sigs[0], k); status != sigExpired {
+            t.Errorf("got %q, want expired", sigStatusText[status])
+        }
+    })
+
+    t.Run("unlinked hash", func(t *testing.T) {
+        sig := *sigs[0]
+        sig.Hash = crypto.MD4
+        if status, detail := checkSignature(data, &sig, key); status != sigBad {
+            t.Errorf("got %q %s, want bad signature", sigStatusText[status], detail)
+        }
+    })
+
+    t.Run("revoked", func(t *testing.T) {
+        revoked := *e
+        revoked.Revocations = []*packet.Signature{{SigType: packet.SigTypeKeyRevocation}}
+        k := key
+        k.Entity = &revoked
+        if status, _ := checkSignature(data, sigs[0], k); status != sigRevoked {
+            t.Errorf("got %q, want revoked", sigStatusText[status])
+        }
+    })
+}
+
+func TestCheckSignatureSubkey(t *testing.T) {
+    // the primary key expires after an hour; the signing subkey
+    // binding has no expiry of its own
+    e, err := openpgp.NewEntity("sub", "test", "sub@example.com", &packet.Config{RSABits: 1024, KeyLifetimeSecs: 3600})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := e.AddSigningSubkey(testConfig); err != nil {
+        t.Fatal(err)
+    }
+    data := []byte("manifest\n")
+    var buf bytes.Buffer
+    if err := openpgp.DetachSign(&buf, e, bytes.NewReader(data), testConfig); err != nil {
+        t.Fatal(err)
+    }
+    sigs, err := readSignatures(buf.Bytes())
+    if err != nil || len(sigs) != 1 {
+        t.Fatalf("readSignatures: %v, %d signatures", err, len(sigs))
+    }
+    sub := e.Subkeys[len(e.Subkeys)-1]
+    if *sigs[0].IssuerKeyId != sub.PublicKey.KeyId {
+        t.Fatal("manifest not signed by the subkey")
+    }
+    keyFor := func() openpgp.Key {
+        return openpgp.EntityList{e}.KeysById(*sigs[0].IssuerKeyId)[0]
+    }
+    if status, detail := checkSignature(data, sigs[0], keyFor()); status != sigGood {
+        t.Fatalf("fresh subkey: %s %s", sigStatusText[status], detail)
+    }
+
+    t.Run("expired primary", func(t *testing.T) {
+        prev := pgpNow
+        pgpNow = func() time.Time { return e.PrimaryKey.CreationTime.Add(2 * time.Hour) }
+        defer func() { pgpNow = prev }()
+        if status, detail := checkSignature(data, sigs[0], keyFor()); status != sigExpired {
+            t.Errorf("got %q %s, want expired", sigStatusText[status], detail)
+        }
+    })
+
+    t.Run("revoked subkey", func(t *testing.T) {
+        if err := e.RevokeSubkey(&e.Subkeys[len(e.Subkeys)-1], packet.KeyCompromised, "", testConfig); err != nil {
+            t.Fatal(err)
+        }
+        if status, detail := checkSignature(data, sigs[0], keyFor()); status != sigRevoked {
+            t.Errorf("got %q %s, want revoked", sigStatusText[status], detail)
+        }
+    })
+}
+
+// TestEmbeddedKeys checks the keys shipped in the binary, not the
+// generated ones the other tests swap in: every pinned signer must
+// load and match its fingerprint.
+func TestEmbeddedKeys(t *testing.T) {
+    if signingKeys != fs.FS(embeddedKeys) {
+        t.Fatal("signingKeys is not the embedded keys directory")
+    }
+    signers := append([]signer{lndSigner, litSigner}, bitcoinCoreSigners...)
+    for _, s := range signers {
+        e, err := loadSignerKey(s)
+        if err != nil {
+            t.Errorf("%s: %v", s.name, err)
+            continue
+        }
+        if got := strings.ToUpper(hex.EncodeToString(e.PrimaryKey.Fingerprint[:])); got != s.fingerprint {
+            t.Errorf("%s: fingerprint %s, pinned %s", s.name, got, s.fingerprint)
+        }
+    }
+}
+
+func TestCheckSignatureEdDSA(t *testing.T) {
+    // LND and Lightning Terminal signers may use ed25519 keys
+    e, err := openpgp.NewEntity("ed", "test", "ed@example.com", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+    if err != nil {
+        t.Fatal(err)
+    }
+    data := []byte("manifest\n")
+    var buf bytes.Buffer
+    if err := openpgp.ArmoredDetachSign(&buf, e, bytes.NewReader(data), nil); err != nil {
+        t.Fatal(err)
+    }
+    sigs, err := readSignatures(buf.Bytes())
+    if err != nil || len(sigs) != 1 {
+        t.Fatalf("readSignatures: %v, %d signatures", err, len(sigs))
+    }
+    key := openpgp.EntityList{e}.KeysById(*sigs[0].IssuerKeyId)[0]
+    if status, detail := checkSignature(data, sigs[0], key); status != sigGood {
+        t.Errorf("got %q %s, want good", sigStatusText[status], detail)
+    }
+}
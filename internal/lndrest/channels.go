@@ -0,0 +1,139 @@
+package lndrest
+
+import (
+    "context"
+    "encoding/base64"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "slices"
+    "strconv"
+    "strings"
+)
+
+// txidFromBytes turns the base64 txid LND's REST API sends into
+// the hex form block explorers show. The bytes are in internal
+// order, which is the reverse of the displayed hash.
+func txidFromBytes(b64 string) (string, error) {
+    b, err := base64.StdEncoding.DecodeString(b64)
+    if err != nil {
+        return "", fmt.Errorf("txid %q: %w", b64, err)
+    }
+    slices.Reverse(b)
+    return hex.EncodeToString(b), nil
+}
+
+// ConnectPeer connects to pubkey at host (host:port). Being
+// connected already is not an error.
+func (c *Client) ConnectPeer(ctx context.Context, pubkey, host string) error {
+    body := map[string]any{
+        "addr":    map[string]string{"pubkey": pubkey, "host": host},
+        "perm":    false,
+        "timeout": "30",
+    }
+    err := c.Do(ctx, http.MethodPost, "/v1/peers", body, nil)
+    if err != nil && strings.Contains(err.Error(), "already connected") {
+        return nil
+    }
+    return err
+}
+
+// OpenChannelRequest is what OpenChannel needs; the peer has to
+// be connected first. SatPerVByte 0 leaves the fee to LND.
+type OpenChannelRequest struct {
+    Pubkey      string
+    AmountSats  int64
+    SatPerVByte int64
+    Private     bool
+}
+
+// OpenChannel funds a channel from the on-chain wallet and
+// returns the funding transaction's txid once it is broadcast.
+func (c *Client) OpenChannel(ctx context.Context, r OpenChannelRequest) (string, error) {
+    pub, err := hex.DecodeString(r.Pubkey)
+    if err != nil || len(pub) != 33 {
+        return "", fmt.Errorf("%q is not a node pubkey", r.Pubkey)
+    }
+    body := map[string]any{
+        "node_pubkey":          base64.StdEncoding.EncodeToString(pub),
+        "local_funding_amount": strconv.FormatInt(r.AmountSats, 10),
+        "private":              r.Private,
+    }
+    if r.SatPerVByte > 0 {
+        body["sat_per_vbyte"] = strconv.FormatInt(r.SatPerVByte, 10)
+    }
+    var point struct {
+        FundingTxidBytes string `json:"funding_txid_bytes"`
+        FundingTxidStr   string `json:"funding_txid_str"`
+    }
+    if err := c.Do(ctx, http.MethodPost, "/v1/channels", body, &point); err != nil {
+        return "", err
+    }
+    if point.FundingTxidStr != "" {
+        return point.FundingTxidStr, nil
+    }
+    return txidFromBytes(point.FundingTxidBytes)
+}
+
+// CloseChannel starts closing the channel at channelPoint
+// (txid:index) and returns the closing txid once it is broadcast.
+// A cooperative close needs the peer online and pays satPerVByte,
+// 0 for LND's estimate; a force close needs neither, and the
+// funds wait out the channel's timelock.
+func (c *Client) CloseChannel(ctx context.Context, channelPoint string, force bool, satPerVByte int64) (string, error) {
+    txid, index, ok := strings.Cut(channelPoint, ":")
+    if !ok {
+        return "", fmt.Errorf("%q is not a channel point", channelPoint)
+    }
+    q := url.Values{}
+    if force {
+        q.Set("force", "true")
+    } else if satPerVByte > 0 {
+        q.Set("sat_per_vbyte", strconv.FormatInt(satPerVByte, 10))
+    }
+    path := "/v1/channels/" + url.PathEscape(txid) + "/" + url.PathEscape(index)
+    if len(q) > 0 {
+        path += "?" + q.Encode()
+    }
+    // The reply is a stream of updates; the first says the
+    // closing transaction is out, which is all we wait for.
+    resp, err := c.send(ctx, http.MethodDelete, path, nil, true)
+    if err != nil {
+        return "", err
+    }
+    defer resp.Body.Close()
+    var update struct {
+        Result struct {
+            ClosePending struct {
+                Txid string `json:"txid"`
+            } `json:"close_pending"`
+        } `json:"result"`
+        Error *Error `json:"error"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&update); err != nil {
+        return "", fmt.Errorf("close %s: %w", channelPoint, err)
+    }
+    if update.Error != nil {
+        return "", fmt.Errorf("close %s: %w", channelPoint, update.Error)
+    }
+    if update.Result.ClosePending.Txid == "" {
+        return "", fmt.Errorf("close %s: no closing transaction in reply", channelPoint)
+    }
+    return txidFromBytes(update.Result.ClosePending.Txid)
+}
+
+// NodeAlias looks pubkey up in the channel graph. Nodes that have
+// not announced themselves have no alias.
+func (c *Client) NodeAlias(ctx context.Context, pubkey string) (string, error) {
+    var resp struct {
+        Node struct {
+            Alias string `json:"alias"`
+        } `json:"node"`
+    }
+    if err := c.get(ctx, "/v1/graph/node/"+url.PathEscape(pubkey), &resp); err != nil {
+        return "", err
+    }
+    return resp.Node.Alias, nil
+}
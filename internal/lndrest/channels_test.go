@@ -0,0 +1,65 @@
+package lndrest
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "strings"
+    "testing"
+)
+
+const testPubkey = "03864ef025fde8fb587d989186ce6a4a186895ee44a926bfc370e2c366597a3f8f"
+
+func TestOpenChannel(t *testing.T) {
+    c := newTestClient(t, func(r *http.Request) (int, string) {
+        var body map[string]any
+        if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+            t.Errorf("decode request: %v", err)
+        }
+        if r.Method != http.MethodPost || r.URL.Path != "/v1/channels" ||
+            body["node_pubkey"] != "A4ZO8CX96PtYfZiRhs5qShhole5EqSa/w3Diw2ZZej+P" ||
+            body["local_funding_amount"] != "250000" || body["sat_per_vbyte"] != "4" || body["private"] != true {
+            t.Errorf("got %s %s %v", r.Method, r.URL.Path, body)
+        }
+        // txid bytes are sent reversed
+        return 200, `{"funding_txid_bytes": "AQIDBA==", "output_index": 0}`
+    })
+    txid, err := c.OpenChannel(context.Background(), OpenChannelRequest{
+        Pubkey: testPubkey, AmountSats: 250000, SatPerVByte: 4, Private: true,
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if txid != "04030201" {
+        t.Errorf("txid = %s, want 04030201", txid)
+    }
+
+    if _, err := c.OpenChannel(context.Background(), OpenChannelRequest{Pubkey: "02ab", AmountSats: 1}); err == nil {
+        t.Error("short pubkey accepted")
+    }
+}
+
+func TestCloseChannel(t *testing.T) {
+    c := newTestClient(t, func(r *http.Request) (int, string) {
+        if r.Method != http.MethodDelete || r.URL.Path != "/v1/channels/abcd/1" || r.URL.Query().Get("force") != "true" {
+            t.Errorf("got %s %s", r.Method, r.URL)
+        }
+        return 200, `{"result": {"close_pending": {"txid": "BQY=", "output_index": 0}}}` + "\n" +
+            `{"result": {"chan_close": {"closing_txid": "BQY=", "success": true}}}` + "\n"
+    })
+    txid, err := c.CloseChannel(context.Background(), "abcd:1", true, 0)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if txid != "0605" {
+        t.Errorf("txid = %s, want 0605", txid)
+    }
+
+    c = newTestClient(t, func(r *http.Request) (int, string) {
+        return 200, `{"error": {"code": 2, "message": "peer is offline"}}`
+    })
+    if _, err := c.CloseChannel(context.Background(), "abcd:1", false, 3); err == nil ||
+        !strings.Contains(err.Error(), "peer is offline") {
+        t.Errorf("err = %v, want the stream's error", err)
+    }
+}
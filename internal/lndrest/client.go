@@ -0,0 +1,356 @@
+// Package lndrest is a small client for the node's own LND REST
+// API on localhost:8080. It pins LND's self-signed TLS certificate
+// and authenticates with a network's admin macaroon.
+package lndrest
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "crypto/tls"
+    "crypto/x509"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "os"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Client calls one LND's REST API. A Client keeps its connections
+// open between calls, so callers should make one and reuse it.
+type Client struct {
+    // URL is the REST endpoint, https://localhost:8080.
+    URL string
+    // MacaroonPath is read on every call but State, since the
+    // macaroon only appears once the wallet exists. Empty sends
+    // none.
+    MacaroonPath string
+    // CertPath is LND's self-signed TLS certificate. It is read
+    // on every call rather than in New, since LND only writes it
+    // on first start and writes a new one when it expires or is
+    // deleted.
+    CertPath string
+    // HTTP, when set, is used for every call instead of one built
+    // from CertPath.
+    HTTP *http.Client
+
+    mu       sync.Mutex
+    pinned   *http.Client
+    certSum  [sha256.Size]byte // of the cert pinned
+    insecure *http.Client
+}
+
+// New returns a client for LND on localhost:8080, trusting the
+// certificate at certPath and using the macaroon at macaroonPath.
+func New(certPath, macaroonPath string) *Client {
+    return &Client{
+        URL:          "https://localhost:8080",
+        MacaroonPath: macaroonPath,
+        CertPath:     certPath,
+    }
+}
+
+// idleConnTimeout closes connections left idle between polls, so
+// a client that is dropped does not hold them open.
+const idleConnTimeout = 30 * time.Second
+
+// newHTTPClient returns an HTTP client with its own transport. It
+// has no timeout of its own: opening and closing channels wait on
+// the peer, so every call is bounded by its context instead.
+func newHTTPClient(tlsConfig *tls.Config) *http.Client {
+    return &http.Client{
+        Transport: &http.Transport{
+            TLSClientConfig: tlsConfig,
+            IdleConnTimeout: idleConnTimeout,
+        },
+    }
+}
+
+// httpClient returns the HTTP client for a call. Calls that carry
+// the macaroon or a body, such as InitWallet's seed, need the
+// certificate pinned; State, which sends nothing, falls back to an
+// unchecked connection until LND has written its certificate. LND
+// only listens on localhost. The pinned client is rebuilt when the
+// certificate on disk changes; while it is missing or half written
+// the last one stays pinned.
+func (c *Client) httpClient(secret bool) (*http.Client, error) {
+    if c.HTTP != nil {
+        return c.HTTP, nil
+    }
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    certData, err := os.ReadFile(c.CertPath)
+    if sum := sha256.Sum256(certData); err == nil && (c.pinned == nil || sum != c.certSum) {
+        pool := x509.NewCertPool()
+        if pool.AppendCertsFromPEM(certData) {
+            if c.pinned != nil {
+                c.pinned.CloseIdleConnections()
+            }
+            c.pinned = newHTTPClient(&tls.Config{RootCAs: pool})
+            c.certSum = sum
+        } else {
+            err = fmt.Errorf("%s: no certificate found", c.CertPath)
+        }
+    }
+    if c.pinned != nil {
+        return c.pinned, nil
+    }
+    if secret {
+        return nil, fmt.Errorf("LND TLS certificate: %w", err)
+    }
+    if c.insecure == nil {
+        c.insecure = newHTTPClient(&tls.Config{InsecureSkipVerify: true})
+    }
+    return c.insecure, nil
+}
+
+// Error is an error reported by LND, such as a locked wallet or a
+// bad request.
+type Error struct {
+    Code    int    `json:"code"`
+    Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+    return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// Do sends method to path, with body encoded as JSON when it is
+// not nil, and decodes the reply into result, which may be nil to
+// discard it.
+func (c *Client) Do(ctx context.Context, method, path string, body, result any) error {
+    return c.do(ctx, method, path, body, result, c.MacaroonPath != "")
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, result any, macaroon bool) error {
+    resp, err := c.send(ctx, method, path, body, macaroon)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    data, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return fmt.Errorf("%s: %w", path, err)
+    }
+    if result == nil {
+        return nil
+    }
+    if err := json.Unmarshal(data, result); err != nil {
+        return fmt.Errorf("%s: %w", path, err)
+    }
+    return nil
+}
+
+// send makes the request and returns the response when LND
+// answers 200. The caller closes the body.
+func (c *Client) send(ctx context.Context, method, path string, body any, macaroon bool) (*http.Response, error) {
+    var reqBody io.Reader
+    if body != nil {
+        data, err := json.Marshal(body)
+        if err != nil {
+            return nil, err
+        }
+        reqBody = bytes.NewReader(data)
+    }
+    req, err := http.NewRequestWithContext(ctx, method, c.URL+path, reqBody)
+    if err != nil {
+        return nil, err
+    }
+    if macaroon {
+        mac, err := os.ReadFile(c.MacaroonPath)
+        if err != nil {
+            return nil, fmt.Errorf("read macaroon: %w", err)
+        }
+        req.Header.Set("Grpc-Metadata-macaroon", hex.EncodeToString(mac))
+    }
+    if body != nil {
+        req.Header.Set("Content-Type", "application/json")
+    }
+    client, err := c.httpClient(macaroon || body != nil)
+    if err != nil {
+        return nil, err
+    }
+    resp, err := client.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+    if resp.StatusCode == http.StatusOK {
+        return resp, nil
+    }
+    defer resp.Body.Close()
+    data, _ := io.ReadAll(resp.Body)
+    var lndErr Error
+    if json.Unmarshal(data, &lndErr) == nil && lndErr.Message != "" {
+        return nil, fmt.Errorf("%s: %w", path, &lndErr)
+    }
+    return nil, fmt.Errorf("%s: HTTP %d: %s", path, resp.StatusCode, strings.TrimSpace(string(data)))
+}
+
+func (c *Client) get(ctx context.Context, path string, result any) error {
+    return c.Do(ctx, http.MethodGet, path, nil, result)
+}
+
+// State returns LND's state, such as LOCKED, RPC_ACTIVE or
+// SERVER_ACTIVE. It needs no macaroon and answers while the
+// wallet is locked.
+func (c *Client) State(ctx context.Context) (string, error) {
+    var st struct {
+        State string `json:"state"`
+    }
+    if err := c.do(ctx, http.MethodGet, "/v1/state", nil, &st, false); err != nil {
+        return "", err
+    }
+    return st.State, nil
+}
+
+// Info is the result of getinfo.
+type Info struct {
+    IdentityPubkey      string   `json:"identity_pubkey"`
+    Alias               string   `json:"alias"`
+    Version             string   `json:"version"`
+    NumPendingChannels  int      `json:"num_pending_channels"`
+    NumActiveChannels   int      `json:"num_active_channels"`
+    NumInactiveChannels int      `json:"num_inactive_channels"`
+    NumPeers            int      `json:"num_peers"`
+    BlockHeight         int64    `json:"block_height"`
+    SyncedToChain       bool     `json:"synced_to_chain"`
+    SyncedToGraph       bool     `json:"synced_to_graph"`
+    URIs                []string `json:"uris"`
+}
+
+func (c *Client) GetInfo(ctx context.Context) (*Info, error) {
+    var info Info
+    if err := c.get(ctx, "/v1/getinfo", &info); err != nil {
+        return nil, err
+    }
+    return &info, nil
+}
+
+// WalletBalance is the result of walletbalance, in sats. LND's
+// REST API sends 64-bit numbers as strings. AnchorReserve is held
+// back to fee-bump anchor channel closes.
+type WalletBalance struct {
+    Total         int64 `json:"total_balance,string"`
+    Confirmed     int64 `json:"confirmed_balance,string"`
+    Unconfirmed   int64 `json:"unconfirmed_balance,string"`
+    Locked        int64 `json:"locked_balance,string"`
+    AnchorReserve int64 `json:"reserved_balance_anchor_chan,string"`
+}
+
+func (c *Client) WalletBalance(ctx context.Context) (*WalletBalance, error) {
+    var b WalletBalance
+    if err := c.get(ctx, "/v1/balance/blockchain", &b); err != nil {
+        return nil, err
+    }
+    return &b, nil
+}
+
+// Amount is a sat/msat pair.
+type Amount struct {
+    Sat  int64 `json:"sat,string"`
+    Msat int64 `json:"msat,string"`
+}
+
+// ChannelBalance is the result of channelbalance.
+type ChannelBalance struct {
+    Local             Amount `json:"local_balance"`
+    Remote            Amount `json:"remote_balance"`
+    PendingOpenLocal  Amount `json:"pending_open_local_balance"`
+    PendingOpenRemote Amount `json:"pending_open_remote_balance"`
+}
+
+func (c *Client) ChannelBalance(ctx context.Context) (*ChannelBalance, error) {
+    var b ChannelBalance
+    if err := c.get(ctx, "/v1/balance/channels", &b); err != nil {
+        return nil, err
+    }
+    return &b, nil
+}
+
+// Channel is one open channel from listchannels.
+type Channel struct {
+    Active        bool   `json:"active"`
+    RemotePubkey  string `json:"remote_pubkey"`
+    PeerAlias     string `json:"peer_alias"`
+    ChannelPoint  string `json:"channel_point"`
+    ChanID        uint64 `json:"chan_id,string"`
+    Capacity      int64  `json:"capacity,string"`
+    LocalBalance  int64  `json:"local_balance,string"`
+    RemoteBalance int64  `json:"remote_balance,string"`
+    CommitFee     int64  `json:"commit_fee,string"`
+    Private       bool   `json:"private"`
+    Initiator     bool   `json:"initiator"`
+}
+
+// ListChannels returns the open channels, with peer aliases
+// looked up from the graph.
+func (c *Client) ListChannels(ctx context.Context) ([]Channel, error) {
+    var resp struct {
+        Channels []Channel `json:"channels"`
+    }
+    if err := c.get(ctx, "/v1/channels?"+url.Values{"peer_alias_lookup": {"true"}}.Encode(), &resp); err != nil {
+        return nil, err
+    }
+    return resp.Channels, nil
+}
+
+// PendingChannel is the part common to every kind of pending
+// channel.
+type PendingChannel struct {
+    RemotePubkey  string `json:"remote_node_pub"`
+    ChannelPoint  string `json:"channel_point"`
+    Capacity      int64  `json:"capacity,string"`
+    LocalBalance  int64  `json:"local_balance,string"`
+    RemoteBalance int64  `json:"remote_balance,string"`
+    Private       bool   `json:"private"`
+}
+
+// PendingChannels is the result of pendingchannels: channels
+// waiting for their funding transaction, for their closing
+// transaction, or for a force close's timelock.
+type PendingChannels struct {
+    TotalLimboBalance int64 `json:"total_limbo_balance,string"`
+    PendingOpen       []struct {
+        Channel PendingChannel `json:"channel"`
+    } `json:"pending_open_channels"`
+    WaitingClose []struct {
+        Channel      PendingChannel `json:"channel"`
+        LimboBalance int64          `json:"limbo_balance,string"`
+    } `json:"waiting_close_channels"`
+    PendingForceClosing []struct {
+        Channel           PendingChannel `json:"channel"`
+        ClosingTxid       string         `json:"closing_txid"`
+        LimboBalance      int64          `json:"limbo_balance,string"`
+        BlocksTilMaturity int            `json:"blocks_til_maturity"`
+    } `json:"pending_force_closing_channels"`
+}
+
+func (c *Client) PendingChannels(ctx context.Context) (*PendingChannels, error) {
+    var p PendingChannels
+    if err := c.get(ctx, "/v1/channels/pending", &p); err != nil {
+        return nil, err
+    }
+    return &p, nil
+}
+
+// Address types for NewAddress.
+const (
+    AddressSegwit  = "WITNESS_PUBKEY_HASH"
+    AddressTaproot = "TAPROOT_PUBKEY"
+)
+
+// NewAddress returns a fresh on-chain address of addrType.
+func (c *Client) NewAddress(ctx context.Context, addrType string) (string, error) {
+    var resp struct {
+        Address string `json:"address"`
+    }
+    if err := c.get(ctx, "/v1/newaddress?"+url.Values{"type": {addrType}}.Encode(), &resp); err != nil {
+        return "", err
+    }
+    return resp.Address, nil
+}
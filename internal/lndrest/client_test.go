@@ -0,0 +1,172 @@
+package lndrest
+
+import (
+    "context"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/tls"
+    "crypto/x509"
+    "crypto/x509/pkix"
+    "encoding/pem"
+    "errors"
+    "math/big"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+// newTestClient serves handle as LND over TLS, with a macaroon on
+// disk whose hex form is 6d6163.
+func newTestClient(t *testing.T, handle func(r *http.Request) (int, string)) *Client {
+    t.Helper()
+    srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        status, body := handle(r)
+        w.WriteHeader(status)
+        w.Write([]byte(body))
+    }))
+    t.Cleanup(srv.Close)
+    mac := filepath.Join(t.TempDir(), "admin.macaroon")
+    if err := os.WriteFile(mac, []byte("mac"), 0600); err != nil {
+        t.Fatal(err)
+    }
+    return &Client{URL: srv.URL, MacaroonPath: mac, HTTP: srv.Client()}
+}
+
+func TestWalletBalance(t *testing.T) {
+    c := newTestClient(t, func(r *http.Request) (int, string) {
+        if r.URL.Path != "/v1/balance/blockchain" || r.Header.Get("Grpc-Metadata-macaroon") != "6d6163" {
+            t.Errorf("got %s with macaroon %q", r.URL.Path, r.Header.Get("Grpc-Metadata-macaroon"))
+        }
+        return 200, `{"total_balance": "150000", "confirmed_balance": "100000",
+            "unconfirmed_balance": "50000", "locked_balance": "0", "reserved_balance_anchor_chan": "10000"}`
+    })
+    b, err := c.WalletBalance(context.Background())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if b.Total != 150000 || b.Confirmed != 100000 || b.AnchorReserve != 10000 {
+        t.Errorf("balance = %+v", b)
+    }
+}
+
+func TestListChannels(t *testing.T) {
+    c := newTestClient(t, func(r *http.Request) (int, string) {
+        if r.URL.Query().Get("peer_alias_lookup") != "true" {
+            t.Errorf("aliases not requested: %s", r.URL)
+        }
+        return 200, `{"channels": [{"active": true, "remote_pubkey": "02ab", "peer_alias": "ACINQ",
+            "chan_id": "869059488094666752", "capacity": "1000000", "local_balance": "400000",
+            "remote_balance": "596530", "private": false}]}`
+    })
+    chans, err := c.ListChannels(context.Background())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(chans) != 1 || chans[0].PeerAlias != "ACINQ" || chans[0].ChanID != 869059488094666752 ||
+        chans[0].LocalBalance != 400000 || !chans[0].Active {
+        t.Errorf("channels = %+v", chans)
+    }
+}
+
+func TestStateNeedsNoMacaroon(t *testing.T) {
+    c := newTestClient(t, func(r *http.Request) (int, string) {
+        if r.Header.Get("Grpc-Metadata-macaroon") != "" {
+            t.Error("macaroon sent to /v1/state")
+        }
+        return 200, `{"state": "LOCKED"}`
+    })
+    c.MacaroonPath = filepath.Join(t.TempDir(), "missing.macaroon")
+    state, err := c.State(context.Background())
+    if err != nil || state != "LOCKED" {
+        t.Fatalf("State() = %q, %v", state, err)
+    }
+}
+
+func TestLNDError(t *testing.T) {
+    c := newTestClient(t, func(r *http.Request) (int, string) {
+        return 500, `{"code": 2, "message": "wallet locked, unlock it to enable full RPC access", "details": []}`
+    })
+    _, err := c.GetInfo(context.Background())
+    var lndErr *Error
+    if !errors.As(err, &lndErr) || lndErr.Code != 2 {
+        t.Fatalf("err = %v, want LND error 2", err)
+    }
+}
+
+// selfSigned returns a new certificate for 127.0.0.1, as LND
+// generates for itself.
+func selfSigned(t *testing.T) tls.Certificate {
+    key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    if err != nil {
+        t.Fatal(err)
+    }
+    tmpl := &x509.Certificate{
+        SerialNumber:          big.NewInt(1),
+        Subject:               pkix.Name{Organization: []string{"lnd autogenerated cert"}},
+        NotBefore:             time.Now().Add(-time.Hour),
+        NotAfter:              time.Now().Add(time.Hour),
+        IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+        KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+        ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+        IsCA:                  true,
+        BasicConstraintsValid: true,
+    }
+    der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestCertPinning(t *testing.T) {
+    srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte(`{"state": "RPC_ACTIVE", "identity_pubkey": "02ab"}`))
+    }))
+    t.Cleanup(srv.Close)
+    dir := t.TempDir()
+    mac := filepath.Join(dir, "admin.macaroon")
+    if err := os.WriteFile(mac, []byte("mac"), 0600); err != nil {
+        t.Fatal(err)
+    }
+    cert := filepath.Join(dir, "tls.cert")
+    c := New(cert, mac)
+    c.URL = srv.URL
+
+    // Before LND writes its cert only State may go out unchecked
+    if _, err := c.State(context.Background()); err != nil {
+        t.Fatalf("State() without a cert: %v", err)
+    }
+    if _, err := c.GetInfo(context.Background()); err == nil {
+        t.Fatal("macaroon sent without a pinned cert")
+    }
+
+    pemCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+    if err := os.WriteFile(cert, pemCert, 0644); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := c.GetInfo(context.Background()); err != nil {
+        t.Fatalf("GetInfo() with the cert pinned: %v", err)
+    }
+
+    // LND regenerates its cert: the old pin must not stick
+    srv2 := httptest.NewUnstartedServer(srv.Config.Handler)
+    srv2.TLS = &tls.Config{Certificates: []tls.Certificate{selfSigned(t)}}
+    srv2.StartTLS()
+    t.Cleanup(srv2.Close)
+    c.URL = srv2.URL
+    if _, err := c.GetInfo(context.Background()); err == nil {
+        t.Fatal("GetInfo() trusted a cert that was not pinned")
+    }
+    pemCert = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv2.Certificate().Raw})
+    if err := os.WriteFile(cert, pemCert, 0644); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := c.GetInfo(context.Background()); err != nil {
+        t.Fatalf("GetInfo() after the cert changed: %v", err)
+    }
+}
@@ -0,0 +1,81 @@
+package lndrest
+
+import (
+    "context"
+    "encoding/base64"
+    "encoding/hex"
+    "fmt"
+    "net/http"
+    "strconv"
+    "time"
+)
+
+// Invoice states as LND reports them.
+const (
+    InvoiceOpen     = "OPEN"
+    InvoiceSettled  = "SETTLED"
+    InvoiceCanceled = "CANCELED"
+    InvoiceAccepted = "ACCEPTED"
+)
+
+// Invoice is the part of LND's invoice the dashboard shows. Times
+// are unix seconds; Expiry is in seconds from CreationDate.
+type Invoice struct {
+    Memo           string `json:"memo"`
+    ValueSats      int64  `json:"value,string"`
+    PaymentRequest string `json:"payment_request"`
+    State          string `json:"state"`
+    AmtPaidSat     int64  `json:"amt_paid_sat,string"`
+    CreationDate   int64  `json:"creation_date,string"`
+    SettleDate     int64  `json:"settle_date,string"`
+    Expiry         int64  `json:"expiry,string"`
+}
+
+// ExpiresAt is when the invoice stops being payable, by LND's
+// clock.
+func (inv *Invoice) ExpiresAt() time.Time {
+    return time.Unix(inv.CreationDate+inv.Expiry, 0)
+}
+
+// AddedInvoice is a new invoice: the BOLT11 string to hand out
+// and the payment hash, in hex, to look it up by.
+type AddedInvoice struct {
+    PaymentRequest string
+    RHash          string
+}
+
+// AddInvoice creates a BOLT11 invoice. valueSats 0 lets the payer
+// choose the amount; expiry 0 takes LND's default of an hour.
+func (c *Client) AddInvoice(ctx context.Context, valueSats int64, memo string, expiry time.Duration) (*AddedInvoice, error) {
+    body := map[string]any{
+        "value": strconv.FormatInt(valueSats, 10),
+        "memo":  memo,
+    }
+    if expiry > 0 {
+        body["expiry"] = strconv.FormatInt(int64(expiry/time.Second), 10)
+    }
+    var resp struct {
+        RHash          string `json:"r_hash"`
+        PaymentRequest string `json:"payment_request"`
+    }
+    if err := c.Do(ctx, http.MethodPost, "/v1/invoices", body, &resp); err != nil {
+        return nil, err
+    }
+    hash, err := base64.StdEncoding.DecodeString(resp.RHash)
+    if err != nil {
+        return nil, fmt.Errorf("payment hash %q: %w", resp.RHash, err)
+    }
+    return &AddedInvoice{PaymentRequest: resp.PaymentRequest, RHash: hex.EncodeToString(hash)}, nil
+}
+
+// LookupInvoice returns the invoice with payment hash rHash (hex).
+func (c *Client) LookupInvoice(ctx context.Context, rHash string) (*Invoice, error) {
+    if _, err := hex.DecodeString(rHash); err != nil || len(rHash) != 64 {
+        return nil, fmt.Errorf("%q is not a payment hash", rHash)
+    }
+    var inv Invoice
+    if err := c.get(ctx, "/v1/invoice/"+rHash, &inv); err != nil {
+        return nil, err
+    }
+    return &inv, nil
+}
@@ -0,0 +1,57 @@
+package lndrest
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestAddInvoice(t *testing.T) {
+    c := newTestClient(t, func(r *http.Request) (int, string) {
+        var body map[string]any
+        if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+            t.Errorf("decode request: %v", err)
+        }
+        if r.Method != http.MethodPost || r.URL.Path != "/v1/invoices" ||
+            body["value"] != "5000" || body["memo"] != "coffee" || body["expiry"] != "1800" {
+            t.Errorf("got %s %s %v", r.Method, r.URL.Path, body)
+        }
+        return 200, `{"r_hash": "AQID", "payment_request": "lnbcrt50u1test", "add_index": "1"}`
+    })
+    inv, err := c.AddInvoice(context.Background(), 5000, "coffee", 30*time.Minute)
+    if err != nil {
+        t.Fatal(err)
+    }
+    // the payment hash is not reversed, unlike txids
+    if inv.RHash != "010203" || inv.PaymentRequest != "lnbcrt50u1test" {
+        t.Errorf("invoice = %+v", inv)
+    }
+}
+
+func TestLookupInvoice(t *testing.T) {
+    hash := strings.Repeat("ab", 32)
+    c := newTestClient(t, func(r *http.Request) (int, string) {
+        if r.URL.Path != "/v1/invoice/"+hash {
+            t.Errorf("got %s", r.URL.Path)
+        }
+        return 200, `{"memo": "coffee", "value": "5000", "state": "SETTLED", "amt_paid_sat": "5000",
+            "creation_date": "1700000000", "settle_date": "1700000042", "expiry": "3600"}`
+    })
+    inv, err := c.LookupInvoice(context.Background(), hash)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if inv.State != InvoiceSettled || inv.AmtPaidSat != 5000 || inv.SettleDate != 1700000042 {
+        t.Errorf("invoice = %+v", inv)
+    }
+    if got := inv.ExpiresAt().Unix(); got != 1700003600 {
+        t.Errorf("ExpiresAt() = %d, want 1700003600", got)
+    }
+
+    if _, err := c.LookupInvoice(context.Background(), "../state"); err == nil {
+        t.Error("bad payment hash accepted")
+    }
+}
@@ -0,0 +1,157 @@
+package lndrest
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// PayReq is a decoded BOLT11 invoice. NumSatoshis 0 means the
+// payer chooses the amount. Timestamp is unix seconds and Expiry
+// seconds from it.
+type PayReq struct {
+    Destination string `json:"destination"`
+    PaymentHash string `json:"payment_hash"`
+    NumSatoshis int64  `json:"num_satoshis,string"`
+    Timestamp   int64  `json:"timestamp,string"`
+    Expiry      int64  `json:"expiry,string"`
+    Description string `json:"description"`
+}
+
+// ExpiresAt is when the invoice stops being payable.
+func (p *PayReq) ExpiresAt() time.Time {
+    return time.Unix(p.Timestamp+p.Expiry, 0)
+}
+
+// DecodePayReq decodes a BOLT11 invoice without paying it.
+func (c *Client) DecodePayReq(ctx context.Context, payReq string) (*PayReq, error) {
+    var p PayReq
+    if err := c.get(ctx, "/v1/payreq/"+url.PathEscape(payReq), &p); err != nil {
+        return nil, err
+    }
+    return &p, nil
+}
+
+// Payment statuses as the router reports them.
+const (
+    PaymentInFlight  = "IN_FLIGHT"
+    PaymentSucceeded = "SUCCEEDED"
+    PaymentFailed    = "FAILED"
+)
+
+// failureReasons explains the router's failure reasons.
+var failureReasons = map[string]string{
+    "FAILURE_REASON_TIMEOUT":                   "timed out before a route was found",
+    "FAILURE_REASON_NO_ROUTE":                  "no route within the fee limit",
+    "FAILURE_REASON_ERROR":                     "unexpected error",
+    "FAILURE_REASON_INCORRECT_PAYMENT_DETAILS": "the recipient rejected the payment details",
+    "FAILURE_REASON_INSUFFICIENT_BALANCE":      "not enough outbound liquidity",
+}
+
+// Payment is the outcome of SendPayment.
+type Payment struct {
+    PaymentHash     string `json:"payment_hash"`
+    PaymentPreimage string `json:"payment_preimage"`
+    Status          string `json:"status"`
+    FailureReason   string `json:"failure_reason"`
+    ValueSat        int64  `json:"value_sat,string"`
+    FeeSat          int64  `json:"fee_sat,string"`
+}
+
+// SendPaymentRequest is what SendPayment needs. AmountSats is only
+// set for invoices without an amount. FeeLimitSats 0 allows only
+// routes without fees.
+type SendPaymentRequest struct {
+    PaymentRequest string
+    AmountSats     int64
+    FeeLimitSats   int64
+    Timeout        time.Duration
+}
+
+// SendPayment pays a BOLT11 invoice and waits until the payment
+// succeeds or fails. A failed payment is returned along with an
+// error saying why.
+func (c *Client) SendPayment(ctx context.Context, r SendPaymentRequest) (*Payment, error) {
+    if r.Timeout < time.Second {
+        return nil, errors.New("payment timeout must be at least a second")
+    }
+    body := map[string]any{
+        "payment_request":     r.PaymentRequest,
+        "fee_limit_sat":       strconv.FormatInt(r.FeeLimitSats, 10),
+        "timeout_seconds":     int(r.Timeout / time.Second),
+        "no_inflight_updates": true,
+    }
+    if r.AmountSats > 0 {
+        body["amt"] = strconv.FormatInt(r.AmountSats, 10)
+    }
+    // The reply is a stream of updates ending in success or
+    // failure.
+    resp, err := c.send(ctx, http.MethodPost, "/v2/router/send", body, true)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    dec := json.NewDecoder(resp.Body)
+    for {
+        var update struct {
+            Result *Payment `json:"result"`
+            Error  *Error   `json:"error"`
+        }
+        if err := dec.Decode(&update); err != nil {
+            return nil, fmt.Errorf("send payment: %w", err)
+        }
+        if update.Error != nil {
+            return nil, fmt.Errorf("send payment: %w", update.Error)
+        }
+        p := update.Result
+        if p == nil {
+            continue
+        }
+        switch p.Status {
+        case PaymentSucceeded:
+            return p, nil
+        case PaymentFailed:
+            reason, ok := failureReasons[p.FailureReason]
+            if !ok {
+                reason = strings.ToLower(strings.TrimPrefix(p.FailureReason, "FAILURE_REASON_"))
+            }
+            return p, fmt.Errorf("payment failed: %s", reason)
+        }
+    }
+}
+
+// SendCoinsRequest is what SendCoins needs. SendAll sweeps the
+// whole confirmed balance and ignores AmountSats. SatPerVByte 0
+// leaves the fee to LND.
+type SendCoinsRequest struct {
+    Address     string
+    AmountSats  int64
+    SatPerVByte int64
+    SendAll     bool
+}
+
+// SendCoins sends on-chain from LND's wallet and returns the txid.
+func (c *Client) SendCoins(ctx context.Context, r SendCoinsRequest) (string, error) {
+    body := map[string]any{"addr": r.Address}
+    if r.SendAll {
+        body["send_all"] = true
+    } else {
+        body["amount"] = strconv.FormatInt(r.AmountSats, 10)
+    }
+    if r.SatPerVByte > 0 {
+        body["sat_per_vbyte"] = strconv.FormatInt(r.SatPerVByte, 10)
+    }
+    var resp struct {
+        Txid string `json:"txid"`
+    }
+    if err := c.Do(ctx, http.MethodPost, "/v1/transactions", body, &resp); err != nil {
+        return "", err
+    }
+    return resp.Txid, nil
+}
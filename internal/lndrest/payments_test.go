@@ -0,0 +1,81 @@
+package lndrest
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestDecodePayReq(t *testing.T) {
+    c := newTestClient(t, func(r *http.Request) (int, string) {
+        if r.URL.Path != "/v1/payreq/lnbcrt50u1test" {
+            t.Errorf("got %s", r.URL.Path)
+        }
+        return 200, `{"destination": "` + testPubkey + `", "num_satoshis": "5000",
+            "timestamp": "1700000000", "expiry": "3600", "description": "coffee"}`
+    })
+    p, err := c.DecodePayReq(context.Background(), "lnbcrt50u1test")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if p.NumSatoshis != 5000 || p.Description != "coffee" || p.ExpiresAt().Unix() != 1700003600 {
+        t.Errorf("payreq = %+v", p)
+    }
+}
+
+func TestSendPayment(t *testing.T) {
+    c := newTestClient(t, func(r *http.Request) (int, string) {
+        var body map[string]any
+        if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+            t.Errorf("decode request: %v", err)
+        }
+        if r.URL.Path != "/v2/router/send" || body["fee_limit_sat"] != "50" ||
+            body["timeout_seconds"] != float64(60) || body["amt"] != nil {
+            t.Errorf("got %s %v", r.URL.Path, body)
+        }
+        return 200, `{"result": {"status": "IN_FLIGHT"}}` + "\n" +
+            `{"result": {"status": "SUCCEEDED", "value_sat": "5000", "fee_sat": "3", "payment_preimage": "ab"}}` + "\n"
+    })
+    p, err := c.SendPayment(context.Background(), SendPaymentRequest{
+        PaymentRequest: "lnbcrt50u1test", FeeLimitSats: 50, Timeout: time.Minute,
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if p.FeeSat != 3 || p.PaymentPreimage != "ab" {
+        t.Errorf("payment = %+v", p)
+    }
+
+    c = newTestClient(t, func(r *http.Request) (int, string) {
+        return 200, `{"result": {"status": "FAILED", "failure_reason": "FAILURE_REASON_NO_ROUTE"}}` + "\n"
+    })
+    _, err = c.SendPayment(context.Background(), SendPaymentRequest{
+        PaymentRequest: "lnbcrt50u1test", Timeout: time.Minute,
+    })
+    if err == nil || !strings.Contains(err.Error(), "no route") {
+        t.Errorf("err = %v, want no route", err)
+    }
+}
+
+func TestSendCoins(t *testing.T) {
+    c := newTestClient(t, func(r *http.Request) (int, string) {
+        var body map[string]any
+        if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+            t.Errorf("decode request: %v", err)
+        }
+        if r.URL.Path != "/v1/transactions" || body["send_all"] != true ||
+            body["amount"] != nil || body["sat_per_vbyte"] != "7" {
+            t.Errorf("got %s %v", r.URL.Path, body)
+        }
+        return 200, `{"txid": "abcd"}`
+    })
+    txid, err := c.SendCoins(context.Background(), SendCoinsRequest{
+        Address: "bcrt1qtest", AmountSats: 1000, SatPerVByte: 7, SendAll: true,
+    })
+    if err != nil || txid != "abcd" {
+        t.Fatalf("SendCoins() = %q, %v", txid, err)
+    }
+}
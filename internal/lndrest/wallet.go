@@ -0,0 +1,60 @@
+package lndrest
+
+import (
+    "context"
+    "encoding/base64"
+    "net/http"
+)
+
+// InitWalletRequest restores a wallet from an aezeed seed.
+// ChannelBackup is the contents of a channel.backup file, or nil;
+// with one, LND asks each channel's peer to force close so the
+// funds come back on-chain.
+type InitWalletRequest struct {
+    Password   string
+    Mnemonic   []string
+    Passphrase string
+    // RecoveryWindow is how many addresses per account LND looks
+    // ahead for funds while rescanning the chain.
+    RecoveryWindow int
+    ChannelBackup  []byte
+}
+
+// InitWallet creates LND's wallet from a seed. Like State it
+// needs no macaroon, since none exists until the wallet does.
+func (c *Client) InitWallet(ctx context.Context, r InitWalletRequest) error {
+    body := map[string]any{
+        "wallet_password":      base64.StdEncoding.EncodeToString([]byte(r.Password)),
+        "cipher_seed_mnemonic": r.Mnemonic,
+        "recovery_window":      r.RecoveryWindow,
+    }
+    if r.Passphrase != "" {
+        body["aezeed_passphrase"] = base64.StdEncoding.EncodeToString([]byte(r.Passphrase))
+    }
+    if len(r.ChannelBackup) > 0 {
+        body["channel_backups"] = map[string]any{
+            "multi_chan_backup": map[string]string{
+                "multi_chan_backup": base64.StdEncoding.EncodeToString(r.ChannelBackup),
+            },
+        }
+    }
+    return c.do(ctx, http.MethodPost, "/v1/initwallet", body, nil, false)
+}
+
+// RecoveryInfo is how far LND's rescan for a restored wallet's
+// funds has got. Progress runs from 0 to 1.
+type RecoveryInfo struct {
+    RecoveryMode     bool    `json:"recovery_mode"`
+    RecoveryFinished bool    `json:"recovery_finished"`
+    Progress         float64 `json:"progress"`
+}
+
+// RecoveryInfo reports on the rescan started by InitWallet with a
+// recovery window.
+func (c *Client) RecoveryInfo(ctx context.Context) (*RecoveryInfo, error) {
+    var info RecoveryInfo
+    if err := c.get(ctx, "/v1/getrecoveryinfo", &info); err != nil {
+        return nil, err
+    }
+    return &info, nil
+}
@@ -0,0 +1,59 @@
+package lndrest
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestInitWallet(t *testing.T) {
+    seed := strings.Fields(strings.Repeat("abandon ", 23) + "art")
+    c := newTestClient(t, func(r *http.Request) (int, string) {
+        if r.Header.Get("Grpc-Metadata-macaroon") != "" {
+            t.Error("macaroon sent to /v1/initwallet")
+        }
+        var body struct {
+            Password       string   `json:"wallet_password"`
+            Mnemonic       []string `json:"cipher_seed_mnemonic"`
+            Passphrase     string   `json:"aezeed_passphrase"`
+            RecoveryWindow int      `json:"recovery_window"`
+            ChannelBackups struct {
+                Multi struct {
+                    Backup string `json:"multi_chan_backup"`
+                } `json:"multi_chan_backup"`
+            } `json:"channel_backups"`
+        }
+        if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+            t.Errorf("decode request: %v", err)
+        }
+        // base64 of "password1", "" and "scb"
+        if r.URL.Path != "/v1/initwallet" || body.Password != "cGFzc3dvcmQx" || len(body.Mnemonic) != 24 ||
+            body.Passphrase != "" || body.RecoveryWindow != 2500 || body.ChannelBackups.Multi.Backup != "c2Ni" {
+            t.Errorf("got %s %+v", r.URL.Path, body)
+        }
+        return 200, `{"admin_macaroon": "AgE="}`
+    })
+    c.MacaroonPath = filepath.Join(t.TempDir(), "missing.macaroon")
+    err := c.InitWallet(context.Background(), InitWalletRequest{
+        Password: "password1", Mnemonic: seed, RecoveryWindow: 2500, ChannelBackup: []byte("scb"),
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestRecoveryInfo(t *testing.T) {
+    c := newTestClient(t, func(r *http.Request) (int, string) {
+        return 200, `{"recovery_mode": true, "recovery_finished": false, "progress": 0.42}`
+    })
+    info, err := c.RecoveryInfo(context.Background())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !info.RecoveryMode || info.RecoveryFinished || info.Progress != 0.42 {
+        t.Errorf("info = %+v", info)
+    }
+}
@@ -0,0 +1,152 @@
+// Package paths is the one place that knows where the node's
+// files live.
+//
+// Two layouts are in play. Node is where rlvpn itself reads and
+// writes files; it is the live system unless `--root` points it at
+// a staging directory. Live is always the live system and is used
+// for paths that end up inside generated files or commands, since
+// those are read by bitcoind, lnd and systemd on the node itself.
+package paths
+
+import (
+    "path/filepath"
+)
+
+// Layout maps the node's well-known files to locations on disk.
+// The zero Layout is the live system; with Root set, every path
+// is placed under that directory instead.
+type Layout struct {
+    Root string
+}
+
+// Live is the layout of the running node.
+var Live = Layout{}
+
+// Node is the layout rlvpn reads and writes through. main
+// replaces it when --root is given.
+var Node = Layout{}
+
+// SetRoot points Node at dir. An empty dir or "/" restores the
+// live system.
+func SetRoot(dir string) {
+    if dir == "/" {
+        dir = ""
+    }
+    Node = Layout{Root: dir}
+}
+
+// Path places an absolute path under the layout's root.
+func (l Layout) Path(p string) string {
+    if l.Root == "" {
+        return p
+    }
+    return filepath.Join(l.Root, p)
+}
+
+// ── System ───────────────────────────────────────────────
+
+func (l Layout) Bin(name string) string  { return l.Path(filepath.Join("/usr/local/bin", name)) }
+func (l Layout) Unit(name string) string { return l.Path(filepath.Join("/etc/systemd/system", name)) }
+func (l Layout) Tmp(name string) string  { return l.Path(filepath.Join("/tmp", name)) }
+
+// Release is the versioned directory an upgraded release's
+// binaries live in, such as bitcoin-29.3; the names in Bin link
+// to the active one. Release("") is the parent of them all.
+func (l Layout) Release(name string) string { return l.Path(filepath.Join("/opt/rlvpn", name)) }
+
+// AdminBashrc is the login shell profile of the admin user.
+func (l Layout) AdminBashrc() string { return l.Path("/home/ripsline/.bashrc") }
+
+// ── rlvpn ────────────────────────────────────────────────
+
+func (l Layout) RLVPNDir() string     { return l.Path("/etc/rlvpn") }
+func (l Layout) Config() string       { return l.Path("/etc/rlvpn/config.json") }
+func (l Layout) InstallState() string { return l.Path("/etc/rlvpn/install-state.json") }
+
+// VerifiedHashes records the SHA256 of every release file that
+// passed manifest verification.
+func (l Layout) VerifiedHashes() string { return l.Path("/etc/rlvpn/verified-hashes.json") }
+
+// ── Tor ──────────────────────────────────────────────────
+
+func (l Layout) TorConfig() string { return l.Path("/etc/tor/torrc") }
+
+// OnionDir is the HiddenServiceDir for the named service.
+func (l Layout) OnionDir(name string) string { return l.Path("/var/lib/tor/" + name) }
+
+func (l Layout) OnionHostname(name string) string {
+    return l.Path("/var/lib/tor/" + name + "/hostname")
+}
+
+// ── Bitcoin Core ─────────────────────────────────────────
+
+func (l Layout) BitcoinConfDir() string { return l.Path("/etc/bitcoin") }
+func (l Layout) BitcoinConf() string    { return l.Path("/etc/bitcoin/bitcoin.conf") }
+func (l Layout) BitcoinData() string    { return l.Path("/var/lib/bitcoin") }
+
+// BitcoinRPCAuthConf holds the rpcauth logins handed to wallets.
+// bitcoin.conf pulls it in with includeconf.
+func (l Layout) BitcoinRPCAuthConf() string { return l.Path("/etc/bitcoin/rpcauth.conf") }
+
+// BitcoinChainData is the datadir of one network. Non-mainnet
+// chains live in a subdirectory named after the network.
+func (l Layout) BitcoinChainData(network string) string {
+    if network == "mainnet" {
+        return l.Path("/var/lib/bitcoin")
+    }
+    return l.Path("/var/lib/bitcoin/" + network)
+}
+
+// BitcoinCookie is bitcoind's RPC cookie.
+func (l Layout) BitcoinCookie(network string) string {
+    return l.BitcoinChainData(network) + "/.cookie"
+}
+
+// ── LND ──────────────────────────────────────────────────
+
+func (l Layout) LNDConfDir() string        { return l.Path("/etc/lnd") }
+func (l Layout) LNDConf() string           { return l.Path("/etc/lnd/lnd.conf") }
+func (l Layout) LNDData() string           { return l.Path("/var/lib/lnd") }
+func (l Layout) LNDTLSCert() string        { return l.Path("/var/lib/lnd/tls.cert") }
+func (l Layout) LNDWalletPassword() string { return l.Path("/var/lib/lnd/wallet_password") }
+
+// LNDChainDir holds the wallet, macaroons and channel backup for
+// one network.
+func (l Layout) LNDChainDir(network string) string {
+    return l.Path("/var/lib/lnd/data/chain/bitcoin/" + network)
+}
+
+func (l Layout) LNDMacaroon(network string) string {
+    return l.LNDChainDir(network) + "/admin.macaroon"
+}
+
+func (l Layout) LNDChannelBackup(network string) string {
+    return l.LNDChainDir(network) + "/channel.backup"
+}
+
+func (l Layout) LNDWalletDB(network string) string {
+    return l.LNDChainDir(network) + "/wallet.db"
+}
+
+func (l Layout) LNDChannelDB(network string) string {
+    return l.Path("/var/lib/lnd/data/graph/" + network + "/channel.db")
+}
+
+// LNDUpgradeBackup holds the databases snapshotted before
+// upgrading away from version.
+func (l Layout) LNDUpgradeBackup(version string) string {
+    return l.Path("/var/lib/lnd/upgrade-backup/" + version)
+}
+
+// ── Lightning Terminal ───────────────────────────────────
+
+func (l Layout) LITConfDir() string { return l.Path("/etc/lit") }
+func (l Layout) LITConf() string    { return l.Path("/etc/lit/lit.conf") }
+func (l Layout) LITData() string    { return l.Path("/var/lib/lit") }
+
+// ── Syncthing ────────────────────────────────────────────
+
+func (l Layout) SyncthingConfDir() string   { return l.Path("/etc/syncthing") }
+func (l Layout) SyncthingConfig() string    { return l.Path("/etc/syncthing/config.xml") }
+func (l Layout) SyncthingData() string      { return l.Path("/var/lib/syncthing") }
+func (l Layout) SyncthingBackupDir() string { return l.Path("/var/lib/syncthing/lnd-backup") }
@@ -0,0 +1,155 @@
+package system
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "os"
+    "time"
+)
+
+// DownloadOptions controls a single Download.
+type DownloadOptions struct {
+    // Proxy is a SOCKS5 address such as 127.0.0.1:9050. Names
+    // are resolved by the proxy, so nothing leaks to local DNS.
+    Proxy string
+    // Timeout bounds each attempt. Zero means no limit.
+    Timeout time.Duration
+    // Retries is how many more attempts follow a failed one.
+    Retries int
+    // Progress, if set, is called as bytes arrive. total is -1
+    // when the server does not send a length.
+    Progress func(done, total int64)
+}
+
+// DownloadError is returned when the server answers with a status
+// that retrying will not fix.
+type DownloadError struct {
+    URL    string
+    Status string
+}
+
+func (e *DownloadError) Error() string {
+    return fmt.Sprintf("download %s: %s", e.URL, e.Status)
+}
+
+// partSuffix marks a download still in progress. A later attempt,
+// in this run or the next, continues from the end of it.
+const partSuffix = ".part"
+
+// retryDelay is the wait before the first retry; each later retry
+// waits one more multiple of it.
+var retryDelay = 2 * time.Second
+
+// Download fetches rawURL into dest. Bytes are written to
+// dest.part and renamed into place once complete; an existing
+// .part file is resumed with a Range request.
+func (Host) Download(ctx context.Context, rawURL, dest string, opts DownloadOptions) error {
+    client := &http.Client{}
+    if opts.Proxy != "" {
+        client.Transport = &http.Transport{
+            Proxy: http.ProxyURL(&url.URL{Scheme: "socks5", Host: opts.Proxy}),
+        }
+    }
+    var err error
+    for attempt := 0; attempt <= opts.Retries; attempt++ {
+        if attempt > 0 {
+            select {
+            case <-ctx.Done():
+                return ctx.Err()
+            case <-time.After(time.Duration(attempt) * retryDelay):
+            }
+        }
+        err = downloadOnce(ctx, client, rawURL, dest, opts)
+        var de *DownloadError
+        if err == nil || errors.As(err, &de) || ctx.Err() != nil {
+            break
+        }
+    }
+    if err != nil {
+        return err
+    }
+    return os.Rename(dest+partSuffix, dest)
+}
+
+func downloadOnce(ctx context.Context, client *http.Client, rawURL, dest string, opts DownloadOptions) error {
+    if opts.Timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+        defer cancel()
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+    if err != nil {
+        return err
+    }
+    part := dest + partSuffix
+    var offset int64
+    if info, err := os.Stat(part); err == nil && info.Size() > 0 {
+        offset = info.Size()
+        req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+    }
+
+    resp, err := client.Do(req)
+    if err != nil {
+        return fmt.Errorf("download %s: %w", rawURL, err)
+    }
+    defer resp.Body.Close()
+
+    flags := os.O_WRONLY | os.O_CREATE
+    switch {
+    case resp.StatusCode == http.StatusPartialContent && offset > 0:
+        flags |= os.O_APPEND
+    case resp.StatusCode == http.StatusOK:
+        // Server ignored the Range header; start over
+        offset = 0
+        flags |= os.O_TRUNC
+    case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+        // The partial file is no use to this server; drop it and
+        // let the next attempt fetch the whole file
+        os.Remove(part)
+        return fmt.Errorf("download %s: %s", rawURL, resp.Status)
+    case resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout ||
+        resp.StatusCode == http.StatusTooManyRequests:
+        return fmt.Errorf("download %s: %s", rawURL, resp.Status)
+    default:
+        return &DownloadError{URL: rawURL, Status: resp.Status}
+    }
+
+    f, err := os.OpenFile(part, flags, 0644)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+
+    total := int64(-1)
+    if resp.ContentLength >= 0 {
+        total = offset + resp.ContentLength
+    }
+    var src io.Reader = resp.Body
+    if opts.Progress != nil {
+        opts.Progress(offset, total)
+        src = &progressReader{r: resp.Body, done: offset, total: total, report: opts.Progress}
+    }
+    if _, err := io.Copy(f, src); err != nil {
+        return fmt.Errorf("download %s: %w", rawURL, err)
+    }
+    return f.Close()
+}
+
+type progressReader struct {
+    r           io.Reader
+    done, total int64
+    report      func(done, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+    n, err := p.r.Read(b)
+    if n > 0 {
+        p.done += int64(n)
+        p.report(p.done, p.total)
+    }
+    return n, err
+}
@@ -0,0 +1,106 @@
+package system
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestDownloadResumesPartialFile(t *testing.T) {
+    body := bytes.Repeat([]byte("0123456789"), 1000)
+    var ranges []string
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        ranges = append(ranges, r.Header.Get("Range"))
+        http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(body))
+    }))
+    defer srv.Close()
+
+    dest := filepath.Join(t.TempDir(), "file")
+    if err := os.WriteFile(dest+partSuffix, body[:4000], 0644); err != nil {
+        t.Fatal(err)
+    }
+    var last, total int64
+    opts := DownloadOptions{Progress: func(d, t int64) { last, total = d, t }}
+    if err := (Host{}).Download(context.Background(), srv.URL, dest, opts); err != nil {
+        t.Fatal(err)
+    }
+
+    got, err := os.ReadFile(dest)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !bytes.Equal(got, body) {
+        t.Errorf("content mismatch: got %d bytes, want %d", len(got), len(body))
+    }
+    if len(ranges) != 1 || ranges[0] != "bytes=4000-" {
+        t.Errorf("Range headers = %q, want [bytes=4000-]", ranges)
+    }
+    if last != int64(len(body)) || total != int64(len(body)) {
+        t.Errorf("progress = %d/%d, want %d/%d", last, total, len(body), len(body))
+    }
+    if _, err := os.Stat(dest + partSuffix); !os.IsNotExist(err) {
+        t.Errorf("%s left behind", dest+partSuffix)
+    }
+}
+
+func TestDownloadRetriesServerErrors(t *testing.T) {
+    body := []byte("release")
+    calls := 0
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        calls++
+        if calls == 1 {
+            // Cut the body short so the retry has something to resume
+            w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+            w.Write(body[:3])
+            return
+        }
+        http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(body))
+    }))
+    defer srv.Close()
+
+    defer func(d time.Duration) { retryDelay = d }(retryDelay)
+    retryDelay = time.Millisecond
+
+    dest := filepath.Join(t.TempDir(), "file")
+    opts := DownloadOptions{Retries: 1}
+    if err := (Host{}).Download(context.Background(), srv.URL, dest, opts); err != nil {
+        t.Fatal(err)
+    }
+    got, _ := os.ReadFile(dest)
+    if string(got) != string(body) {
+        t.Errorf("got %q, want %q", got, body)
+    }
+    if calls != 2 {
+        t.Errorf("server called %d times, want 2", calls)
+    }
+}
+
+func TestDownloadDoesNotRetryNotFound(t *testing.T) {
+    calls := 0
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        calls++
+        http.NotFound(w, r)
+    }))
+    defer srv.Close()
+
+    dest := filepath.Join(t.TempDir(), "file")
+    err := (Host{}).Download(context.Background(), srv.URL, dest, DownloadOptions{Retries: 3})
+    var de *DownloadError
+    if !errors.As(err, &de) {
+        t.Fatalf("err = %v, want *DownloadError", err)
+    }
+    if !strings.Contains(de.Status, "404") {
+        t.Errorf("status = %q", de.Status)
+    }
+    if calls != 1 {
+        t.Errorf("server called %d times, want 1", calls)
+    }
+}
@@ -0,0 +1,160 @@
+package system
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "io/fs"
+    "os"
+    "strings"
+    "testing/fstest"
+)
+
+// Recorder is a Runner for tests. Commands succeed with no output
+// unless a response was registered with Respond, and file
+// operations act on the in-memory Files. Every command and write
+// is appended to Calls in order.
+type Recorder struct {
+    Files     fstest.MapFS
+    Calls     []string
+    responses []response
+}
+
+type response struct {
+    prefix string
+    out    []byte
+    err    error
+}
+
+func NewRecorder() *Recorder {
+    return &Recorder{Files: fstest.MapFS{}}
+}
+
+// Respond makes commands whose command line starts with prefix
+// return out and err. Later registrations take precedence.
+func (r *Recorder) Respond(prefix, out string, err error) {
+    r.responses = append(r.responses, response{prefix, []byte(out), err})
+}
+
+// AddFile seeds a file the code under test will read.
+func (r *Recorder) AddFile(path, data string) {
+    r.Files[key(path)] = &fstest.MapFile{Data: []byte(data), Mode: 0644}
+}
+
+// Commands returns only the commands from Calls.
+func (r *Recorder) Commands() []string {
+    var cmds []string
+    for _, c := range r.Calls {
+        if strings.HasPrefix(c, "$ ") {
+            cmds = append(cmds, strings.TrimPrefix(c, "$ "))
+        }
+    }
+    return cmds
+}
+
+func (r *Recorder) command(line string) ([]byte, error) {
+    r.Calls = append(r.Calls, "$ "+line)
+    for i := len(r.responses) - 1; i >= 0; i-- {
+        if strings.HasPrefix(line, r.responses[i].prefix) {
+            return r.responses[i].out, r.responses[i].err
+        }
+    }
+    return nil, nil
+}
+
+func (r *Recorder) Run(name string, args ...string) ([]byte, error) {
+    return r.command(CommandLine(name, args...))
+}
+
+func (r *Recorder) RunIn(dir, name string, args ...string) ([]byte, error) {
+    return r.command("cd " + dir + " && " + CommandLine(name, args...))
+}
+
+func (r *Recorder) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+    return r.command(CommandLine(name, args...))
+}
+
+// LookPath reports every tool as installed.
+func (r *Recorder) LookPath(file string) (string, error) {
+    return "/usr/bin/" + file, nil
+}
+
+// Download records the fetch without creating dest; seed the file
+// with AddFile if the code under test reads it back.
+func (r *Recorder) Download(ctx context.Context, url, dest string, opts DownloadOptions) error {
+    line := "download " + url + " " + dest
+    if opts.Proxy != "" {
+        line += " via " + opts.Proxy
+    }
+    r.Calls = append(r.Calls, line)
+    return nil
+}
+
+func (r *Recorder) ReadFile(path string) ([]byte, error) {
+    return fs.ReadFile(r.Files, key(path))
+}
+
+func (r *Recorder) Open(path string) (io.ReadCloser, error) {
+    return r.Files.Open(key(path))
+}
+
+func (r *Recorder) ReadDir(path string) ([]os.DirEntry, error) {
+    return fs.ReadDir(r.Files, key(path))
+}
+
+func (r *Recorder) Stat(path string) (os.FileInfo, error) {
+    return fs.Stat(r.Files, key(path))
+}
+
+func (r *Recorder) WriteFile(path string, data []byte, perm os.FileMode) error {
+    r.Calls = append(r.Calls, fmt.Sprintf("write %s %04o", path, perm))
+    r.Files[key(path)] = &fstest.MapFile{Data: append([]byte(nil), data...), Mode: perm}
+    return nil
+}
+
+func (r *Recorder) AppendFile(path string, data []byte, perm os.FileMode) error {
+    r.Calls = append(r.Calls, "append "+path)
+    f, ok := r.Files[key(path)]
+    if !ok {
+        f = &fstest.MapFile{Mode: perm}
+        r.Files[key(path)] = f
+    }
+    f.Data = append(f.Data, data...)
+    return nil
+}
+
+func (r *Recorder) MkdirAll(path string, perm os.FileMode) error {
+    r.Calls = append(r.Calls, fmt.Sprintf("mkdir %s %04o", path, perm))
+    r.Files[key(path)] = &fstest.MapFile{Mode: fs.ModeDir | perm}
+    return nil
+}
+
+func (r *Recorder) Chmod(path string, perm os.FileMode) error {
+    r.Calls = append(r.Calls, fmt.Sprintf("chmod %s %04o", path, perm))
+    if f, ok := r.Files[key(path)]; ok {
+        f.Mode = f.Mode&fs.ModeType | perm
+    }
+    return nil
+}
+
+func (r *Recorder) Remove(path string) error {
+    r.Calls = append(r.Calls, "rm "+path)
+    delete(r.Files, key(path))
+    return nil
+}
+
+func (r *Recorder) RemoveAll(path string) error {
+    r.Calls = append(r.Calls, "rm -r "+path)
+    prefix := key(path) + "/"
+    for name := range r.Files {
+        if name == key(path) || strings.HasPrefix(name, prefix) {
+            delete(r.Files, name)
+        }
+    }
+    return nil
+}
+
+// key maps an absolute path to its fstest.MapFS name.
+func key(path string) string {
+    return strings.Trim(path, "/")
+}
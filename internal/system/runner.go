@@ -0,0 +1,124 @@
+// Package system wraps the commands and file operations the
+// installer and dashboard perform on the host. Code that goes
+// through a Runner can be pointed at a Recorder in tests, or at a
+// printer for `rlvpn install --plan`, without needing root.
+//
+// Interactive commands that take over the terminal (lncli create,
+// apt-get upgrade, journalctl) are not routed through a Runner.
+package system
+
+import (
+    "context"
+    "io"
+    "os"
+    "os/exec"
+    "strings"
+)
+
+// Runner runs commands and touches files on behalf of the caller.
+type Runner interface {
+    // Run executes a command and returns its combined output.
+    Run(name string, args ...string) ([]byte, error)
+    // RunIn is Run with the working directory set to dir.
+    RunIn(dir, name string, args ...string) ([]byte, error)
+    // Output executes a command and returns its stdout only. The
+    // command is killed when ctx is done.
+    Output(ctx context.Context, name string, args ...string) ([]byte, error)
+    LookPath(file string) (string, error)
+    // Download fetches url into dest.
+    Download(ctx context.Context, url, dest string, opts DownloadOptions) error
+
+    ReadFile(path string) ([]byte, error)
+    // Open is for files too large to read whole, such as release
+    // tarballs.
+    Open(path string) (io.ReadCloser, error)
+    ReadDir(path string) ([]os.DirEntry, error)
+    Stat(path string) (os.FileInfo, error)
+
+    WriteFile(path string, data []byte, perm os.FileMode) error
+    AppendFile(path string, data []byte, perm os.FileMode) error
+    MkdirAll(path string, perm os.FileMode) error
+    Chmod(path string, perm os.FileMode) error
+    Remove(path string) error
+    RemoveAll(path string) error
+}
+
+// Host applies every action to the running system.
+type Host struct{}
+
+func (Host) Run(name string, args ...string) ([]byte, error) {
+    return exec.Command(name, args...).CombinedOutput()
+}
+
+func (Host) RunIn(dir, name string, args ...string) ([]byte, error) {
+    cmd := exec.Command(name, args...)
+    cmd.Dir = dir
+    return cmd.CombinedOutput()
+}
+
+func (Host) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+    return exec.CommandContext(ctx, name, args...).Output()
+}
+
+func (Host) LookPath(file string) (string, error) {
+    return exec.LookPath(file)
+}
+
+func (Host) ReadFile(path string) ([]byte, error) {
+    return os.ReadFile(path)
+}
+
+func (Host) Open(path string) (io.ReadCloser, error) {
+    return os.Open(path)
+}
+
+func (Host) ReadDir(path string) ([]os.DirEntry, error) {
+    return os.ReadDir(path)
+}
+
+func (Host) Stat(path string) (os.FileInfo, error) {
+    return os.Stat(path)
+}
+
+func (Host) WriteFile(path string, data []byte, perm os.FileMode) error {
+    return os.WriteFile(path, data, perm)
+}
+
+func (Host) AppendFile(path string, data []byte, perm os.FileMode) error {
+    f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, perm)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+    _, err = f.Write(data)
+    return err
+}
+
+func (Host) MkdirAll(path string, perm os.FileMode) error {
+    return os.MkdirAll(path, perm)
+}
+
+func (Host) Chmod(path string, perm os.FileMode) error {
+    return os.Chmod(path, perm)
+}
+
+func (Host) Remove(path string) error {
+    return os.Remove(path)
+}
+
+func (Host) RemoveAll(path string) error {
+    return os.RemoveAll(path)
+}
+
+// CommandLine renders a command for display, quoting arguments
+// that would otherwise be ambiguous when read back.
+func CommandLine(name string, args ...string) string {
+    parts := []string{name}
+    for _, a := range args {
+        if a == "" || strings.ContainsAny(a, " \t\"'$*?;&|<>") {
+            a = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+        }
+        parts = append(parts, a)
+    }
+    return strings.Join(parts, " ")
+}
@@ -0,0 +1,404 @@
+package welcome
+
+import (
+    "bufio"
+    "context"
+    "fmt"
+    "math"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    tea "github.com/charmbracelet/bubbletea"
+    "github.com/charmbracelet/lipgloss"
+
+    "github.com/ripsline/virtual-private-node/internal/config"
+    "github.com/ripsline/virtual-private-node/internal/lndrest"
+)
+
+// The channel list is a subview of the Lightning detail screen.
+// Opening and closing need typed input, so they run on the plain
+// terminal like the other shell actions and return to the list.
+
+const (
+    // minChannelSats is LND's default minchansize.
+    minChannelSats = 20000
+    // channelCallTimeout bounds opening and closing, which wait on
+    // the peer.
+    channelCallTimeout = 2 * time.Minute
+    // channelRowsShown is how many channels fit the screen.
+    channelRowsShown = 10
+)
+
+// channelRow is one open or pending channel. state is "active",
+// "inactive", "opening", "closing" or "force closing".
+type channelRow struct {
+    state    string
+    alias    string
+    pubkey   string
+    point    string
+    capacity int64
+    local    int64
+    remote   int64
+    private  bool
+    // note says when a force-closed channel's funds come back.
+    note string
+}
+
+// closable reports whether the channel is open, as opposed to
+// still opening or already closing.
+func (r channelRow) closable() bool {
+    return r.state == "active" || r.state == "inactive"
+}
+
+// channelsMsg carries a fresh channel list into the model, with
+// any aliases looked up for it.
+type channelsMsg struct {
+    rows    []channelRow
+    aliases map[string]string
+    err     error
+}
+
+// fetchChannels lists the channels. aliases is the model's cache,
+// which is only read here: the model replaces it rather than
+// adding to it.
+func fetchChannels(cfg *config.AppConfig, aliases map[string]string) tea.Cmd {
+    return func() tea.Msg {
+        rows, found, err := listChannelRows(cfg, aliases)
+        return channelsMsg{rows: rows, aliases: found, err: err}
+    }
+}
+
+// listChannelRows returns the open channels followed by pending
+// ones. Pending channels carry no alias, so it is looked up in the
+// graph, once per peer: known holds the aliases found before, and
+// new ones are returned. Peers that never announced themselves
+// have none.
+func listChannelRows(cfg *config.AppConfig, known map[string]string) ([]channelRow, map[string]string, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+    lnd := lndClient(cfg)
+    open, err := lnd.ListChannels(ctx)
+    if err != nil {
+        return nil, nil, err
+    }
+    pending, err := lnd.PendingChannels(ctx)
+    if err != nil {
+        return nil, nil, err
+    }
+    found := make(map[string]string)
+    var rows []channelRow
+    for _, c := range open {
+        state := "inactive"
+        if c.Active {
+            state = "active"
+        }
+        rows = append(rows, channelRow{
+            state: state, alias: c.PeerAlias, pubkey: c.RemotePubkey, point: c.ChannelPoint,
+            capacity: c.Capacity, local: c.LocalBalance, remote: c.RemoteBalance, private: c.Private,
+        })
+        // saves a lookup once the channel starts closing
+        if _, ok := known[c.RemotePubkey]; !ok && c.PeerAlias != "" {
+            found[c.RemotePubkey] = c.PeerAlias
+        }
+    }
+    alias := func(pubkey string) string {
+        if a, ok := known[pubkey]; ok {
+            return a
+        }
+        if a, ok := found[pubkey]; ok {
+            return a
+        }
+        ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+        defer cancel()
+        a, err := lnd.NodeAlias(ctx, pubkey)
+        if err == nil {
+            found[pubkey] = a
+        }
+        return a
+    }
+    add := func(state string, c lndrest.PendingChannel, note string) {
+        alias := alias(c.RemotePubkey)
+        rows = append(rows, channelRow{
+            state: state, alias: alias, pubkey: c.RemotePubkey, point: c.ChannelPoint,
+            capacity: c.Capacity, local: c.LocalBalance, remote: c.RemoteBalance, private: c.Private,
+            note: note,
+        })
+    }
+    for _, p := range pending.PendingOpen {
+        add("opening", p.Channel, "")
+    }
+    for _, p := range pending.WaitingClose {
+        add("closing", p.Channel, "")
+    }
+    for _, p := range pending.PendingForceClosing {
+        note := ""
+        if p.BlocksTilMaturity > 0 {
+            note = fmt.Sprintf("funds return in %d blocks", p.BlocksTilMaturity)
+        }
+        add("force closing", p.Channel, note)
+    }
+    return rows, found, nil
+}
+
+// fmtSats formats n with thousands separators.
+func fmtSats(n int64) string {
+    s := strconv.FormatInt(n, 10)
+    neg := strings.HasPrefix(s, "-")
+    s = strings.TrimPrefix(s, "-")
+    for i := len(s) - 3; i > 0; i -= 3 {
+        s = s[:i] + "," + s[i:]
+    }
+    if neg {
+        s = "-" + s
+    }
+    return s
+}
+
+func (m Model) viewChannels() string {
+    bw := min(m.width-4, wContentWidth)
+    var lines []string
+    lines = append(lines, wLightningStyle.Render("⚡ Channels"))
+    lines = append(lines, "")
+
+    switch {
+    case m.chanRows == nil && m.chanErr == "":
+        lines = append(lines, wDimStyle.Render("Loading..."))
+    case m.chanErr != "":
+        lines = append(lines, wWarnStyle.Render(m.chanErr))
+    case len(m.chanRows) == 0:
+        lines = append(lines, wDimStyle.Render("No channels yet."))
+    default:
+        lines = append(lines, wLabelStyle.Render(fmt.Sprintf("    %-14s %11s %11s %11s  %s",
+            "Peer", "Capacity", "Local", "Remote", "State")))
+        start := max(0, m.chanCursor-channelRowsShown+1)
+        for i := start; i < len(m.chanRows) && i < start+channelRowsShown; i++ {
+            r := m.chanRows[i]
+            dot := wGreenDotStyle.Render("●")
+            switch r.state {
+            case "inactive", "force closing":
+                dot = wRedDotStyle.Render("●")
+            case "opening", "closing":
+                dot = wDimStyle.Render("◌")
+            }
+            alias := r.alias
+            if alias == "" {
+                alias = r.pubkey[:min(12, len(r.pubkey))]
+            }
+            if len([]rune(alias)) > 14 {
+                alias = string([]rune(alias)[:13]) + "…"
+            }
+            state := r.state
+            if r.private {
+                state += ", private"
+            }
+            row := fmt.Sprintf("%-14s %11s %11s %11s  %s", alias,
+                fmtSats(r.capacity), fmtSats(r.local), fmtSats(r.remote), state)
+            cursor := "  "
+            style := wValueStyle
+            if i == m.chanCursor {
+                cursor = wActionStyle.Render("▸ ")
+                style = wActionStyle
+            }
+            lines = append(lines, cursor+dot+" "+style.Render(row))
+        }
+        sel := m.chanRows[m.chanCursor]
+        lines = append(lines, "")
+        lines = append(lines, "  "+wLabelStyle.Render("Peer: ")+wMonoStyle.Render(sel.pubkey))
+        lines = append(lines, "  "+wLabelStyle.Render("Point: ")+wMonoStyle.Render(sel.point))
+        if sel.note != "" {
+            lines = append(lines, "  "+wDimStyle.Render(sel.note))
+        }
+    }
+    lines = append(lines, "")
+    actions := "[o] open channel"
+    if m.chanCursor < len(m.chanRows) && m.chanRows[m.chanCursor].closable() {
+        actions += "    [x] close"
+    }
+    lines = append(lines, wActionStyle.Render(actions))
+
+    box := wOuterBox.Width(bw).Padding(1, 2).Render(strings.Join(lines, "\n"))
+    title := wTitleStyle.Width(bw).Align(lipgloss.Center).Render(" ⚡ Channels ")
+    footer := wFooterStyle.Render("  ↑↓ select • o open • x close • backspace back • q quit  ")
+    full := lipgloss.JoinVertical(lipgloss.Center, "", title, "", box, "", footer)
+    return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, full)
+}
+
+// ── Open / close prompts ─────────────────────────────────
+
+// promptHeader clears the terminal for a plain-terminal prompt.
+func promptHeader(title string) {
+    fmt.Print("\033[2J\033[H")
+    fmt.Println("\n  ═══════════════════════════════════════════")
+    fmt.Println("    " + title)
+    fmt.Print("  ═══════════════════════════════════════════\n\n")
+}
+
+func promptLine(in *bufio.Reader, label string) string {
+    fmt.Print(label)
+    line, _ := in.ReadString('\n')
+    return strings.TrimSpace(line)
+}
+
+// parseSats accepts a whole number of sats, allowing "," and "_"
+// as separators.
+func parseSats(s string) (int64, error) {
+    n, err := strconv.ParseInt(strings.NewReplacer(",", "", "_", "").Replace(s), 10, 64)
+    if err != nil || n <= 0 {
+        return 0, fmt.Errorf("%q is not an amount in sats", s)
+    }
+    return n, nil
+}
+
+// promptFeeRate asks for a fee rate, offering bitcoind's 6-block
+// estimate. 0 leaves the choice to LND.
+func promptFeeRate(in *bufio.Reader, cfg *config.AppConfig) (int64, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    var suggested int64
+    if rpc, err := bitcoinRPC(cfg); err == nil {
+        if est, err := rpc.EstimateSmartFee(ctx, 6, ""); err == nil {
+            suggested = int64(math.Ceil(est.SatPerVByte()))
+        }
+    }
+    def := "LND's estimate"
+    if suggested > 0 {
+        def = strconv.FormatInt(suggested, 10)
+    }
+    line := promptLine(in, fmt.Sprintf("  Fee rate in sat/vB [%s]: ", def))
+    if line == "" {
+        return suggested, nil
+    }
+    return parseFeeRate(line)
+}
+
+// holdResult prints the outcome and waits for Enter.
+func holdResult(in *bufio.Reader, action string, err error) {
+    if err != nil {
+        fmt.Printf("\n  %s failed: %v\n", action, err)
+    }
+    fmt.Print("\n  Press Enter to return...")
+    in.ReadString('\n')
+}
+
+// runChannelOpen asks for a peer, amount and fee rate, then
+// connects to the peer and funds the channel from the on-chain
+// wallet.
+func runChannelOpen(cfg *config.AppConfig) {
+    promptHeader("Open Channel")
+    in := bufio.NewReader(os.Stdin)
+    lnd := lndClient(cfg)
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    if bal, err := lnd.WalletBalance(ctx); err == nil {
+        fmt.Printf("  On-chain: %s sats confirmed\n\n", fmtSats(bal.Confirmed))
+    }
+    cancel()
+
+    node := promptLine(in, "  Node (pubkey@host:port), empty to cancel: ")
+    if node == "" {
+        return
+    }
+    pubkey, host, ok := strings.Cut(node, "@")
+    if !ok || pubkey == "" || host == "" {
+        holdResult(in, "Open", fmt.Errorf("expected pubkey@host:port, got %q", node))
+        return
+    }
+    amount, err := parseSats(promptLine(in, "  Amount in sats: "))
+    if err != nil {
+        holdResult(in, "Open", err)
+        return
+    }
+    if amount < minChannelSats {
+        holdResult(in, "Open", fmt.Errorf("channels must be at least %s sats", fmtSats(minChannelSats)))
+        return
+    }
+    fee, err := promptFeeRate(in, cfg)
+    if err != nil {
+        holdResult(in, "Open", err)
+        return
+    }
+    private := strings.ToLower(promptLine(in, "  Private (unannounced) channel? [y/N]: ")) == "y"
+
+    kind := "public"
+    if private {
+        kind = "private"
+    }
+    rate := "LND's fee estimate"
+    if fee > 0 {
+        rate = fmt.Sprintf("%d sat/vB", fee)
+    }
+    fmt.Printf("\n  Open a %s sat %s channel to\n  %s\n  at %s?\n", fmtSats(amount), kind, node, rate)
+    if strings.ToLower(promptLine(in, "  [y/N]: ")) != "y" {
+        return
+    }
+
+    ctx, cancel = context.WithTimeout(context.Background(), channelCallTimeout)
+    defer cancel()
+    fmt.Println("\n  Connecting to peer...")
+    if err := lnd.ConnectPeer(ctx, pubkey, host); err != nil {
+        holdResult(in, "Open", err)
+        return
+    }
+    fmt.Println("  Opening channel...")
+    txid, err := lnd.OpenChannel(ctx, lndrest.OpenChannelRequest{
+        Pubkey: pubkey, AmountSats: amount, SatPerVByte: fee, Private: private,
+    })
+    if err != nil {
+        holdResult(in, "Open", err)
+        return
+    }
+    fmt.Printf("\n  ✅ Funding transaction: %s\n", txid)
+    fmt.Println("  The channel is usable after 3 confirmations.")
+    holdResult(in, "Open", nil)
+}
+
+// runChannelClose closes r after the user types which kind of
+// close they want.
+func runChannelClose(cfg *config.AppConfig, r channelRow) {
+    promptHeader("Close Channel")
+    in := bufio.NewReader(os.Stdin)
+    alias := r.alias
+    if alias == "" {
+        alias = "(no alias)"
+    }
+    fmt.Printf("  Peer:     %s\n            %s\n", alias, r.pubkey)
+    fmt.Printf("  Channel:  %s\n", r.point)
+    fmt.Printf("  Capacity: %s sats, %s sats yours\n\n", fmtSats(r.capacity), fmtSats(r.local))
+    fmt.Println("  A cooperative close needs the peer online and pays out")
+    fmt.Println("  once the closing transaction confirms.")
+    fmt.Println("  A force close works without the peer, but your funds stay")
+    fmt.Println("  locked until the channel's timelock expires (up to 2 weeks).")
+    if r.state != "active" {
+        fmt.Println("\n  ⚠️ The peer is offline, so a cooperative close will fail.")
+    }
+
+    answer := promptLine(in, "\n  Type 'close' for a cooperative close, 'force' for a force close: ")
+    var force bool
+    var fee int64
+    switch answer {
+    case "close":
+        var err error
+        if fee, err = promptFeeRate(in, cfg); err != nil {
+            holdResult(in, "Close", err)
+            return
+        }
+    case "force":
+        force = true
+    default:
+        fmt.Println("\n  Cancelled.")
+        holdResult(in, "Close", nil)
+        return
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), channelCallTimeout)
+    defer cancel()
+    fmt.Println("\n  Closing channel...")
+    txid, err := lndClient(cfg).CloseChannel(ctx, r.point, force, fee)
+    if err != nil {
+        holdResult(in, "Close", err)
+        return
+    }
+    fmt.Printf("\n  ✅ Closing transaction: %s\n", txid)
+    holdResult(in, "Close", nil)
+}
@@ -0,0 +1,220 @@
+package welcome
+
+import (
+    "bufio"
+    "context"
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    tea "github.com/charmbracelet/bubbletea"
+    "github.com/charmbracelet/lipgloss"
+
+    "github.com/ripsline/virtual-private-node/internal/config"
+    "github.com/ripsline/virtual-private-node/internal/lndrest"
+)
+
+// The receive screen is a subview of the Lightning detail screen.
+// Addresses come straight from LND; invoices need an amount and
+// memo typed in, so they are created on the plain terminal and
+// the screen reopens showing the new invoice.
+
+const (
+    // defaultInvoiceExpiry matches LND's default.
+    defaultInvoiceExpiry = time.Hour
+    // receiveDetailWidth is the column beside the QR code.
+    receiveDetailWidth = 44
+)
+
+// receiveTarget is the invoice or address on the receive screen.
+// kind is "invoice", "taproot" or "segwit". The invoice fields are
+// refreshed from LND while it waits to be paid.
+type receiveTarget struct {
+    kind    string
+    text    string
+    qr      string
+    rHash   string
+    amount  int64
+    memo    string
+    expires time.Time
+    state   string
+    paid    int64
+}
+
+// newReceiveTarget renders the QR code once. Upper case fits the
+// QR code's alphanumeric mode, which makes for a smaller code;
+// bech32 and BOLT11 are both case-insensitive.
+func newReceiveTarget(kind, text string) *receiveTarget {
+    scheme := "BITCOIN:"
+    if kind == "invoice" {
+        scheme = "LIGHTNING:"
+    }
+    return &receiveTarget{kind: kind, text: text, qr: renderQRCode(scheme + strings.ToUpper(text))}
+}
+
+// waiting reports whether the invoice can still be paid.
+func (t *receiveTarget) waiting() bool {
+    return t != nil && t.kind == "invoice" &&
+        (t.state == "" || t.state == lndrest.InvoiceOpen || t.state == lndrest.InvoiceAccepted) &&
+        time.Now().Before(t.expires)
+}
+
+// receiveMsg carries a new address, or an error, into the model.
+type receiveMsg struct {
+    target *receiveTarget
+    err    error
+}
+
+func fetchAddress(cfg *config.AppConfig, kind string) tea.Cmd {
+    return func() tea.Msg {
+        ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+        defer cancel()
+        addrType := lndrest.AddressSegwit
+        if kind == "taproot" {
+            addrType = lndrest.AddressTaproot
+        }
+        addr, err := lndClient(cfg).NewAddress(ctx, addrType)
+        if err != nil {
+            return receiveMsg{err: err}
+        }
+        return receiveMsg{target: newReceiveTarget(kind, addr)}
+    }
+}
+
+// invoiceMsg carries an invoice's latest state into the model.
+type invoiceMsg struct {
+    rHash string
+    inv   *lndrest.Invoice
+    err   error
+}
+
+func fetchInvoice(cfg *config.AppConfig, rHash string) tea.Cmd {
+    return func() tea.Msg {
+        ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+        defer cancel()
+        inv, err := lndClient(cfg).LookupInvoice(ctx, rHash)
+        return invoiceMsg{rHash: rHash, inv: inv, err: err}
+    }
+}
+
+// receiveStatus describes the invoice's state for the screen.
+func (t *receiveTarget) receiveStatus() string {
+    switch {
+    case t.state == lndrest.InvoiceSettled:
+        return wGoodStyle.Render(fmt.Sprintf("✅ paid %s sats", fmtSats(t.paid)))
+    case t.state == lndrest.InvoiceCanceled:
+        return wWarnStyle.Render("canceled")
+    case !time.Now().Before(t.expires):
+        return wWarnStyle.Render("expired")
+    case t.state == lndrest.InvoiceAccepted:
+        return wValueStyle.Render("payment arriving...")
+    default:
+        left := int(time.Until(t.expires).Round(time.Minute).Minutes())
+        return wValueStyle.Render("waiting for payment") +
+            wDimStyle.Render(fmt.Sprintf(" (expires in %d min)", max(left, 1)))
+    }
+}
+
+func (m Model) viewReceive() string {
+    var lines []string
+    lines = append(lines, wLightningStyle.Render("⚡ Receive"))
+    lines = append(lines, "")
+
+    t := m.recv
+    switch {
+    case m.recvErr != "":
+        lines = append(lines, wWarnStyle.Render(m.recvErr))
+        lines = append(lines, "")
+    case t == nil:
+        lines = append(lines, wDimStyle.Render("Create an invoice or a new"))
+        lines = append(lines, wDimStyle.Render("on-chain address to receive to."))
+        lines = append(lines, "")
+    case t.kind == "invoice":
+        amount := "any amount"
+        if t.amount > 0 {
+            amount = fmtSats(t.amount) + " sats"
+        }
+        lines = append(lines, wLabelStyle.Render("Invoice: ")+wValueStyle.Render(amount))
+        if t.memo != "" {
+            lines = append(lines, wLabelStyle.Render("Memo: ")+wValueStyle.Render(t.memo))
+        }
+        lines = append(lines, wLabelStyle.Render("Status: ")+t.receiveStatus())
+        lines = append(lines, "")
+    default:
+        lines = append(lines, wLabelStyle.Render("Address: ")+wValueStyle.Render(t.kind))
+        lines = append(lines, "")
+    }
+    if t != nil {
+        lines = append(lines, wMonoStyle.Width(receiveDetailWidth).Render(t.text))
+        lines = append(lines, "")
+    }
+    lines = append(lines, wActionStyle.Render("[i] invoice"))
+    lines = append(lines, wActionStyle.Render("[t] taproot address"))
+    lines = append(lines, wActionStyle.Render("[s] segwit address"))
+    details := lipgloss.NewStyle().Width(receiveDetailWidth).Render(strings.Join(lines, "\n"))
+
+    body := details
+    if t != nil && t.qr != "" {
+        body = lipgloss.JoinHorizontal(lipgloss.Center, t.qr, "    ", details)
+    }
+    var rows []string
+    if t != nil {
+        rows = append(rows, wDimStyle.Render("Zoom out: Cmd+Minus / Ctrl+Minus"))
+    }
+    rows = append(rows, body, "")
+    rows = append(rows, wFooterStyle.Render("i invoice • t taproot • s segwit • backspace back • q quit"))
+    return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center,
+        lipgloss.JoinVertical(lipgloss.Left, rows...))
+}
+
+// runInvoicePrompt asks for an amount, memo and expiry and creates
+// the invoice. It returns nil if the user cancels or LND refuses.
+func runInvoicePrompt(cfg *config.AppConfig) *receiveTarget {
+    promptHeader("New Invoice")
+    in := bufio.NewReader(os.Stdin)
+
+    line := promptLine(in, "  Amount in sats, empty for any amount, 'c' to cancel: ")
+    if strings.ToLower(line) == "c" {
+        return nil
+    }
+    var amount int64
+    if line != "" {
+        var err error
+        if amount, err = parseSats(line); err != nil {
+            holdResult(in, "Invoice", err)
+            return nil
+        }
+    }
+    memo := promptLine(in, "  Memo (shown to the payer, optional): ")
+    expiry := defaultInvoiceExpiry
+    line = promptLine(in, fmt.Sprintf("  Expiry in minutes [%d]: ", int(defaultInvoiceExpiry.Minutes())))
+    if line != "" {
+        mins, err := strconv.Atoi(line)
+        if err != nil || mins < 1 {
+            holdResult(in, "Invoice", fmt.Errorf("%q is not a number of minutes", line))
+            return nil
+        }
+        expiry = time.Duration(mins) * time.Minute
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+    lnd := lndClient(cfg)
+    added, err := lnd.AddInvoice(ctx, amount, memo, expiry)
+    if err != nil {
+        holdResult(in, "Invoice", err)
+        return nil
+    }
+    // the expiry counts from LND's creation date, not from here
+    inv, err := lnd.LookupInvoice(ctx, added.RHash)
+    if err != nil {
+        holdResult(in, "Invoice", err)
+        return nil
+    }
+    t := newReceiveTarget("invoice", added.PaymentRequest)
+    t.rHash, t.amount, t.memo = added.RHash, amount, memo
+    t.expires, t.state = inv.ExpiresAt(), inv.State
+    return t
+}
@@ -0,0 +1,82 @@
+package welcome
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    tea "github.com/charmbracelet/bubbletea"
+
+    "github.com/ripsline/virtual-private-node/internal/config"
+    "github.com/ripsline/virtual-private-node/internal/lndrest"
+)
+
+// Regtest actions on the Bitcoin card. A regtest chain only moves
+// when someone mines, so the dashboard does it for the developer.
+
+const (
+    // regtestMineBlocks confirms pending transactions, including
+    // channel opens, which LND waits 3 blocks for.
+    regtestMineBlocks = 6
+    // regtestFundBlocks is one past coinbase maturity, so the
+    // first block's 50 BTC reward is spendable straight away.
+    regtestFundBlocks = 101
+    regtestTimeout    = 60 * time.Second
+)
+
+// regtestDoneMsg reports the outcome of a mine or fund action.
+type regtestDoneMsg struct {
+    text string
+    err  error
+}
+
+// regtestAction runs fn off the UI goroutine and reports back.
+func regtestAction(fn func() (string, error)) tea.Cmd {
+    return func() tea.Msg {
+        text, err := fn()
+        return regtestDoneMsg{text: text, err: err}
+    }
+}
+
+// mineBlocks mines n blocks to an anyone-can-spend output. Nothing
+// on the node needs the rewards; the blocks are only there to
+// confirm transactions and move the chain tip.
+func mineBlocks(cfg *config.AppConfig, n int) (string, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), regtestTimeout)
+    defer cancel()
+    rpc, err := bitcoinRPC(cfg)
+    if err != nil {
+        return "", err
+    }
+    // generatetodescriptor wants the checksummed form, which
+    // bitcoind computes for us.
+    var info struct {
+        Descriptor string `json:"descriptor"`
+    }
+    if err := rpc.Call(ctx, "getdescriptorinfo", &info, "raw(51)"); err != nil {
+        return "", err
+    }
+    if err := rpc.Call(ctx, "generatetodescriptor", nil, n, info.Descriptor); err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("Mined %d blocks", n), nil
+}
+
+// fundLNDWallet mines regtestFundBlocks to a fresh LND address,
+// leaving the wallet with one mature coinbase to open channels.
+func fundLNDWallet(cfg *config.AppConfig) (string, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), regtestTimeout)
+    defer cancel()
+    addr, err := lndClient(cfg).NewAddress(ctx, lndrest.AddressSegwit)
+    if err != nil {
+        return "", fmt.Errorf("new LND address: %w", err)
+    }
+    rpc, err := bitcoinRPC(cfg)
+    if err != nil {
+        return "", err
+    }
+    if err := rpc.Call(ctx, "generatetoaddress", nil, regtestFundBlocks, addr); err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("Funded LND (%d blocks)", regtestFundBlocks), nil
+}
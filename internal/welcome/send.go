@@ -0,0 +1,287 @@
+package welcome
+
+import (
+    "bufio"
+    "context"
+    "fmt"
+    "math"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/charmbracelet/lipgloss"
+
+    "github.com/ripsline/virtual-private-node/internal/config"
+    "github.com/ripsline/virtual-private-node/internal/lndrest"
+)
+
+// The send screen is a subview of the Lightning detail screen.
+// Paying and sending need an invoice or address pasted in, so both
+// run on the plain terminal and show what will happen before
+// anything leaves the wallet.
+
+const (
+    // defaultPayTimeout is how long LND may look for a route.
+    defaultPayTimeout = 60 * time.Second
+    // minFeeLimitSats is the smallest default routing fee limit;
+    // 1% of a small payment would not pay for any route.
+    minFeeLimitSats = 10
+)
+
+// feeTargets are the confirmation targets offered for on-chain
+// sends, in blocks.
+var feeTargets = []struct {
+    label  string
+    blocks int
+}{
+    {"fast", 2},
+    {"normal", 6},
+    {"slow", 24},
+    {"economy", 144},
+}
+
+func (m Model) viewSend() string {
+    bw := min(m.width-4, wContentWidth)
+    var lines []string
+    lines = append(lines, wLightningStyle.Render("⚡ Send"))
+    lines = append(lines, "")
+    if l := m.status; l != nil && l.Lightning != nil && l.Lightning.Pubkey != "" {
+        lines = append(lines, "  "+wLabelStyle.Render("In channels: ")+
+            wValueStyle.Render(fmtSats(l.Lightning.ChannelSats)+" sats"))
+        lines = append(lines, "  "+wLabelStyle.Render("On-chain: ")+
+            wValueStyle.Render(fmtSats(l.Lightning.OnchainSats)+" sats"))
+    } else {
+        lines = append(lines, "  "+wDimStyle.Render("Loading..."))
+    }
+    lines = append(lines, "")
+    lines = append(lines, "  "+wActionStyle.Render("[p] pay invoice    [o] send on-chain"))
+    lines = append(lines, "")
+    lines = append(lines, "  "+wDimStyle.Render("Both show the details and ask before sending."))
+
+    box := wOuterBox.Width(bw).Padding(1, 2).Render(strings.Join(lines, "\n"))
+    title := wTitleStyle.Width(bw).Align(lipgloss.Center).Render(" ⚡ Send ")
+    footer := wFooterStyle.Render("  p pay invoice • o on-chain • backspace back • q quit  ")
+    full := lipgloss.JoinVertical(lipgloss.Center, "", title, "", box, "", footer)
+    return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, full)
+}
+
+// ── Pay invoice ──────────────────────────────────────────
+
+// runPayInvoice decodes a pasted invoice, shows what it asks for,
+// and pays it within a fee limit and timeout.
+func runPayInvoice(cfg *config.AppConfig) {
+    promptHeader("Pay Invoice")
+    in := bufio.NewReader(os.Stdin)
+    lnd := lndClient(cfg)
+
+    payReq := promptLine(in, "  Invoice, empty to cancel: ")
+    payReq = strings.TrimPrefix(strings.ToLower(payReq), "lightning:")
+    if payReq == "" {
+        return
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    p, err := lnd.DecodePayReq(ctx, payReq)
+    alias := ""
+    if err == nil {
+        alias, _ = lnd.NodeAlias(ctx, p.Destination)
+    }
+    cancel()
+    if err != nil {
+        holdResult(in, "Decode", err)
+        return
+    }
+
+    amount := "any amount"
+    if p.NumSatoshis > 0 {
+        amount = fmtSats(p.NumSatoshis) + " sats"
+    }
+    if alias == "" {
+        alias = "(no alias)"
+    }
+    fmt.Printf("\n  Amount:      %s\n", amount)
+    fmt.Printf("  Destination: %s\n               %s\n", alias, p.Destination)
+    if p.Description != "" {
+        fmt.Printf("  Description: %s\n", p.Description)
+    }
+    expires := p.ExpiresAt()
+    if !time.Now().Before(expires) {
+        holdResult(in, "Pay", fmt.Errorf("the invoice expired at %s", expires.Format("2006-01-02 15:04")))
+        return
+    }
+    fmt.Printf("  Expires:     %s (in %s)\n\n", expires.Format("2006-01-02 15:04"),
+        time.Until(expires).Round(time.Second))
+
+    sats := p.NumSatoshis
+    var amt int64
+    if sats == 0 {
+        if amt, err = parseSats(promptLine(in, "  Amount to pay in sats: ")); err != nil {
+            holdResult(in, "Pay", err)
+            return
+        }
+        sats = amt
+    }
+    feeLimit := max(sats/100, minFeeLimitSats)
+    if line := promptLine(in, fmt.Sprintf("  Fee limit in sats [%d]: ", feeLimit)); line != "" {
+        if feeLimit, err = strconv.ParseInt(line, 10, 64); err != nil || feeLimit < 0 {
+            holdResult(in, "Pay", fmt.Errorf("%q is not a fee limit", line))
+            return
+        }
+    }
+    timeout := defaultPayTimeout
+    if line := promptLine(in, fmt.Sprintf("  Timeout in seconds [%d]: ", int(defaultPayTimeout.Seconds()))); line != "" {
+        secs, err := strconv.Atoi(line)
+        if err != nil || secs < 1 {
+            holdResult(in, "Pay", fmt.Errorf("%q is not a number of seconds", line))
+            return
+        }
+        timeout = time.Duration(secs) * time.Second
+    }
+
+    fmt.Printf("\n  Pay %s sats to %s,\n  with up to %s sats in routing fees?\n",
+        fmtSats(sats), alias, fmtSats(feeLimit))
+    if strings.ToLower(promptLine(in, "  [y/N]: ")) != "y" {
+        return
+    }
+
+    // LND gives up at timeout; the margin covers its reply.
+    ctx, cancel = context.WithTimeout(context.Background(), timeout+30*time.Second)
+    defer cancel()
+    fmt.Println("\n  Paying...")
+    pay, err := lnd.SendPayment(ctx, lndrest.SendPaymentRequest{
+        PaymentRequest: payReq, AmountSats: amt, FeeLimitSats: feeLimit, Timeout: timeout,
+    })
+    if err != nil {
+        holdResult(in, "Pay", err)
+        return
+    }
+    fmt.Printf("\n  ✅ Paid %s sats, %s sats in fees\n", fmtSats(pay.ValueSat), fmtSats(pay.FeeSat))
+    fmt.Printf("  Preimage: %s\n", pay.PaymentPreimage)
+    holdResult(in, "Pay", nil)
+}
+
+// ── Send on-chain ────────────────────────────────────────
+
+// promptFeeChoice lists bitcoind's estimates for feeTargets and
+// returns the chosen rate, or a typed one. 0 leaves the choice to
+// LND, which is the default when bitcoind has no estimates yet, as
+// on a fresh regtest chain.
+func promptFeeChoice(in *bufio.Reader, cfg *config.AppConfig) (int64, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+    rpc, err := bitcoinRPC(cfg)
+    if err != nil {
+        return 0, err
+    }
+    var rates []int64
+    fmt.Println()
+    for i, t := range feeTargets {
+        est, err := rpc.EstimateSmartFee(ctx, t.blocks, "")
+        if err != nil || est.FeeRate <= 0 {
+            break
+        }
+        rate := int64(math.Ceil(est.SatPerVByte()))
+        rates = append(rates, rate)
+        fmt.Printf("  [%d] %-8s %4d sat/vB  (~%d blocks)\n", i+1, t.label, rate, t.blocks)
+    }
+    if len(rates) == 0 {
+        fmt.Println("  bitcoind has no fee estimates yet.")
+        line := promptLine(in, "  Fee rate in sat/vB [LND's estimate]: ")
+        if line == "" {
+            return 0, nil
+        }
+        return parseFeeRate(line)
+    }
+    def := min(2, len(rates))
+    line := promptLine(in, fmt.Sprintf("  Choose 1-%d, or 'c' for a custom rate [%d]: ", len(rates), def))
+    switch {
+    case line == "":
+        return rates[def-1], nil
+    case strings.ToLower(line) == "c":
+        return parseFeeRate(promptLine(in, "  Fee rate in sat/vB: "))
+    }
+    i, err := strconv.Atoi(line)
+    if err != nil || i < 1 || i > len(rates) {
+        return 0, fmt.Errorf("%q is not one of the choices", line)
+    }
+    return rates[i-1], nil
+}
+
+func parseFeeRate(s string) (int64, error) {
+    rate, err := strconv.ParseInt(s, 10, 64)
+    if err != nil || rate < 1 {
+        return 0, fmt.Errorf("%q is not a fee rate", s)
+    }
+    return rate, nil
+}
+
+// runSendOnchain asks for an address, amount and fee rate and,
+// after a confirmation screen, sends from LND's wallet.
+func runSendOnchain(cfg *config.AppConfig) {
+    promptHeader("Send On-chain")
+    in := bufio.NewReader(os.Stdin)
+    lnd := lndClient(cfg)
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    var confirmed int64
+    if bal, err := lnd.WalletBalance(ctx); err == nil {
+        confirmed = bal.Confirmed
+        fmt.Printf("  On-chain: %s sats confirmed\n\n", fmtSats(bal.Confirmed))
+    }
+    cancel()
+
+    // accept a bitcoin: URI as well as a bare address
+    addr := promptLine(in, "  Address, empty to cancel: ")
+    if len(addr) > 8 && strings.EqualFold(addr[:8], "bitcoin:") {
+        addr, _, _ = strings.Cut(addr[8:], "?")
+    }
+    if addr == "" {
+        return
+    }
+    line := promptLine(in, "  Amount in sats, or 'all' to sweep the wallet: ")
+    sendAll := strings.ToLower(line) == "all"
+    var amount int64
+    if !sendAll {
+        var err error
+        if amount, err = parseSats(line); err != nil {
+            holdResult(in, "Send", err)
+            return
+        }
+    }
+    fee, err := promptFeeChoice(in, cfg)
+    if err != nil {
+        holdResult(in, "Send", err)
+        return
+    }
+
+    promptHeader("Confirm On-chain Send")
+    fmt.Printf("  Network:  %s\n", cfg.Network)
+    fmt.Printf("  To:       %s\n", addr)
+    if sendAll {
+        fmt.Printf("  Amount:   everything, %s sats less the fee\n", fmtSats(confirmed))
+    } else {
+        fmt.Printf("  Amount:   %s sats\n", fmtSats(amount))
+    }
+    if fee > 0 {
+        fmt.Printf("  Fee rate: %d sat/vB\n", fee)
+    } else {
+        fmt.Println("  Fee rate: LND's estimate")
+    }
+    fmt.Println("\n  On-chain transactions cannot be reversed.")
+    if promptLine(in, "  Type 'send' to broadcast, anything else cancels: ") != "send" {
+        fmt.Println("\n  Cancelled.")
+        holdResult(in, "Send", nil)
+        return
+    }
+
+    ctx, cancel = context.WithTimeout(context.Background(), 30*time.Second)
+    defer cancel()
+    txid, err := lnd.SendCoins(ctx, lndrest.SendCoinsRequest{
+        Address: addr, AmountSats: amount, SatPerVByte: fee, SendAll: sendAll,
+    })
+    if err != nil {
+        holdResult(in, "Send", err)
+        return
+    }
+    fmt.Printf("\n  ✅ Transaction: %s\n", txid)
+    holdResult(in, "Send", nil)
+}
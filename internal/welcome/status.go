@@ -0,0 +1,414 @@
+package welcome
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "strconv"
+    "strings"
+    "sync"
+    "syscall"
+    "time"
+
+    "github.com/ripsline/virtual-private-node/internal/bitcoinrpc"
+    "github.com/ripsline/virtual-private-node/internal/config"
+    "github.com/ripsline/virtual-private-node/internal/installer"
+    "github.com/ripsline/virtual-private-node/internal/lndrest"
+    "github.com/ripsline/virtual-private-node/internal/paths"
+    "github.com/ripsline/virtual-private-node/internal/system"
+)
+
+// sys runs the dashboard's commands and reads. Tests replace it
+// with a system.Recorder.
+var sys system.Runner = system.Host{}
+
+// StatusSchemaVersion is bumped whenever a field in Status is
+// renamed or removed. Adding fields does not change it.
+const StatusSchemaVersion = 1
+
+// Status is the node snapshot shown on the dashboard and printed
+// by `rlvpn status --json`. The JSON field names are the stable
+// schema documented in the README.
+type Status struct {
+    SchemaVersion  int              `json:"schema_version"`
+    CollectedAt    time.Time        `json:"collected_at"`
+    Network        string           `json:"network"`
+    Components     string           `json:"components"`
+    Services       map[string]bool  `json:"services"`
+    Disk           Usage            `json:"disk"`
+    RAM            Usage            `json:"ram"`
+    DirSizes       map[string]int64 `json:"dir_sizes"`
+    Bitcoin        BitcoinStatus    `json:"bitcoin"`
+    Tuning         BitcoinTuning    `json:"bitcoin_tuning"`
+    Lightning      *LightningStatus `json:"lightning,omitempty"`
+    RebootRequired bool             `json:"reboot_required"`
+}
+
+// Usage is a total/used pair in bytes. Percent is 0-100.
+type Usage struct {
+    TotalBytes uint64  `json:"total_bytes"`
+    UsedBytes  uint64  `json:"used_bytes"`
+    Percent    float64 `json:"percent"`
+}
+
+// BitcoinStatus is the subset of getblockchaininfo we report,
+// plus peer, mempool and fee figures. All fields other than
+// Responding are zero when bitcoind is not answering RPC.
+// FeeRate is the 6-block estimate in sat/vB, 0 until bitcoind has
+// seen enough blocks to estimate.
+type BitcoinStatus struct {
+    Responding           bool    `json:"responding"`
+    Blocks               int64   `json:"blocks"`
+    Headers              int64   `json:"headers"`
+    VerificationProgress float64 `json:"verification_progress"`
+    InitialBlockDownload bool    `json:"initial_block_download"`
+    Synced               bool    `json:"synced"`
+    Peers                int     `json:"peers"`
+    MempoolTxs           int     `json:"mempool_txs"`
+    FeeRate              float64 `json:"fee_rate_sat_vb"`
+}
+
+// BitcoinTuning is the resource settings in bitcoin.conf, in MB
+// where they are sizes. DBCacheAfterSync is what dbcache drops to
+// when the initial block download ends, and 0 once it has.
+type BitcoinTuning struct {
+    DBCache          int `json:"dbcache"`
+    DBCacheAfterSync int `json:"dbcache_after_sync"`
+    Par              int `json:"par"`
+    MaxConnections   int `json:"maxconnections"`
+    MaxMempool       int `json:"maxmempool"`
+}
+
+// LightningStatus is LND's state plus the getinfo and balance
+// figures. State is empty when LND is not answering; the other
+// fields stay zero until the wallet is unlocked. Balances are in
+// sats; ChannelSats is the local side of open channels.
+type LightningStatus struct {
+    State            string `json:"state"`
+    Pubkey           string `json:"pubkey"`
+    Alias            string `json:"alias"`
+    SyncedToChain    bool   `json:"synced_to_chain"`
+    BlockHeight      int64  `json:"block_height"`
+    Peers            int    `json:"peers"`
+    ActiveChannels   int    `json:"active_channels"`
+    InactiveChannels int    `json:"inactive_channels"`
+    PendingChannels  int    `json:"pending_channels"`
+    OnchainSats      int64  `json:"onchain_sats"`
+    ChannelSats      int64  `json:"channel_sats"`
+}
+
+// CollectStatus gathers service states, resource usage and
+// bitcoind sync progress. It never fails; missing data is left
+// at its zero value.
+func CollectStatus(cfg *config.AppConfig) Status {
+    s := Status{
+        SchemaVersion: StatusSchemaVersion,
+        CollectedAt:   time.Now().UTC(),
+        Network:       cfg.Network,
+        Components:    cfg.Components,
+        Services:      make(map[string]bool),
+        DirSizes:      make(map[string]int64),
+    }
+
+    for _, name := range serviceNames(cfg) {
+        _, err := sys.Run("systemctl", "is-active",
+            "--quiet", name)
+        s.Services[name] = err == nil
+    }
+
+    s.Disk = diskUsage(paths.Node.Path("/"))
+    s.RAM = memUsage()
+    s.DirSizes["bitcoin"] = dirSize(paths.Node.BitcoinData())
+    if cfg.HasLND() {
+        s.DirSizes["lnd"] = dirSize(paths.Node.LNDData())
+    }
+
+    if _, err := sys.Stat("/var/run/reboot-required"); err == nil {
+        s.RebootRequired = true
+    }
+
+    s.Bitcoin = bitcoinStatus(cfg)
+    s.Tuning = bitcoinTuning()
+    if cfg.HasLND() {
+        ln := lightningStatus(cfg)
+        s.Lightning = &ln
+    }
+    return s
+}
+
+// bitcoinTuning reads the resource settings back out of
+// bitcoin.conf. Settings that are missing stay 0.
+func bitcoinTuning() BitcoinTuning {
+    var t BitcoinTuning
+    data, err := sys.ReadFile(paths.Node.BitcoinConf())
+    if err != nil {
+        return t
+    }
+    fields := map[string]*int{
+        "dbcache=": &t.DBCache, installer.IBDMarker: &t.DBCacheAfterSync,
+        "par=": &t.Par, "maxconnections=": &t.MaxConnections, "maxmempool=": &t.MaxMempool,
+    }
+    for _, line := range strings.Split(string(data), "\n") {
+        for prefix, v := range fields {
+            if rest, ok := strings.CutPrefix(line, prefix); ok {
+                *v, _ = strconv.Atoi(rest)
+            }
+        }
+    }
+    return t
+}
+
+func bitcoinStatus(cfg *config.AppConfig) BitcoinStatus {
+    var b BitcoinStatus
+    ctx, cancel := context.WithTimeout(
+        context.Background(), 5*time.Second)
+    defer cancel()
+    rpc, err := bitcoinRPC(cfg)
+    if err != nil {
+        return b
+    }
+    info, err := rpc.GetBlockchainInfo(ctx)
+    if err != nil {
+        return b
+    }
+    b.Responding = true
+    b.Blocks = info.Blocks
+    b.Headers = info.Headers
+    b.VerificationProgress = info.VerificationProgress
+    b.InitialBlockDownload = info.InitialBlockDownload
+    b.Synced = !info.InitialBlockDownload
+    if net, err := rpc.GetNetworkInfo(ctx); err == nil {
+        b.Peers = net.Connections
+    }
+    if mem, err := rpc.GetMempoolInfo(ctx); err == nil {
+        b.MempoolTxs = mem.Size
+    }
+    if fee, err := rpc.EstimateSmartFee(ctx, 6, ""); err == nil {
+        b.FeeRate = fee.SatPerVByte()
+    }
+    return b
+}
+
+func lightningStatus(cfg *config.AppConfig) LightningStatus {
+    var l LightningStatus
+    ctx, cancel := context.WithTimeout(
+        context.Background(), 5*time.Second)
+    defer cancel()
+    lnd := lndClient(cfg)
+    state, err := lnd.State(ctx)
+    if err != nil {
+        return l
+    }
+    l.State = state
+    if state != "RPC_ACTIVE" && state != "SERVER_ACTIVE" {
+        return l
+    }
+    if info, err := lnd.GetInfo(ctx); err == nil {
+        l.Pubkey = info.IdentityPubkey
+        l.Alias = info.Alias
+        l.SyncedToChain = info.SyncedToChain
+        l.BlockHeight = info.BlockHeight
+        l.Peers = info.NumPeers
+        l.ActiveChannels = info.NumActiveChannels
+        l.InactiveChannels = info.NumInactiveChannels
+        l.PendingChannels = info.NumPendingChannels
+    }
+    if bal, err := lnd.WalletBalance(ctx); err == nil {
+        l.OnchainSats = bal.Total
+    }
+    if bal, err := lnd.ChannelBalance(ctx); err == nil {
+        l.ChannelSats = bal.Local.Sat
+    }
+    return l
+}
+
+// lndClients holds one REST client per network, so the dashboard's
+// polling reuses its connections instead of opening new ones.
+var (
+    lndClientsMu sync.Mutex
+    lndClients   = make(map[string]*lndrest.Client)
+)
+
+// lndClient returns the REST client for LND, authenticated with
+// cfg's network's macaroon.
+func lndClient(cfg *config.AppConfig) *lndrest.Client {
+    lndClientsMu.Lock()
+    defer lndClientsMu.Unlock()
+    c, ok := lndClients[cfg.Network]
+    if !ok {
+        c = lndrest.New(paths.Node.LNDTLSCert(), paths.Node.LNDMacaroon(cfg.Network))
+        lndClients[cfg.Network] = c
+    }
+    return c
+}
+
+// bitcoinRPC returns a client for bitcoind on cfg's network.
+func bitcoinRPC(cfg *config.AppConfig) (*bitcoinrpc.Client, error) {
+    network, err := installer.NetworkConfigFromName(cfg.Network)
+    if err != nil {
+        return nil, err
+    }
+    return bitcoinrpc.New(network.RPCPort, paths.Node.BitcoinCookie(cfg.Network)), nil
+}
+
+// serviceNames lists the systemd units for the installed
+// components, in dashboard order.
+func serviceNames(cfg *config.AppConfig) []string {
+    names := []string{"tor", "bitcoind"}
+    if cfg.HasLND() {
+        names = append(names, "lnd")
+    }
+    if cfg.LITInstalled {
+        names = append(names, "litd")
+    }
+    if cfg.SyncthingInstalled {
+        names = append(names, "syncthing")
+    }
+    return names
+}
+
+// PrintStatus collects the status and writes it to w, either as
+// indented JSON or as a short human-readable summary.
+func PrintStatus(w io.Writer, cfg *config.AppConfig, asJSON bool) error {
+    s := CollectStatus(cfg)
+    if asJSON {
+        enc := json.NewEncoder(w)
+        enc.SetIndent("", "  ")
+        return enc.Encode(s)
+    }
+
+    fmt.Fprintf(w, "Network:   %s (%s)\n", s.Network, s.Components)
+    for _, name := range serviceNames(cfg) {
+        state := "inactive"
+        if s.Services[name] {
+            state = "active"
+        }
+        fmt.Fprintf(w, "Service:   %-10s %s\n", name, state)
+    }
+    fmt.Fprintf(w, "Disk:      %s\n", fmtUsage(s.Disk))
+    fmt.Fprintf(w, "RAM:       %s\n", fmtUsage(s.RAM))
+    fmt.Fprintf(w, "Bitcoin:   %s\n", fmtBytes(s.DirSizes["bitcoin"]))
+    if size, ok := s.DirSizes["lnd"]; ok {
+        fmt.Fprintf(w, "LND:       %s\n", fmtBytes(size))
+    }
+    fmt.Fprintf(w, "Tuning:    %s\n", fmtTuning(s.Tuning))
+    if s.Bitcoin.Responding {
+        fmt.Fprintf(w, "Height:    %d / %d (%s)\n", s.Bitcoin.Blocks,
+            s.Bitcoin.Headers, fmtProgress(s.Bitcoin.VerificationProgress))
+        fmt.Fprintf(w, "Peers:     %d, mempool %d tx, fee %s\n", s.Bitcoin.Peers,
+            s.Bitcoin.MempoolTxs, fmtFeeRate(s.Bitcoin.FeeRate))
+    } else {
+        fmt.Fprintln(w, "Height:    bitcoind not responding")
+    }
+    if l := s.Lightning; l != nil {
+        switch {
+        case l.State == "":
+            fmt.Fprintln(w, "Lightning: LND not responding")
+        case l.Pubkey == "":
+            fmt.Fprintf(w, "Lightning: %s\n", strings.ToLower(l.State))
+        default:
+            fmt.Fprintf(w, "Lightning: %d channels (%d pending), %d sats on-chain, %d sats in channels\n",
+                l.ActiveChannels+l.InactiveChannels, l.PendingChannels, l.OnchainSats, l.ChannelSats)
+        }
+    }
+    if s.RebootRequired {
+        fmt.Fprintln(w, "Reboot required")
+    }
+    return nil
+}
+
+// ── Collection helpers ───────────────────────────────────
+
+func diskUsage(path string) Usage {
+    var st syscall.Statfs_t
+    if err := syscall.Statfs(path, &st); err != nil {
+        return Usage{}
+    }
+    bsize := uint64(st.Bsize)
+    used := (st.Blocks - st.Bfree) * bsize
+    avail := st.Bavail * bsize
+    u := Usage{TotalBytes: st.Blocks * bsize, UsedBytes: used}
+    // Same basis as df: reserved blocks don't count as available
+    if used+avail > 0 {
+        u.Percent = float64(used) / float64(used+avail) * 100
+    }
+    return u
+}
+
+func memUsage() Usage {
+    data, _ := sys.ReadFile("/proc/meminfo")
+    var total, avail uint64
+    for _, line := range strings.Split(string(data), "\n") {
+        if strings.HasPrefix(line, "MemTotal:") {
+            fmt.Sscanf(line, "MemTotal: %d kB", &total)
+        }
+        if strings.HasPrefix(line, "MemAvailable:") {
+            fmt.Sscanf(line, "MemAvailable: %d kB", &avail)
+        }
+    }
+    if total == 0 {
+        return Usage{}
+    }
+    used := total - avail
+    return Usage{
+        TotalBytes: total * 1024,
+        UsedBytes:  used * 1024,
+        Percent:    float64(used) / float64(total) * 100,
+    }
+}
+
+// dirSize returns the apparent size of path in bytes, or -1 if
+// it could not be measured.
+func dirSize(path string) int64 {
+    out, err := sys.Output(context.Background(), "du", "-sb", path)
+    if err != nil {
+        return -1
+    }
+    f := strings.Fields(string(out))
+    if len(f) < 1 {
+        return -1
+    }
+    n, err := strconv.ParseInt(f[0], 10, 64)
+    if err != nil {
+        return -1
+    }
+    return n
+}
+
+// ── Formatting ───────────────────────────────────────────
+
+func fmtFeeRate(satVB float64) string {
+    if satVB == 0 {
+        return "no estimate"
+    }
+    return fmt.Sprintf("%.1f sat/vB", satVB)
+}
+
+func fmtTuning(t BitcoinTuning) string {
+    s := fmt.Sprintf("dbcache %d MB, par %d, maxconnections %d, maxmempool %d MB",
+        t.DBCache, t.Par, t.MaxConnections, t.MaxMempool)
+    if t.DBCacheAfterSync > 0 {
+        s += fmt.Sprintf(" (dbcache %d MB after sync)", t.DBCacheAfterSync)
+    }
+    return s
+}
+
+func fmtUsage(u Usage) string {
+    if u.TotalBytes == 0 {
+        return "N/A"
+    }
+    return fmt.Sprintf("%s / %s (%.0f%%)", fmtBytes(int64(u.UsedBytes)),
+        fmtBytes(int64(u.TotalBytes)), u.Percent)
+}
+
+func fmtBytes(b int64) string {
+    if b < 0 {
+        return "N/A"
+    }
+    return fmtKB(int(b / 1024))
+}
+
+func fmtProgress(p float64) string {
+    return fmt.Sprintf("%.2f%%", p*100)
+}
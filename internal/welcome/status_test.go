@@ -0,0 +1,148 @@
+package welcome
+
+import (
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "github.com/ripsline/virtual-private-node/internal/config"
+    "github.com/ripsline/virtual-private-node/internal/installer"
+    "github.com/ripsline/virtual-private-node/internal/lndrest"
+    "github.com/ripsline/virtual-private-node/internal/system"
+)
+
+// useRecorder points sys at a Recorder for the test.
+func useRecorder(t *testing.T) *system.Recorder {
+    rec := system.NewRecorder()
+    prev := sys
+    sys = rec
+    t.Cleanup(func() { sys = prev })
+    return rec
+}
+
+// fakeLND serves replies by path and stands in for cfg's network
+// in the shared LND clients.
+func fakeLND(t *testing.T, network string, replies map[string]string) {
+    srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        body, ok := replies[r.URL.Path]
+        if !ok {
+            http.NotFound(w, r)
+            return
+        }
+        w.Write([]byte(body))
+    }))
+    t.Cleanup(srv.Close)
+    lndClientsMu.Lock()
+    prev, had := lndClients[network]
+    lndClients[network] = &lndrest.Client{URL: srv.URL, HTTP: srv.Client()}
+    lndClientsMu.Unlock()
+    t.Cleanup(func() {
+        lndClientsMu.Lock()
+        defer lndClientsMu.Unlock()
+        if had {
+            lndClients[network] = prev
+        } else {
+            delete(lndClients, network)
+        }
+    })
+}
+
+func TestCollectStatus(t *testing.T) {
+    rec := useRecorder(t)
+    rec.Respond("systemctl is-active --quiet lnd", "", errors.New("exit status 3"))
+    rec.Respond("du -sb /var/lib/bitcoin", "1048576\t/var/lib/bitcoin\n", nil)
+    rec.Respond("du -sb /var/lib/lnd", "garbage", nil)
+    rec.AddFile("/proc/meminfo", "MemTotal: 4000000 kB\nMemFree: 100 kB\nMemAvailable: 1000000 kB\n")
+    rec.AddFile("/etc/bitcoin/bitcoin.conf", "dbcache=2048\n"+installer.IBDMarker+"450\n"+
+        "par=2\nmaxconnections=40\nmaxmempool=300\n")
+    rec.AddFile("/var/run/reboot-required", "")
+    fakeLND(t, "testnet4", map[string]string{
+        "/v1/state":              `{"state": "RPC_ACTIVE"}`,
+        "/v1/getinfo":            `{"identity_pubkey": "02ab", "alias": "node", "num_peers": 3, "num_active_channels": 2}`,
+        "/v1/balance/blockchain": `{"total_balance": "5000"}`,
+        "/v1/balance/channels":   `{"local_balance": {"sat": "7000"}}`,
+    })
+
+    cfg := &config.AppConfig{Network: "testnet4", Components: "bitcoin+lnd", SyncthingInstalled: true}
+    s := CollectStatus(cfg)
+
+    want := map[string]bool{"tor": true, "bitcoind": true, "lnd": false, "syncthing": true}
+    if len(s.Services) != len(want) {
+        t.Errorf("services = %v, want %v", s.Services, want)
+    }
+    for name, active := range want {
+        if s.Services[name] != active {
+            t.Errorf("service %s active = %v, want %v", name, s.Services[name], active)
+        }
+    }
+    if s.DirSizes["bitcoin"] != 1048576 || s.DirSizes["lnd"] != -1 {
+        t.Errorf("dir sizes = %v", s.DirSizes)
+    }
+    if s.RAM.TotalBytes != 4000000*1024 || s.RAM.Percent != 75 {
+        t.Errorf("RAM = %+v", s.RAM)
+    }
+    if tu := s.Tuning; tu.DBCache != 2048 || tu.DBCacheAfterSync != 450 || tu.Par != 2 ||
+        tu.MaxConnections != 40 || tu.MaxMempool != 300 {
+        t.Errorf("tuning = %+v", s.Tuning)
+    }
+    if !s.RebootRequired {
+        t.Error("reboot-required not reported")
+    }
+    // no cookie, so bitcoind counts as not answering
+    if s.Bitcoin.Responding {
+        t.Error("bitcoind reported responding without a cookie")
+    }
+    l := s.Lightning
+    if l == nil || l.State != "RPC_ACTIVE" || l.Pubkey != "02ab" || l.Peers != 3 ||
+        l.ActiveChannels != 2 || l.OnchainSats != 5000 || l.ChannelSats != 7000 {
+        t.Errorf("lightning = %+v", l)
+    }
+
+    var out strings.Builder
+    if err := PrintStatus(&out, cfg, false); err != nil {
+        t.Fatal(err)
+    }
+    for _, line := range []string{"Service:   lnd        inactive", "Height:    bitcoind not responding",
+        "Lightning: 2 channels (0 pending), 5000 sats on-chain, 7000 sats in channels", "Reboot required"} {
+        if !strings.Contains(out.String(), line) {
+            t.Errorf("status output missing %q:\n%s", line, out.String())
+        }
+    }
+}
+
+func TestServiceActions(t *testing.T) {
+    rec := useRecorder(t)
+    m := NewModel(&config.AppConfig{Network: "testnet4", Components: "bitcoin+lnd"}, "test")
+    m.cardActive = true
+    key := func(k string) {
+        t.Helper()
+        next, cmd := m.handleCardKey(k)
+        m = next.(Model)
+        if cmd != nil {
+            cmd()
+        }
+    }
+
+    key("j")
+    key("r")
+    if m.svcConfirm != "restart" {
+        t.Fatalf("svcConfirm = %q, want restart", m.svcConfirm)
+    }
+    key("n")
+    if len(rec.Commands()) != 0 {
+        t.Errorf("cancelled action ran %v", rec.Commands())
+    }
+
+    key("j")
+    key("s")
+    key("y")
+    key("k")
+    key("a")
+    key("y")
+    got := strings.Join(rec.Commands(), "\n")
+    if want := "systemctl stop lnd\nsystemctl start bitcoind"; got != want {
+        t.Errorf("commands:\n%s\nwant:\n%s", got, want)
+    }
+}
@@ -4,8 +4,8 @@ import (
     "context"
     "encoding/base64"
     "encoding/hex"
-    "encoding/json"
     "fmt"
+    "maps"
     "os"
     "os/exec"
     "strconv"
@@ -17,6 +17,7 @@ import (
     qrcode "github.com/skip2/go-qrcode"
 
     "github.com/ripsline/virtual-private-node/internal/config"
+    "github.com/ripsline/virtual-private-node/internal/paths"
     "github.com/ripsline/virtual-private-node/internal/installer"
 )
 
@@ -80,10 +81,25 @@ const (
     svQR
     svFullURL
     svWalletCreate
+    svWalletRestore
     svLITInstall
     svSyncthingInstall
     svSystemUpdate
     svLogView
+    svBitcoinUpgrade
+    svLNDUpgrade
+    svUnprune
+    svPruneResize
+    svRPCAdd
+    svRPCRevoke
+    svChannels
+    svChannelOpen
+    svChannelClose
+    svReceive
+    svInvoiceCreate
+    svSend
+    svPayInvoice
+    svSendOnchain
 )
 
 type cardPos int
@@ -107,17 +123,8 @@ const (
 
 type svcActionDoneMsg struct{}
 
-type statusMsg struct {
-    services map[string]bool
-    diskTotal, diskUsed, diskPct string
-    ramTotal, ramUsed, ramPct   string
-    btcSize, lndSize            string
-    btcBlocks, btcHeaders       string
-    btcProgress                 string
-    btcSynced                   bool
-    btcResponding               bool
-    rebootRequired              bool
-}
+// statusMsg carries a fresh Status into the model.
+type statusMsg Status
 
 type tickMsg time.Time
 
@@ -133,13 +140,23 @@ type Model struct {
     svcCursor    int
     svcConfirm   string
     sysConfirm   string
+    btcBusy      bool
+    btcResult    string
     logSel       logSelection
     pairingFocus int
     urlTarget    string
+    rpcCursor    int
+    rpcConfirm   bool
+    chanRows     []channelRow
+    chanErr      string
+    chanCursor   int
+    aliases      map[string]string // node aliases by pubkey
+    recv         *receiveTarget
+    recvErr      string
     width        int
     height       int
     shellAction  wSubview
-    status       *statusMsg
+    status       *Status
 }
 
 func NewModel(cfg *config.AppConfig, version string) Model {
@@ -152,11 +169,31 @@ func NewModel(cfg *config.AppConfig, version string) Model {
 
 // Show launches the welcome TUI. Re-launches after shell actions.
 func Show(cfg *config.AppConfig, version string) {
+    // reopen returns to a subview after its shell action
+    reopen := svNone
+    var recv *receiveTarget
+    // aliases outlive each relaunch, so pending channels are not
+    // looked up again
+    var aliases map[string]string
     for {
         m := NewModel(cfg, version)
+        m.aliases = aliases
+        switch reopen {
+        case svSparrow:
+            m.activeTab, m.pairingFocus, m.subview = tabPairing, 1, svSparrow
+            m.rpcCursor = max(len(cfg.RPCUsers)-1, 0)
+        case svChannels:
+            m.dashCard, m.subview = cardLightning, svChannels
+        case svReceive:
+            m.dashCard, m.subview, m.recv = cardLightning, svReceive, recv
+        case svSend:
+            m.dashCard, m.subview = cardLightning, svSend
+        }
+        reopen = svNone
         p := tea.NewProgram(m, tea.WithAltScreen())
         result, _ := p.Run()
         final := result.(Model)
+        aliases = final.aliases
 
         switch final.shellAction {
         case svWalletCreate:
@@ -165,6 +202,12 @@ func Show(cfg *config.AppConfig, version string) {
                 cfg = u
             }
             continue
+        case svWalletRestore:
+            installer.RunWalletRestore(cfg)
+            if u, e := config.Load(); e == nil {
+                cfg = u
+            }
+            continue
         case svLITInstall:
             installer.RunLITInstall(cfg)
             if u, e := config.Load(); e == nil {
@@ -180,6 +223,67 @@ func Show(cfg *config.AppConfig, version string) {
         case svSystemUpdate:
             runSystemUpdate()
             continue
+        case svBitcoinUpgrade:
+            installer.RunBitcoinUpgradePrompt(cfg)
+            if u, e := config.Load(); e == nil {
+                cfg = u
+            }
+            continue
+        case svLNDUpgrade:
+            installer.RunLNDUpgradePrompt(cfg)
+            if u, e := config.Load(); e == nil {
+                cfg = u
+            }
+            continue
+        case svUnprune:
+            installer.RunUnprunePrompt(cfg)
+            if u, e := config.Load(); e == nil {
+                cfg = u
+            }
+            continue
+        case svPruneResize:
+            installer.RunPruneResizePrompt(cfg)
+            if u, e := config.Load(); e == nil {
+                cfg = u
+            }
+            continue
+        case svRPCAdd:
+            installer.RunRPCUserAddPrompt(cfg)
+            if u, e := config.Load(); e == nil {
+                cfg = u
+            }
+            reopen = svSparrow
+            continue
+        case svRPCRevoke:
+            installer.RunRPCUserRevokePrompt(cfg, cfg.RPCUsers[final.rpcCursor].Name)
+            if u, e := config.Load(); e == nil {
+                cfg = u
+            }
+            reopen = svSparrow
+            continue
+        case svChannelOpen:
+            runChannelOpen(cfg)
+            reopen = svChannels
+            continue
+        case svChannelClose:
+            runChannelClose(cfg, final.chanRows[final.chanCursor])
+            reopen = svChannels
+            continue
+        case svInvoiceCreate:
+            recv = final.recv
+            if t := runInvoicePrompt(cfg); t != nil {
+                recv = t
+            }
+            reopen = svReceive
+            continue
+        case svPayInvoice:
+            runPayInvoice(cfg)
+            reopen = svSend
+            continue
+        case svSendOnchain:
+            runSendOnchain(cfg)
+            reopen = svSend
+            continue
         case svLogView:
             runLogViewer(final.logSel, cfg)
             continue
@@ -190,10 +294,14 @@ func Show(cfg *config.AppConfig, version string) {
 }
 
 func (m Model) Init() tea.Cmd {
-    return tea.Batch(
-        fetchStatus(m.cfg),
-        tickEvery(5*time.Second),
-    )
+    cmds := []tea.Cmd{fetchStatus(m.cfg), tickEvery(5 * time.Second)}
+    if m.subview == svChannels {
+        cmds = append(cmds, fetchChannels(m.cfg, m.aliases))
+    }
+    if m.subview == svReceive && m.recv.waiting() {
+        cmds = append(cmds, fetchInvoice(m.cfg, m.recv.rHash))
+    }
+    return tea.Batch(cmds...)
 }
 
 func tickEvery(d time.Duration) tea.Cmd {
@@ -204,90 +312,16 @@ func tickEvery(d time.Duration) tea.Cmd {
 
 func fetchStatus(cfg *config.AppConfig) tea.Cmd {
     return func() tea.Msg {
-        s := statusMsg{services: make(map[string]bool)}
-
-        names := []string{"tor", "bitcoind"}
-        if cfg.HasLND() {
-            names = append(names, "lnd")
-        }
-        if cfg.LITInstalled {
-            names = append(names, "litd")
-        }
-        if cfg.SyncthingInstalled {
-            names = append(names, "syncthing")
-        }
-        for _, name := range names {
-            err := exec.Command("systemctl", "is-active",
-                "--quiet", name).Run()
-            s.services[name] = err == nil
-        }
-
-        s.diskTotal, s.diskUsed, s.diskPct = diskUsage("/")
-        s.ramTotal, s.ramUsed, s.ramPct = memUsage()
-        s.btcSize = dirSize("/var/lib/bitcoin")
-        if cfg.HasLND() {
-            s.lndSize = dirSize("/var/lib/lnd")
-        }
-
-        if _, err := os.Stat("/var/run/reboot-required"); err == nil {
-            s.rebootRequired = true
-        }
-
-        ctx, cancel := context.WithTimeout(
-            context.Background(), 5*time.Second)
-        defer cancel()
-        cmd := exec.CommandContext(ctx, "sudo", "-u", "bitcoin",
-            "bitcoin-cli", "-datadir=/var/lib/bitcoin",
-            "-conf=/etc/bitcoin/bitcoin.conf",
-            "getblockchaininfo")
-        output, err := cmd.CombinedOutput()
-        if err == nil {
-            s.btcResponding = true
-            info := string(output)
-            s.btcBlocks = extractJSON(info, "blocks")
-            s.btcHeaders = extractJSON(info, "headers")
-            s.btcSynced = !strings.Contains(info,
-                `"initialblockdownload": true`)
-            progress := extractJSON(info,
-                "verificationprogress")
-            if progress != "" {
-                pct, e := strconv.ParseFloat(progress, 64)
-                if e == nil {
-                    s.btcProgress = fmt.Sprintf(
-                        "%.2f%%", pct*100)
-                }
-            }
-        }
-
-        return s
+        return statusMsg(CollectStatus(cfg))
     }
 }
 
 func (m Model) svcCount() int {
-    n := 2
-    if m.cfg.HasLND() {
-        n++
-    }
-    if m.cfg.LITInstalled {
-        n++
-    }
-    if m.cfg.SyncthingInstalled {
-        n++
-    }
-    return n
+    return len(serviceNames(m.cfg))
 }
 
 func (m Model) svcName(i int) string {
-    names := []string{"tor", "bitcoind"}
-    if m.cfg.HasLND() {
-        names = append(names, "lnd")
-    }
-    if m.cfg.LITInstalled {
-        names = append(names, "litd")
-    }
-    if m.cfg.SyncthingInstalled {
-        names = append(names, "syncthing")
-    }
+    names := serviceNames(m.cfg)
     if i < len(names) {
         return names[i]
     }
@@ -305,14 +339,54 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
         return m.handleKey(msg)
     case svcActionDoneMsg:
         return m, fetchStatus(m.cfg)
+    case regtestDoneMsg:
+        m.btcBusy = false
+        m.btcResult = msg.text
+        if msg.err != nil {
+            m.btcResult = msg.err.Error()
+        }
+        return m, fetchStatus(m.cfg)
     case statusMsg:
-        m.status = &msg
+        st := Status(msg)
+        m.status = &st
+        return m, nil
+    case channelsMsg:
+        m.chanRows, m.chanErr = msg.rows, ""
+        if len(msg.aliases) > 0 {
+            aliases := make(map[string]string, len(m.aliases)+len(msg.aliases))
+            maps.Copy(aliases, m.aliases)
+            maps.Copy(aliases, msg.aliases)
+            m.aliases = aliases
+        }
+        if msg.err != nil {
+            m.chanErr = msg.err.Error()
+        }
+        m.chanCursor = min(m.chanCursor, max(len(m.chanRows)-1, 0))
+        return m, nil
+    case receiveMsg:
+        if msg.err != nil {
+            m.recvErr = msg.err.Error()
+            return m, nil
+        }
+        m.recv, m.recvErr = msg.target, ""
+        return m, nil
+    case invoiceMsg:
+        // a reply for an invoice no longer shown is dropped
+        if msg.err == nil && m.recv != nil && m.recv.rHash == msg.rHash {
+            t := *m.recv
+            t.state, t.paid, t.expires = msg.inv.State, msg.inv.AmtPaidSat, msg.inv.ExpiresAt()
+            m.recv = &t
+        }
         return m, nil
     case tickMsg:
-        return m, tea.Batch(
-            fetchStatus(m.cfg),
-            tickEvery(5*time.Second),
-        )
+        cmds := []tea.Cmd{fetchStatus(m.cfg), tickEvery(5 * time.Second)}
+        if m.subview == svChannels {
+            cmds = append(cmds, fetchChannels(m.cfg, m.aliases))
+        }
+        if m.subview == svReceive && m.recv.waiting() {
+            cmds = append(cmds, fetchInvoice(m.cfg, m.recv.rHash))
+        }
+        return m, tea.Batch(cmds...)
     }
     return m, nil
 }
@@ -321,6 +395,14 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
     key := msg.String()
 
     // Subviews
+    if m.subview == svSparrow && m.rpcConfirm {
+        m.rpcConfirm = false
+        if key == "y" {
+            m.shellAction = svRPCRevoke
+            return m, tea.Quit
+        }
+        return m, nil
+    }
     if m.subview != svNone {
         switch key {
         case "q", "ctrl+c":
@@ -329,6 +411,8 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
             switch m.subview {
             case svMacaroon, svQR:
                 m.subview = svZeus
+            case svChannels, svReceive, svSend:
+                m.subview = svLightning
             case svFullURL:
                 m.subview = svNone
             default:
@@ -345,6 +429,75 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
                 m.subview = svQR
                 return m, nil
             }
+            if m.subview == svLightning && m.cfg.WalletExists() {
+                m.subview = svReceive
+                return m, nil
+            }
+        case "i":
+            if m.subview == svReceive {
+                m.shellAction = svInvoiceCreate
+                return m, tea.Quit
+            }
+        case "p":
+            if m.subview == svSend {
+                m.shellAction = svPayInvoice
+                return m, tea.Quit
+            }
+        case "t", "s":
+            if m.subview == svLightning && key == "s" && m.cfg.WalletExists() {
+                m.subview = svSend
+                return m, nil
+            }
+            if m.subview == svReceive {
+                kind := "taproot"
+                if key == "s" {
+                    kind = "segwit"
+                }
+                return m, fetchAddress(m.cfg, kind)
+            }
+        case "up", "k":
+            if m.subview == svSparrow && m.rpcCursor > 0 {
+                m.rpcCursor--
+            }
+            if m.subview == svChannels && m.chanCursor > 0 {
+                m.chanCursor--
+            }
+        case "down", "j":
+            if m.subview == svSparrow && m.rpcCursor < len(m.cfg.RPCUsers)-1 {
+                m.rpcCursor++
+            }
+            if m.subview == svChannels && m.chanCursor < len(m.chanRows)-1 {
+                m.chanCursor++
+            }
+        case "c":
+            if m.subview == svLightning && m.cfg.WalletExists() {
+                m.subview = svChannels
+                m.chanRows, m.chanErr = nil, ""
+                return m, fetchChannels(m.cfg, m.aliases)
+            }
+        case "o":
+            if m.subview == svChannels {
+                m.shellAction = svChannelOpen
+                return m, tea.Quit
+            }
+            if m.subview == svSend {
+                m.shellAction = svSendOnchain
+                return m, tea.Quit
+            }
+        case "a":
+            if m.subview == svSparrow {
+                m.shellAction = svRPCAdd
+                return m, tea.Quit
+            }
+        case "x":
+            if m.subview == svSparrow && m.rpcCursor < len(m.cfg.RPCUsers) {
+                m.rpcConfirm = true
+            }
+            if m.subview == svChannels && m.chanCursor < len(m.chanRows) &&
+                m.chanRows[m.chanCursor].closable() {
+                m.shellAction = svChannelClose
+                return m, tea.Quit
+            }
         }
         return m, nil
     }
@@ -386,6 +539,22 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
         m = m.navRight()
     case "enter":
         return m.handleEnter()
+    case "b":
+        if m.activeTab == tabSoftware {
+            m.shellAction = svBitcoinUpgrade
+            return m, tea.Quit
+        }
+    case "n":
+        if m.activeTab == tabSoftware && m.cfg.HasLND() {
+            m.shellAction = svLNDUpgrade
+            return m, tea.Quit
+        }
+    case "r":
+        if m.activeTab == tabDashboard && m.dashCard == cardLightning &&
+            m.cfg.HasLND() && !m.cfg.WalletExists() {
+            m.shellAction = svWalletRestore
+            return m, tea.Quit
+        }
     }
     return m, nil
 }
@@ -396,6 +565,7 @@ func (m Model) handleCardKey(key string) (tea.Model, tea.Cmd) {
         m.cardActive = false
         m.svcConfirm = ""
         m.sysConfirm = ""
+        m.btcResult = ""
         return m, nil
     case "q":
         return m, tea.Quit
@@ -421,7 +591,7 @@ func (m Model) handleCardKey(key string) (tea.Model, tea.Cmd) {
                 action := m.svcConfirm
                 m.svcConfirm = ""
                 return m, func() tea.Msg {
-                    exec.Command("systemctl", action, svc).Run()
+                    sys.Run("systemctl", action, svc)
                     return svcActionDoneMsg{}
                 }
             default:
@@ -459,7 +629,7 @@ func (m Model) handleCardKey(key string) (tea.Model, tea.Cmd) {
                 }
                 if action == "reboot" {
                     return m, func() tea.Msg {
-                        exec.Command("reboot").Run()
+                        sys.Run("reboot")
                         return svcActionDoneMsg{}
                     }
                 }
@@ -472,13 +642,42 @@ func (m Model) handleCardKey(key string) (tea.Model, tea.Cmd) {
         switch key {
         case "u":
             m.sysConfirm = "update"
+        case "f":
+            if m.cfg.IsPruned() {
+                m.shellAction = svUnprune
+                return m, tea.Quit
+            }
+        case "p":
+            if m.cfg.IsPruned() {
+                m.shellAction = svPruneResize
+                return m, tea.Quit
+            }
         case "r":
-            if m.status != nil && m.status.rebootRequired {
+            if m.status != nil && m.status.RebootRequired {
                 m.sysConfirm = "reboot"
             }
         }
     }
 
+    if m.dashCard == cardBitcoin && !m.btcBusy {
+        switch key {
+        case "m":
+            m.btcBusy = true
+            cfg := m.cfg
+            return m, regtestAction(func() (string, error) {
+                return mineBlocks(cfg, regtestMineBlocks)
+            })
+        case "f":
+            if m.cfg.HasLND() && m.cfg.WalletExists() {
+                cfg := m.cfg
+                m.btcBusy = true
+                return m, regtestAction(func() (string, error) {
+                    return fundLNDWallet(cfg)
+                })
+            }
+        }
+    }
+
     return m, nil
 }
 
@@ -561,6 +760,12 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
         case cardSystem:
             m.cardActive = true
             return m, nil
+        case cardBitcoin:
+            if m.cfg.IsRegtest() {
+                m.cardActive = true
+                m.btcResult = ""
+            }
+            return m, nil
         case cardLightning:
             if !m.cfg.HasLND() {
                 return m, nil
@@ -583,7 +788,7 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
     case tabSoftware:
         if m.pairingFocus == 0 {
             if m.cfg.SyncthingInstalled {
-                syncOnion := readOnion("/var/lib/tor/syncthing/hostname")
+                syncOnion := readOnion(paths.Node.OnionHostname("syncthing"))
                 if syncOnion != "" {
                     m.urlTarget = "http://" + syncOnion + ":8384"
                     m.subview = svFullURL
@@ -594,7 +799,7 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
             }
         } else {
             if m.cfg.LITInstalled {
-                litOnion := readOnion("/var/lib/tor/lnd-lit/hostname")
+                litOnion := readOnion(paths.Node.OnionHostname("lnd-lit"))
                 if litOnion != "" {
                     m.urlTarget = "https://" + litOnion + ":8443"
                     m.subview = svFullURL
@@ -621,6 +826,12 @@ func (m Model) View() string {
         return m.viewZeus()
     case svSparrow:
         return m.viewSparrow()
+    case svChannels:
+        return m.viewChannels()
+    case svReceive:
+        return m.viewReceive()
+    case svSend:
+        return m.viewSend()
     case svMacaroon:
         return m.viewMacaroon()
     case svQR:
@@ -689,16 +900,31 @@ func (m Model) viewFooter() string {
                 "  ↑↓ select • [r]estart [s]top [a]start • backspace back • q quit  ")
         }
         if m.dashCard == cardSystem {
-            if m.status != nil && m.status.rebootRequired {
+            keys := "[u]pdate system"
+            if m.status != nil && m.status.RebootRequired {
+                keys = "[u]pdate • [r]eboot"
+            }
+            if m.cfg.IsPruned() {
+                keys += " • [p]rune • [f]ull node"
+            }
+            return wFooterStyle.Render(
+                "  " + keys + " • backspace back • q quit  ")
+        }
+        if m.dashCard == cardBitcoin {
+            if m.cfg.HasLND() && m.cfg.WalletExists() {
                 return wFooterStyle.Render(
-                    "  [u]pdate • [r]eboot • backspace back • q quit  ")
+                    "  [m]ine blocks • [f]und LND • backspace back • q quit  ")
             }
             return wFooterStyle.Render(
-                "  [u]pdate system • backspace back • q quit  ")
+                "  [m]ine blocks • backspace back • q quit  ")
         }
     }
     switch m.activeTab {
     case tabDashboard:
+        if m.dashCard == cardLightning && m.cfg.HasLND() && !m.cfg.WalletExists() {
+            return wFooterStyle.Render(
+                "  ↑↓←→ navigate • enter create wallet • r restore • tab switch • q quit  ")
+        }
         return wFooterStyle.Render(
             "  ↑↓←→ navigate • enter select • tab switch • q quit  ")
     case tabPairing:
@@ -709,7 +935,7 @@ func (m Model) viewFooter() string {
             "  ↑↓ select • enter view • tab switch • q quit  ")
     case tabSoftware:
         return wFooterStyle.Render(
-            "  ←→ select • enter install/view • tab switch • q quit  ")
+            "  ←→ select • enter install/view • [b]/[n] upgrade • tab switch • q quit  ")
     }
     return ""
 }
@@ -745,21 +971,10 @@ func (m Model) cardServicesView(w, h int) string {
     lines = append(lines, wHeaderStyle.Render("Services"))
     lines = append(lines, "")
 
-    names := []string{"tor", "bitcoind"}
-    if m.cfg.HasLND() {
-        names = append(names, "lnd")
-    }
-    if m.cfg.LITInstalled {
-        names = append(names, "litd")
-    }
-    if m.cfg.SyncthingInstalled {
-        names = append(names, "syncthing")
-    }
-
-    for i, name := range names {
+    for i, name := range serviceNames(m.cfg) {
         dot := wRedDotStyle.Render("●")
         if m.status != nil {
-            if active, ok := m.status.services[name]; ok && active {
+            if active, ok := m.status.Services[name]; ok && active {
                 dot = wGreenDotStyle.Render("●")
             }
         }
@@ -797,41 +1012,53 @@ func (m Model) cardSystemView(w, h int) string {
 
     if m.status != nil {
         lines = append(lines, wLabelStyle.Render("Disk: ")+
-            wValueStyle.Render(fmt.Sprintf("%s / %s (%s)",
-                m.status.diskUsed, m.status.diskTotal,
-                m.status.diskPct)))
+            wValueStyle.Render(fmtUsage(m.status.Disk)))
         lines = append(lines, wLabelStyle.Render("RAM:  ")+
-            wValueStyle.Render(fmt.Sprintf("%s / %s (%s)",
-                m.status.ramUsed, m.status.ramTotal,
-                m.status.ramPct)))
+            wValueStyle.Render(fmtUsage(m.status.RAM)))
+        storage := "unpruned"
+        if m.cfg.IsPruned() {
+            storage = fmt.Sprintf("pruned %d GB", m.cfg.PruneSize)
+        }
         lines = append(lines,
             wLabelStyle.Render("Bitcoin: ")+
-                wValueStyle.Render(m.status.btcSize))
+                wValueStyle.Render(fmtBytes(m.status.DirSizes["bitcoin"]))+
+                wDimStyle.Render(" ("+storage+")"))
         if m.cfg.HasLND() {
             lines = append(lines,
                 wLabelStyle.Render("LND: ")+
-                    wValueStyle.Render(m.status.lndSize))
+                    wValueStyle.Render(fmtBytes(m.status.DirSizes["lnd"])))
+        }
+        t := m.status.Tuning
+        lines = append(lines,
+            wLabelStyle.Render("dbcache: ")+wValueStyle.Render(fmt.Sprintf("%d MB", t.DBCache))+
+                wLabelStyle.Render("  mempool: ")+wValueStyle.Render(fmt.Sprintf("%d MB", t.MaxMempool)))
+        peers := wLabelStyle.Render("par: ") + wValueStyle.Render(strconv.Itoa(t.Par)) +
+            wLabelStyle.Render("  peers: ") + wValueStyle.Render(strconv.Itoa(t.MaxConnections))
+        if t.DBCacheAfterSync > 0 {
+            peers += wDimStyle.Render("  (sync cache)")
         }
+        lines = append(lines, peers)
     } else {
         lines = append(lines, wDimStyle.Render("Loading..."))
     }
 
+    // An active card drops the spacer so the actions still fit
     if m.cardActive && m.dashCard == cardSystem {
-        lines = append(lines, "")
         if m.sysConfirm != "" {
             lines = append(lines, wWarningStyle.Render(
                 fmt.Sprintf("%s system? [y/n]", m.sysConfirm)))
         } else {
-            lines = append(lines,
-                wActionStyle.Render("[u]pdate packages"))
-            if m.status != nil && m.status.rebootRequired {
-                lines = append(lines,
-                    wWarningStyle.Render("⚠ Reboot required"))
+            actions := wActionStyle.Render("[u]pdate packages")
+            if m.status != nil && m.status.RebootRequired {
+                actions += wWarningStyle.Render("  ⚠ [r]eboot")
+            }
+            lines = append(lines, actions)
+            if m.cfg.IsPruned() {
                 lines = append(lines,
-                    wActionStyle.Render("[r]eboot"))
+                    wActionStyle.Render("[p]rune size  [f]ull node"))
             }
         }
-    } else if m.status != nil && m.status.rebootRequired {
+    } else if m.status != nil && m.status.RebootRequired {
         lines = append(lines, "")
         lines = append(lines,
             wWarningStyle.Render("⚠ Reboot required"))
@@ -848,10 +1075,10 @@ func (m Model) cardBitcoinView(w, h int) string {
 
     if m.status == nil {
         lines = append(lines, wDimStyle.Render("Loading..."))
-    } else if !m.status.btcResponding {
+    } else if !m.status.Bitcoin.Responding {
         lines = append(lines, wWarnStyle.Render("Not responding"))
     } else {
-        if m.status.btcSynced {
+        if m.status.Bitcoin.Synced {
             lines = append(lines,
                 wLabelStyle.Render("Sync: ")+
                     wGoodStyle.Render("✅ synced"))
@@ -862,16 +1089,39 @@ func (m Model) cardBitcoinView(w, h int) string {
         }
         lines = append(lines,
             wLabelStyle.Render("Height: ")+
-                wValueStyle.Render(m.status.btcBlocks+
-                    " / "+m.status.btcHeaders))
-        if m.status.btcProgress != "" {
-            lines = append(lines,
-                wLabelStyle.Render("Progress: ")+
-                    wValueStyle.Render(m.status.btcProgress))
-        }
+                wValueStyle.Render(fmt.Sprintf("%d / %d",
+                    m.status.Bitcoin.Blocks, m.status.Bitcoin.Headers)))
+        lines = append(lines,
+            wLabelStyle.Render("Progress: ")+
+                wValueStyle.Render(fmtProgress(
+                    m.status.Bitcoin.VerificationProgress)))
         lines = append(lines,
             wLabelStyle.Render("Network: ")+
                 wValueStyle.Render(m.cfg.Network))
+        lines = append(lines,
+            wLabelStyle.Render("Peers: ")+
+                wValueStyle.Render(strconv.Itoa(m.status.Bitcoin.Peers))+
+                wLabelStyle.Render("  Fee: ")+
+                wValueStyle.Render(fmtFeeRate(m.status.Bitcoin.FeeRate)))
+    }
+
+    if m.cardActive && m.dashCard == cardBitcoin {
+        lines = append(lines, "")
+        switch {
+        case m.btcBusy:
+            lines = append(lines, wDimStyle.Render("Mining..."))
+        case m.btcResult != "":
+            lines = append(lines, wValueStyle.Render(m.btcResult))
+        default:
+            lines = append(lines, wActionStyle.Render(
+                fmt.Sprintf("[m]ine %d blocks", regtestMineBlocks)))
+            if m.cfg.HasLND() && m.cfg.WalletExists() {
+                lines = append(lines, wActionStyle.Render("[f]und LND wallet"))
+            }
+        }
+    } else if m.cfg.IsRegtest() && m.status != nil {
+        lines = append(lines, "")
+        lines = append(lines, wActionStyle.Render("Select to mine ▸"))
     }
 
     return m.getBorder(cardBitcoin, true).Width(w).
@@ -895,6 +1145,7 @@ func (m Model) cardLightningView(w, h int) string {
             wWarningStyle.Render("not created"))
         lines = append(lines, "")
         lines = append(lines, wActionStyle.Render("Select to create ▸"))
+        lines = append(lines, wActionStyle.Render("[r] restore from seed"))
     } else {
         lines = append(lines, wLabelStyle.Render("Wallet: ")+
             wGoodStyle.Render("created"))
@@ -925,21 +1176,30 @@ func (m Model) viewLightning() string {
             lines = append(lines, "  "+wLabelStyle.Render("Auto-unlock: ")+
                 wGoodStyle.Render("enabled"))
         }
-        balance := getLNDBalance(m.cfg)
-        if balance != "" {
-            lines = append(lines, "  "+wLabelStyle.Render("Balance: ")+
-                wValueStyle.Render(balance+" sats"))
-        }
-        chanCount := getLNDChannelCount(m.cfg)
-        if chanCount != "" {
+        switch l := m.status; {
+        case l == nil || l.Lightning == nil:
+            lines = append(lines, "  "+wDimStyle.Render("Loading..."))
+        case l.Lightning.State == "":
+            lines = append(lines, "  "+wWarnStyle.Render("LND not responding"))
+        case l.Lightning.Pubkey == "":
+            lines = append(lines, "  "+wLabelStyle.Render("State: ")+
+                wWarnStyle.Render(strings.ToLower(l.Lightning.State)))
+        default:
+            ln := l.Lightning
+            lines = append(lines, "  "+wLabelStyle.Render("On-chain: ")+
+                wValueStyle.Render(fmt.Sprintf("%d sats", ln.OnchainSats)))
+            lines = append(lines, "  "+wLabelStyle.Render("In channels: ")+
+                wValueStyle.Render(fmt.Sprintf("%d sats", ln.ChannelSats)))
             lines = append(lines, "  "+wLabelStyle.Render("Channels: ")+
-                wValueStyle.Render(chanCount))
-        }
-        pubkey := getLNDPubkey(m.cfg)
-        if pubkey != "" {
+                wValueStyle.Render(fmt.Sprintf("%d active, %d inactive, %d pending",
+                    ln.ActiveChannels, ln.InactiveChannels, ln.PendingChannels)))
+            lines = append(lines, "  "+wLabelStyle.Render("Peers: ")+
+                wValueStyle.Render(strconv.Itoa(ln.Peers)))
             lines = append(lines, "")
             lines = append(lines, "  "+wLabelStyle.Render("Pubkey:"))
-            lines = append(lines, "  "+wMonoStyle.Render(pubkey))
+            lines = append(lines, "  "+wMonoStyle.Render(ln.Pubkey))
+            lines = append(lines, "")
+            lines = append(lines, "  "+wActionStyle.Render("[c] channels    [r] receive    [s] send"))
         }
     } else {
         lines = append(lines, "  "+wWarningStyle.Render("Wallet not created"))
@@ -949,7 +1209,7 @@ func (m Model) viewLightning() string {
     box := wOuterBox.Width(bw).Padding(1, 2).Render(content)
     title := wTitleStyle.Width(bw).Align(lipgloss.Center).
         Render(" ⚡ Lightning Details ")
-    footer := wFooterStyle.Render("  backspace back • q quit  ")
+    footer := wFooterStyle.Render("  c channels • r receive • s send • backspace back • q quit  ")
     full := lipgloss.JoinVertical(lipgloss.Center,
         "", title, "", box, "", footer)
     return lipgloss.Place(m.width, m.height,
@@ -976,7 +1236,7 @@ func (m Model) viewPairing(bw int) string {
     var zeusLines []string
     zeusEnabled := m.cfg.HasLND() && m.cfg.WalletExists()
     if zeusEnabled {
-        restOnion := readOnion("/var/lib/tor/lnd-rest/hostname")
+        restOnion := readOnion(paths.Node.OnionHostname("lnd-rest"))
         status := wGreenDotStyle.Render("●") + " ready"
         if restOnion == "" {
             status = wRedDotStyle.Render("●") + " waiting"
@@ -1007,7 +1267,7 @@ func (m Model) viewPairing(bw int) string {
     }
     zeusCard := zBorder.Width(halfW).Padding(1, 2).Render(padLines(zeusLines, cardH))
 
-    btcRPC := readOnion("/var/lib/tor/bitcoin-rpc/hostname")
+    btcRPC := readOnion(paths.Node.OnionHostname("bitcoin-rpc"))
     sStatus := wGreenDotStyle.Render("●") + " ready"
     if btcRPC == "" {
         sStatus = wRedDotStyle.Render("●") + " waiting"
@@ -1033,7 +1293,7 @@ func (m Model) viewZeus() string {
     var lines []string
     lines = append(lines, wLightningStyle.Render("⚡ Zeus Wallet — LND REST over Tor"))
     lines = append(lines, "")
-    restOnion := readOnion("/var/lib/tor/lnd-rest/hostname")
+    restOnion := readOnion(paths.Node.OnionHostname("lnd-rest"))
     if restOnion == "" {
         lines = append(lines, wWarnStyle.Render("Not available yet."))
     } else {
@@ -1069,34 +1329,52 @@ func (m Model) viewSparrow() string {
     var lines []string
     lines = append(lines, wHeaderStyle.Render("₿ Sparrow — Bitcoin Core RPC over Tor"))
     lines = append(lines, "")
-    lines = append(lines, wWarningStyle.Render("WARNING: Cookie changes on restart."))
-    lines = append(lines, "")
-    btcRPC := readOnion("/var/lib/tor/bitcoin-rpc/hostname")
-    if btcRPC != "" {
-        port := "8332"
-        if !m.cfg.IsMainnet() {
-            port = "48332"
-        }
-        cookie := readCookieValue(m.cfg)
+    btcRPC := readOnion(paths.Node.OnionHostname("bitcoin-rpc"))
+    network, err := installer.NetworkConfigFromName(m.cfg.Network)
+    if err != nil {
+        lines = append(lines, wWarnStyle.Render(err.Error()))
+    } else if btcRPC == "" {
+        lines = append(lines, wWarnStyle.Render("Not available yet."))
+    } else {
+        port := strconv.Itoa(network.RPCPort)
         lines = append(lines, "  "+wLabelStyle.Render("Port: ")+wMonoStyle.Render(port))
-        lines = append(lines, "  "+wLabelStyle.Render("User: ")+wMonoStyle.Render("__cookie__"))
-        lines = append(lines, "")
         lines = append(lines, "  "+wLabelStyle.Render("URL:"))
         lines = append(lines, "  "+wMonoStyle.Render(btcRPC))
         lines = append(lines, "")
-        if cookie != "" {
+        if len(m.cfg.RPCUsers) == 0 {
+            lines = append(lines, "  "+wDimStyle.Render("No wallet login yet."))
+            lines = append(lines, "")
+            lines = append(lines, "  "+wActionStyle.Render("[a] create a login for Sparrow"))
+        } else {
+            var names []string
+            for i, u := range m.cfg.RPCUsers {
+                if i == m.rpcCursor {
+                    names = append(names, wActionStyle.Render("▸ "+u.Name))
+                } else {
+                    names = append(names, wDimStyle.Render("  "+u.Name))
+                }
+            }
+            u := m.cfg.RPCUsers[min(m.rpcCursor, len(m.cfg.RPCUsers)-1)]
+            lines = append(lines, "  "+wLabelStyle.Render("Logins: ")+strings.Join(names, " "))
+            lines = append(lines, "  "+wLabelStyle.Render("User: ")+wMonoStyle.Render(u.Name))
             lines = append(lines, "  "+wLabelStyle.Render("Password:"))
-            lines = append(lines, "  "+wMonoStyle.Render(cookie))
+            lines = append(lines, "  "+wMonoStyle.Render(u.Password))
+            lines = append(lines, "")
+            if m.rpcConfirm {
+                lines = append(lines, "  "+wWarningStyle.Render("Revoke "+u.Name+"? Wallets using it disconnect. [y/n]"))
+            } else {
+                lines = append(lines, "  "+wActionStyle.Render("[a] new login    [x] revoke"))
+            }
         }
     }
     lines = append(lines, "")
     lines = append(lines, wDimStyle.Render("1. Sparrow → Settings → Server"))
-    lines = append(lines, wDimStyle.Render("2. Bitcoin Core tab, enter details"))
+    lines = append(lines, wDimStyle.Render("2. Bitcoin Core tab, User / Pass"))
     lines = append(lines, wDimStyle.Render("3. Test Connection"))
 
     box := wOuterBox.Width(bw).Padding(1, 2).Render(strings.Join(lines, "\n"))
     title := wTitleStyle.Width(bw).Align(lipgloss.Center).Render(" Sparrow Wallet Setup ")
-    footer := wFooterStyle.Render("  backspace back • q quit  ")
+    footer := wFooterStyle.Render("  ↑↓ select • a new • x revoke • backspace back • q quit  ")
     full := lipgloss.JoinVertical(lipgloss.Center, "", title, "", box, "", footer)
     return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, full)
 }
@@ -1113,7 +1391,7 @@ func (m Model) viewMacaroon() string {
 }
 
 func (m Model) viewQR() string {
-    restOnion := readOnion("/var/lib/tor/lnd-rest/hostname")
+    restOnion := readOnion(paths.Node.OnionHostname("lnd-rest"))
     mac := readMacaroonHex(m.cfg)
     if restOnion == "" || mac == "" {
         return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center,
@@ -1186,7 +1464,8 @@ func (m Model) availableLogs() []logSelection {
 
 func (m Model) viewSoftware(bw int) string {
     halfW := (bw - 4) / 2
-    cardH := wBoxHeight
+    // Two lines go to the node software row below the cards
+    cardH := wBoxHeight - 2
 
     // Syncthing card
     var syncLines []string
@@ -1304,8 +1583,17 @@ func (m Model) viewSoftware(bw int) string {
     litCard := lBorder.Width(halfW).Padding(1, 2).
         Render(padLines(litLines, cardH))
 
-    return lipgloss.JoinHorizontal(lipgloss.Top,
+    cards := lipgloss.JoinHorizontal(lipgloss.Top,
         syncCard, "  ", litCard)
+    core := wBitcoinStyle.Render("₿ Bitcoin Core ") +
+        wValueStyle.Render("v"+installer.InstalledBitcoinVersion(m.cfg)) +
+        wDimStyle.Render("  [b] upgrade")
+    if m.cfg.HasLND() {
+        core += "    " + wLightningStyle.Render("⚡ LND ") +
+            wValueStyle.Render("v"+installer.InstalledLNDVersion(m.cfg)) +
+            wDimStyle.Render("  [n] upgrade")
+    }
+    return lipgloss.JoinVertical(lipgloss.Left, cards, "", core)
 }
 
 // ── Shell actions ────────────────────────────────────────
@@ -1314,7 +1602,7 @@ func runSystemUpdate() {
     fmt.Print("\033[2J\033[H")
     fmt.Println("\n  ═══════════════════════════════════════════")
     fmt.Println("    System Update")
-    fmt.Println("  ═══════════════════════════════════════════\n")
+    fmt.Print("  ═══════════════════════════════════════════\n\n")
     fmt.Println("  Running apt update && apt upgrade...")
     fmt.Println()
 
@@ -1328,13 +1616,13 @@ func runSystemUpdate() {
     cmd.Run()
 
     fmt.Println("\n  ✅ Update complete")
-    if _, err := os.Stat("/var/run/reboot-required"); err == nil {
+    if _, err := sys.Stat("/var/run/reboot-required"); err == nil {
         fmt.Println("\n  ⚠️ Reboot required.")
         fmt.Print("  Reboot now? [y/N]: ")
         var ans string
         fmt.Scanln(&ans)
         if strings.ToLower(ans) == "y" {
-            exec.Command("reboot").Run()
+            sys.Run("reboot")
         }
     }
     fmt.Print("\n  Press Enter to return...")
@@ -1404,44 +1692,6 @@ func hexToBase64URL(hexStr string) string {
     return base64.RawURLEncoding.EncodeToString(data)
 }
 
-// ── LND queries ──────────────────────────────────────────
-
-func getLNDBalance(cfg *config.AppConfig) string {
-    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-    defer cancel()
-    cmd := exec.CommandContext(ctx, "sudo", "-u", "bitcoin", "lncli",
-        "--lnddir=/var/lib/lnd", "--network="+cfg.Network, "walletbalance")
-    out, err := cmd.CombinedOutput()
-    if err != nil {
-        return ""
-    }
-    return extractJSON(string(out), "total_balance")
-}
-
-func getLNDChannelCount(cfg *config.AppConfig) string {
-    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-    defer cancel()
-    cmd := exec.CommandContext(ctx, "sudo", "-u", "bitcoin", "lncli",
-        "--lnddir=/var/lib/lnd", "--network="+cfg.Network, "getinfo")
-    out, err := cmd.CombinedOutput()
-    if err != nil {
-        return ""
-    }
-    return extractJSON(string(out), "num_active_channels")
-}
-
-func getLNDPubkey(cfg *config.AppConfig) string {
-    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-    defer cancel()
-    cmd := exec.CommandContext(ctx, "sudo", "-u", "bitcoin", "lncli",
-        "--lnddir=/var/lib/lnd", "--network="+cfg.Network, "getinfo")
-    out, err := cmd.CombinedOutput()
-    if err != nil {
-        return ""
-    }
-    return extractJSON(string(out), "identity_pubkey")
-}
-
 // ── Helpers ──────────────────────────────────────────────
 
 func padLines(lines []string, target int) string {
@@ -1455,7 +1705,7 @@ func padLines(lines []string, target int) string {
 }
 
 func readOnion(path string) string {
-    data, err := os.ReadFile(path)
+    data, err := sys.ReadFile(path)
     if err != nil {
         return ""
     }
@@ -1467,75 +1717,13 @@ func readMacaroonHex(cfg *config.AppConfig) string {
     if cfg.IsMainnet() {
         network = "mainnet"
     }
-    data, err := os.ReadFile(fmt.Sprintf(
-        "/var/lib/lnd/data/chain/bitcoin/%s/admin.macaroon", network))
+    data, err := sys.ReadFile(paths.Node.LNDMacaroon(network))
     if err != nil {
         return ""
     }
     return hex.EncodeToString(data)
 }
 
-func readCookieValue(cfg *config.AppConfig) string {
-    p := "/var/lib/bitcoin/.cookie"
-    if !cfg.IsMainnet() {
-        p = fmt.Sprintf("/var/lib/bitcoin/%s/.cookie", cfg.Network)
-    }
-    data, err := os.ReadFile(p)
-    if err != nil {
-        return ""
-    }
-    parts := strings.SplitN(strings.TrimSpace(string(data)), ":", 2)
-    if len(parts) != 2 {
-        return ""
-    }
-    return parts[1]
-}
-
-func diskUsage(path string) (string, string, string) {
-    cmd := exec.Command("df", "-h", "--output=size,used,pcent", path)
-    out, _ := cmd.CombinedOutput()
-    lines := strings.Split(strings.TrimSpace(string(out)), "\n")
-    if len(lines) < 2 {
-        return "N/A", "N/A", "N/A"
-    }
-    f := strings.Fields(lines[1])
-    if len(f) < 3 {
-        return "N/A", "N/A", "N/A"
-    }
-    return f[0], f[1], f[2]
-}
-
-func memUsage() (string, string, string) {
-    data, _ := os.ReadFile("/proc/meminfo")
-    var total, avail int
-    for _, line := range strings.Split(string(data), "\n") {
-        if strings.HasPrefix(line, "MemTotal:") {
-            fmt.Sscanf(line, "MemTotal: %d kB", &total)
-        }
-        if strings.HasPrefix(line, "MemAvailable:") {
-            fmt.Sscanf(line, "MemAvailable: %d kB", &avail)
-        }
-    }
-    if total == 0 {
-        return "N/A", "N/A", "N/A"
-    }
-    used := total - avail
-    return fmtKB(total), fmtKB(used), fmt.Sprintf("%.0f%%",
-        float64(used)/float64(total)*100)
-}
-
-func dirSize(path string) string {
-    out, err := exec.Command("du", "-sh", path).CombinedOutput()
-    if err != nil {
-        return "N/A"
-    }
-    f := strings.Fields(string(out))
-    if len(f) < 1 {
-        return "N/A"
-    }
-    return f[0]
-}
-
 func fmtKB(kb int) string {
     if kb >= 1048576 {
         return fmt.Sprintf("%.1f GB", float64(kb)/1048576.0)
@@ -1547,9 +1735,7 @@ func getSyncthingVersion() string {
     ctx, cancel := context.WithTimeout(
         context.Background(), 3*time.Second)
     defer cancel()
-    cmd := exec.CommandContext(ctx, "syncthing",
-        "--version")
-    out, err := cmd.CombinedOutput()
+    out, err := sys.Output(ctx, "syncthing", "--version")
     if err != nil {
         return "unknown"
     }
@@ -1560,27 +1746,3 @@ func getSyncthingVersion() string {
     }
     return "unknown"
 }
-
-func extractJSON(j, key string) string {
-    var m map[string]interface{}
-    if err := json.Unmarshal([]byte(j), &m); err != nil {
-        return ""
-    }
-    v, ok := m[key]
-    if !ok {
-        return ""
-    }
-    switch val := v.(type) {
-    case string:
-        return val
-    case float64:
-        if val == float64(int64(val)) {
-            return fmt.Sprintf("%d", int64(val))
-        }
-        return fmt.Sprintf("%f", val)
-    case bool:
-        return fmt.Sprintf("%t", val)
-    default:
-        return fmt.Sprintf("%v", val)
-    }
-}
\ No newline at end of file
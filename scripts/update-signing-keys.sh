@@ -0,0 +1,38 @@
+#!/bin/bash
+# Refresh the release signing keys embedded in rlvpn.
+# Each key is fetched into a temporary GPG home, so the user's
+# keyring is never touched, and written out only if its primary
+# fingerprint matches the pinned one.
+set -euo pipefail
+
+KEYS_DIR="$(cd "$(dirname "$0")/.." && pwd)/internal/installer/keys"
+GUIX=https://raw.githubusercontent.com/bitcoin-core/guix.sigs/main/builder-keys
+
+# name fingerprint source
+SIGNERS=(
+    "fanquake   E777299FC265DD04793070EB944D35F9AC3DB76A $GUIX/fanquake.gpg"
+    "guggero    FDE04B7075113BFB085020B57BBD8D4D95DB9F03 $GUIX/guggero.gpg"
+    "hebasto    CBE89ED88EE8525FD8D79F1EDB56ADFD8B5EF498 $GUIX/hebasto.gpg"
+    "theStack   9343A22960A50972CC1EFD7DB3B5CB8DB648B27F $GUIX/theStack.gpg"
+    "willcl-ark A0083660F235A27000CD3C81CE6EC49945C17EA6 $GUIX/willcl-ark.gpg"
+    "roasbeef   296212681AADF05656A2CDEE90525F7DEEE0AD86 https://raw.githubusercontent.com/lightningnetwork/lnd/master/scripts/keys/roasbeef.asc"
+    "ViktorT-11 C20A78516A0944900EBFCA29961CC8259AE675D4 hkps://keyserver.ubuntu.com"
+)
+
+GNUPGHOME="$(mktemp -d)"
+export GNUPGHOME
+trap 'rm -rf "$GNUPGHOME"' EXIT
+
+for entry in "${SIGNERS[@]}"; do
+    read -r name fpr source <<< "$entry"
+    case "$source" in
+        hkps://*) gpg --batch --quiet --keyserver "$source" --recv-keys "$fpr" ;;
+        *)        curl -fsSL "$source" | gpg --batch --quiet --import ;;
+    esac
+    if ! gpg --batch --with-colons --fingerprint "$fpr" | grep -q "^fpr:::::::::$fpr:"; then
+        echo "ERROR: $name: fingerprint $fpr not found in $source" >&2
+        exit 1
+    fi
+    gpg --batch --armor --export-options export-minimal --export "$fpr" > "$KEYS_DIR/$name.asc"
+    echo "  ✓ $name ($fpr)"
+done